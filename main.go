@@ -2,6 +2,10 @@ package main
 
 import (
 	"context"
+	crand "crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"flag"
 	"math/rand"
 	"os"
 	"os/signal"
@@ -11,8 +15,10 @@ import (
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/realSunyz/lucky-tgbot/pkg/api"
+	"github.com/realSunyz/lucky-tgbot/pkg/config"
 	"github.com/realSunyz/lucky-tgbot/pkg/database"
 	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	"github.com/realSunyz/lucky-tgbot/pkg/metrics"
 	"github.com/realSunyz/lucky-tgbot/pkg/service"
 	"github.com/realSunyz/lucky-tgbot/pkg/worker"
 	"github.com/realSunyz/lucky-tgbot/plugin/lottery"
@@ -21,6 +27,31 @@ import (
 )
 
 func main() {
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to the YAML config file")
+	restorePath := flag.String("restore", "", "replace the SQLite database with this backup before starting")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		logger.Fatal("invalid configuration", "error", err)
+	}
+	if err := logger.Setup(os.Stdout, cfg.Log.Format, cfg.Log.Level); err != nil {
+		logger.Fatal("invalid log configuration", "error", err)
+	}
+	lottery.SetConfig(cfg)
+	if *restorePath != "" {
+		if cfg.Database.Driver != config.DatabaseDriverSQLite {
+			logger.Fatal("-restore is only supported with sqlite", "driver", cfg.Database.Driver)
+		}
+		if err := database.Restore(*restorePath, cfg.Database.Path); err != nil {
+			logger.Fatal("failed to restore database", "backup", *restorePath, "error", err)
+		}
+	}
+	db, repo, err := openDatabase(cfg.Database)
+	if err != nil {
+		logger.Fatal("failed to open database", "driver", cfg.Database.Driver, "error", err)
+	}
+
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
@@ -29,13 +60,24 @@ func main() {
 
 	opts := []bot.Option{
 		bot.WithErrorsHandler(func(err error) {
-			logger.Errorf("%v", err)
+			logger.Error("telegram bot error", "error", err)
 		}),
+		bot.WithMiddlewares(logContext, lottery.FillImportedProfile),
+		bot.WithCallbackQueryDataHandler(lottery.JoinCallbackPrefix, bot.MatchTypePrefix, lottery.HandleJoinCallback),
+		bot.WithCallbackQueryDataHandler(lottery.RevokeSessionCallbackPrefix, bot.MatchTypePrefix, lottery.HandleRevokeSessionCallback),
+		bot.WithCallbackQueryDataHandler(lottery.MyLotteriesCallbackPrefix, bot.MatchTypePrefix, lottery.HandleMyLotteriesCallback),
+		bot.WithCallbackQueryDataHandler(lottery.DrawCallbackPrefix, bot.MatchTypePrefix, lottery.HandleDrawCallback),
 		bot.WithDefaultHandler(func(ctx context.Context, b *bot.Bot, update *models.Update) {
 			if update.Message == nil {
 				return
 			}
 
+			// Handle chats picked for publishing a lottery
+			if update.Message.ChatShared != nil {
+				lottery.HandleChatShared(ctx, b, update)
+				return
+			}
+
 			inputText := update.Message.Text
 			if inputText == "" {
 				return
@@ -54,6 +96,46 @@ func main() {
 				lottery.HandleDeleteCommand(ctx, b, update)
 				return
 			}
+			if strings.HasPrefix(inputText, "/export") {
+				lottery.HandleExportCommand(ctx, b, update)
+				return
+			}
+			if strings.HasPrefix(inputText, "/admin") {
+				lottery.HandleAdminCommand(ctx, b, update)
+				return
+			}
+			if strings.HasPrefix(inputText, "/addadmin") {
+				lottery.HandleAddAdminCommand(ctx, b, update)
+				return
+			}
+			if strings.HasPrefix(inputText, "/removeadmin") {
+				lottery.HandleRemoveAdminCommand(ctx, b, update)
+				return
+			}
+			if strings.HasPrefix(inputText, "/mylotteries") {
+				lottery.HandleMyLotteriesCommand(ctx, b, update)
+				return
+			}
+			if strings.HasPrefix(inputText, "/draw") {
+				lottery.HandleDrawCommand(ctx, b, update)
+				return
+			}
+			if strings.HasPrefix(inputText, "/status") {
+				lottery.HandleStatusCommand(ctx, b, update)
+				return
+			}
+			if strings.HasPrefix(inputText, "/joined") {
+				lottery.HandleJoinedCommand(ctx, b, update)
+				return
+			}
+			if strings.HasPrefix(inputText, "/sessions") {
+				lottery.HandleSessionsCommand(ctx, b, update)
+				return
+			}
+			if strings.HasPrefix(inputText, "/publish") {
+				lottery.HandlePublishCommand(ctx, b, update)
+				return
+			}
 			if strings.HasPrefix(inputText, "/start") {
 				lottery.HandleStartCommand(ctx, b, update)
 				return
@@ -70,23 +152,118 @@ func main() {
 		}),
 	}
 
-	b, err := bot.New(os.Getenv("TELEGRAM_BOT_TOKEN"), opts...)
+	b, err := bot.New(cfg.Telegram.Token, opts...)
 	if err != nil {
-		logger.Fatalf("error creating bot: %v", err)
+		logger.Fatal("error creating bot", "error", err)
 	}
 
-	lotteryService := service.NewLotteryService(database.GetDB(), lottery.NewTelegramNotifier(b))
+	// Handle inline queries for sharing lotteries
+	b.RegisterHandlerMatchFunc(lottery.IsInlineQuery, lottery.HandleInlineQuery)
+
+	metrics.RegisterDB(db, cfg.Database.Driver)
+
+	lotteryService := service.NewLotteryService(repo, lottery.NewTelegramNotifier(b), cfg)
 	lottery.SetService(lotteryService)
 
+	// Back up the SQLite database on schedule and on demand
+	if cfg.Database.Backup.Dir != "" {
+		backups := worker.NewBackups(cfg.Database.Path, cfg.Database.Backup.Dir, cfg.Database.Backup.Keep)
+		lotteryService.SetBackupper(backups)
+		if cfg.Database.Backup.Interval > 0 {
+			worker.StartBackupWorker(backups, cfg.Database.Backup.Interval)
+		}
+	}
+
+	// Receive updates by webhook on the API server when configured
+	var webhook *api.Webhook
+	if cfg.Telegram.UpdateMode == config.UpdateModeWebhook {
+		webhook = &api.Webhook{Ctx: ctx, Bot: b, SecretToken: webhookSecret(cfg.Telegram.WebhookSecret)}
+	}
+
 	// Start HTTP API server in background
-	go api.StartServer(lotteryService)
+	go api.StartServer(lotteryService, cfg, webhook)
 
 	// Start timed lottery draw checker
-	api.StartTimedDrawChecker(lotteryService)
+	api.StartTimedDrawChecker(lotteryService, cfg.Lottery.DrawCheckInterval)
+
+	// Start published announcement updater
+	lottery.StartAnnouncementUpdater(b)
 
 	// Start cleanup worker
-	worker.StartCleanupWorker()
+	worker.StartCleanupWorker(repo, cfg.Worker.CleanupInterval, cfg.Lottery.DraftExpiry)
+
+	if webhook == nil {
+		// getUpdates is rejected while a webhook is still registered
+		if _, err := b.DeleteWebhook(ctx, &bot.DeleteWebhookParams{}); err != nil {
+			logger.Warn("failed to delete webhook", "error", err)
+		}
+		logger.Info("bot started successfully", "mode", config.UpdateModePolling)
+		b.Start(ctx)
+		return
+	}
 
-	logger.Infof("bot started successfully")
-	b.Start(ctx)
+	webhookURL := cfg.Telegram.WebhookURL + api.WebhookPath
+	if _, err := b.SetWebhook(ctx, &bot.SetWebhookParams{URL: webhookURL, SecretToken: webhook.SecretToken}); err != nil {
+		logger.Fatal("failed to set webhook", "url", webhookURL, "error", err)
+	}
+	logger.Info("bot started successfully", "mode", config.UpdateModeWebhook, "url", webhookURL)
+
+	<-ctx.Done()
+
+	// The signal context is already cancelled, so give the call its own deadline
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+	if _, err := b.DeleteWebhook(shutdownCtx, &bot.DeleteWebhookParams{}); err != nil {
+		logger.Warn("failed to delete webhook", "error", err)
+	}
+}
+
+// logContext adds the update, chat and user IDs to the log context of every
+// update handler.
+func logContext(next bot.HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		args := []any{"update_id", update.ID}
+		switch {
+		case update.Message != nil:
+			args = append(args, "chat_id", update.Message.Chat.ID)
+			if update.Message.From != nil {
+				args = append(args, "user_id", update.Message.From.ID)
+			}
+		case update.CallbackQuery != nil:
+			args = append(args, "user_id", update.CallbackQuery.From.ID)
+		case update.InlineQuery != nil && update.InlineQuery.From != nil:
+			args = append(args, "user_id", update.InlineQuery.From.ID)
+		}
+		next(logger.With(ctx, args...), b, update)
+	}
+}
+
+// webhookSecret returns the configured secret, or a random secret for this
+// run since the webhook is registered again on every start.
+func webhookSecret(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	buf := make([]byte, 32)
+	if _, err := crand.Read(buf); err != nil {
+		logger.Fatal("failed to generate webhook secret", "error", err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// openDatabase opens the configured database and its repository.
+func openDatabase(cfg config.DatabaseConfig) (*sql.DB, database.LotteryRepository, error) {
+	if cfg.Driver == config.DatabaseDriverPostgres {
+		db, err := database.OpenPostgres(cfg.URL, cfg.MaxOpenConns)
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, database.NewPostgresRepository(db), nil
+	}
+
+	db, err := database.Open(cfg.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, database.NewSQLiteRepository(db), nil
 }
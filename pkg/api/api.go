@@ -3,8 +3,9 @@ package api
 import (
 	"context"
 	"errors"
-	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
@@ -14,10 +15,12 @@ import (
 	"github.com/gofiber/fiber/v3/middleware/healthcheck"
 	"github.com/gofiber/fiber/v3/middleware/limiter"
 	"github.com/gofiber/fiber/v3/middleware/recover"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
 	"github.com/gofiber/fiber/v3/middleware/static"
 	"github.com/gofiber/fiber/v3/middleware/timeout"
-	"github.com/realSunyz/lucky-tgbot/pkg/database"
+	"github.com/realSunyz/lucky-tgbot/pkg/config"
 	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	"github.com/realSunyz/lucky-tgbot/pkg/metrics"
 	"github.com/realSunyz/lucky-tgbot/pkg/models"
 	"github.com/realSunyz/lucky-tgbot/pkg/service"
 )
@@ -29,7 +32,6 @@ type LotteryRequest struct {
 	DrawTime          *string `json:"draw_time"`
 	MaxEntries        *int    `json:"max_entries"`
 	Prizes            []Prize `json:"prizes"`
-	CreatorID         int64   `json:"creator_id"`
 	IsWeightsDisabled bool    `json:"is_weights_disabled"`
 }
 
@@ -38,6 +40,8 @@ type Prize struct {
 	Quantity int    `json:"quantity"`
 }
 
+// JoinRequest lists the identity fields older clients sent in the join body.
+// They are rejected now that the identity comes from verified init data.
 type JoinRequest struct {
 	UserID    int64  `json:"user_id"`
 	Username  string `json:"username"`
@@ -58,10 +62,22 @@ type PrizeWeightRequest struct {
 	Weight  int   `json:"weight"`
 }
 
+type ExchangeSessionRequest struct {
+	Code string `json:"code"`
+}
+
+type ExchangeSessionResponse struct {
+	Token     string    `json:"token"`
+	Role      string    `json:"role"`
+	Name      string    `json:"name"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 type LotteryResponse struct {
 	*models.Lottery
-	Prizes  []models.Prize  `json:"prizes"`
-	Winners []models.Winner `json:"winners,omitempty"`
+	Prizes  []models.Prize        `json:"prizes"`
+	Winners []models.Winner       `json:"winners,omitempty"`
+	Weights *models.WeightSummary `json:"weights,omitempty"`
 }
 
 type Handler struct {
@@ -69,21 +85,16 @@ type Handler struct {
 }
 
 const (
-	writeTimeout     = 8 * time.Second
-	joinLimitMax     = 60
-	joinLimitWindow  = time.Minute
-	editLimitMax     = 30
-	editLimitWindow  = time.Minute
-	drawLimitMax     = 20
-	drawLimitWindow  = time.Minute
 	readinessTimeout = 2 * time.Second
+	creatorIDKey     = "creator_id"
+	sessionUserKey   = "session_user_id"
 )
 
 func NewHandler(svc *service.LotteryService) *Handler {
 	return &Handler{service: svc}
 }
 
-func withWriteTimeout(handler fiber.Handler) fiber.Handler {
+func withTimeout(handler fiber.Handler, writeTimeout time.Duration) fiber.Handler {
 	return timeout.New(handler, timeout.Config{
 		Timeout: writeTimeout,
 		OnTimeout: func(c fiber.Ctx) error {
@@ -92,10 +103,10 @@ func withWriteTimeout(handler fiber.Handler) fiber.Handler {
 	})
 }
 
-func lotteryScopedLimiter(max int, expiration time.Duration) fiber.Handler {
+func lotteryScopedLimiter(limit config.RateLimit) fiber.Handler {
 	return limiter.New(limiter.Config{
-		Max:        max,
-		Expiration: expiration,
+		Max:        limit.Max,
+		Expiration: limit.Window,
 		KeyGenerator: func(c fiber.Ctx) string {
 			return c.IP() + "|" + c.Route().Path + "|" + c.Params("id")
 		},
@@ -105,51 +116,151 @@ func lotteryScopedLimiter(max int, expiration time.Duration) fiber.Handler {
 	})
 }
 
-func SetupRoutes(app *fiber.App, svc *service.LotteryService) {
+func SetupRoutes(app *fiber.App, svc *service.LotteryService, cfg *config.Config) {
 	h := NewHandler(svc)
 	api := app.Group("/api")
-	joinLimiter := lotteryScopedLimiter(joinLimitMax, joinLimitWindow)
-	editLimiter := lotteryScopedLimiter(editLimitMax, editLimitWindow)
-	drawLimiter := lotteryScopedLimiter(drawLimitMax, drawLimitWindow)
+	joinLimiter := lotteryScopedLimiter(cfg.Server.JoinLimit)
+	editLimiter := lotteryScopedLimiter(cfg.Server.EditLimit)
+	drawLimiter := lotteryScopedLimiter(cfg.Server.DrawLimit)
+	miniAppAuth := initDataAuth(cfg.Telegram.Token)
+	withWriteTimeout := func(handler fiber.Handler) fiber.Handler {
+		return withTimeout(handler, cfg.Server.WriteTimeout)
+	}
 
 	api.Get("/lottery/:id", h.getLottery)
 	api.Get("/stats", h.getStats)
-	api.Post("/lottery/:id", editLimiter, withWriteTimeout(h.createLottery))
-	api.Post("/lottery/:id/join", joinLimiter, withWriteTimeout(h.joinLottery))
+	api.Get("/me/entries", miniAppAuth, h.getMyEntries)
+	api.Post("/lottery/:id", editLimiter, h.createTokenAuth, withWriteTimeout(h.createLottery))
+	api.Post("/lottery/:id/join", joinLimiter, miniAppAuth, withWriteTimeout(h.joinLottery))
+	api.Post("/lottery/:id/session", editLimiter, withWriteTimeout(h.exchangeEditSession))
 	api.Get("/lottery/:id/results", h.getResults)
+	api.Get("/lottery/:id/events", h.lotteryEvents)
+
+	anyOrganizer := h.tokenAuth(models.RoleOwner, models.RoleEditor, models.RoleModerator)
+	ownerOnly := h.tokenAuth(models.RoleOwner)
+	canEdit := h.tokenAuth(models.RoleOwner, models.RoleEditor)
+	canModerate := h.tokenAuth(models.RoleOwner, models.RoleModerator)
+
+	api.Put("/lottery/:id", editLimiter, canEdit, withWriteTimeout(h.updateLottery))
+	api.Get("/lottery/:id/participants", anyOrganizer, h.getParticipants)
+	api.Get("/lottery/:id/export", anyOrganizer, h.exportLottery)
+	api.Post("/lottery/:id/participants", editLimiter, ownerOnly, withWriteTimeout(h.addParticipant))
+	api.Post("/lottery/:id/participants/import", editLimiter, ownerOnly, withWriteTimeout(h.importParticipants))
+	api.Put("/lottery/:id/participants/:uid", editLimiter, canEdit, withWriteTimeout(h.updateParticipantWeight))
+	api.Post("/lottery/:id/participants/:uid/prize_weight", editLimiter, canEdit, withWriteTimeout(h.updatePrizeWeight))
+	api.Delete("/lottery/:id/participants/:uid/prize_weight/:prize_id", editLimiter, canEdit, withWriteTimeout(h.deletePrizeWeight))
+	api.Delete("/lottery/:id/participants/:uid", editLimiter, canModerate, withWriteTimeout(h.removeParticipant))
+	api.Post("/lottery/:id/draw", drawLimiter, ownerOnly, withWriteTimeout(h.drawLottery))
+	api.Get("/lottery/:id/audit", auditReaderAuth(miniAppAuth, ownerOnly), h.getAuditLog)
+
+	api.Post("/admin/backup", editLimiter, miniAppAuth, h.adminBackup)
+	api.Get("/admin/audit", miniAppAuth, h.getFullAuditLog)
+}
 
-	api.Put("/lottery/:id", editLimiter, h.tokenAuth, withWriteTimeout(h.updateLottery))
-	api.Get("/lottery/:id/participants", h.tokenAuth, h.getParticipants)
-	api.Post("/lottery/:id/participants", editLimiter, h.tokenAuth, withWriteTimeout(h.addParticipant))
-	api.Put("/lottery/:id/participants/:uid", editLimiter, h.tokenAuth, withWriteTimeout(h.updateParticipantWeight))
-	api.Post("/lottery/:id/participants/:uid/prize_weight", editLimiter, h.tokenAuth, withWriteTimeout(h.updatePrizeWeight))
-	api.Delete("/lottery/:id/participants/:uid/prize_weight/:prize_id", editLimiter, h.tokenAuth, withWriteTimeout(h.deletePrizeWeight))
-	api.Delete("/lottery/:id/participants/:uid", editLimiter, h.tokenAuth, withWriteTimeout(h.removeParticipant))
-	api.Post("/lottery/:id/draw", drawLimiter, h.tokenAuth, withWriteTimeout(h.drawLottery))
+// bearerToken extracts the token from an "Authorization: Bearer" header.
+func bearerToken(c fiber.Ctx) string {
+	token, _ := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	return strings.TrimSpace(token)
 }
 
-func (h *Handler) tokenAuth(c fiber.Ctx) error {
-	lotteryID := c.Params("id")
-	token := c.Query("token")
+// tokenAuth validates the edit session token and only lets through organizers
+// whose role is in the allowed list.
+func (h *Handler) tokenAuth(allowed ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		lotteryID := c.Params("id")
+		withLogAttrs(c, "lottery_id", lotteryID)
+		token := bearerToken(c)
+		if token == "" {
+			return SendError(c, fiber.StatusUnauthorized, ERR_UNAUTHORIZED, "Token required")
+		}
+
+		session, err := h.service.ValidateEditToken(lotteryID, token)
+		if err != nil {
+			if errors.Is(err, service.ErrTokenInvalid) {
+				return SendError(c, fiber.StatusUnauthorized, ERR_TOKEN_INVALID, "Invalid or expired token")
+			}
+			logger.ErrorContext(c.Context(), "token validation error", "error", err)
+			return SendError(c, fiber.StatusInternalServerError, ERR_INTERNAL, "Token validation failed")
+		}
+
+		if !slices.Contains(allowed, session.Role) {
+			return SendError(c, fiber.StatusForbidden, ERR_FORBIDDEN, "Insufficient role for this action")
+		}
+		c.Locals(sessionUserKey, session.UserID)
+		withLogAttrs(c, "user_id", session.UserID, "role", session.Role)
+
+		return c.Next()
+	}
+}
+
+// sessionUser returns the organizer behind the edit session checked by
+// tokenAuth.
+func sessionUser(c fiber.Ctx) int64 {
+	return fiber.Locals[int64](c, sessionUserKey)
+}
+
+// createTokenAuth verifies the signed creation token issued by /create and
+// stores the creator it was issued to.
+func (h *Handler) createTokenAuth(c fiber.Ctx) error {
+	token := bearerToken(c)
 	if token == "" {
 		return SendError(c, fiber.StatusUnauthorized, ERR_UNAUTHORIZED, "Token required")
 	}
 
-	if err := h.service.ValidateEditToken(lotteryID, token); err != nil {
+	creatorID, err := h.service.VerifyCreateToken(c.Params("id"), token)
+	if err != nil {
+		return SendError(c, fiber.StatusUnauthorized, ERR_TOKEN_INVALID, "Invalid or expired token")
+	}
+	withLogAttrs(c, "lottery_id", c.Params("id"), "user_id", creatorID)
+
+	c.Locals(creatorIDKey, creatorID)
+	return c.Next()
+}
+
+// exchangeEditSession trades the one-time code from an /edit link for the
+// session token used in the Authorization header.
+func (h *Handler) exchangeEditSession(c fiber.Ctx) error {
+	var req ExchangeSessionRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return SendError(c, fiber.StatusBadRequest, ERR_BAD_REQUEST, "Invalid request body")
+	}
+
+	token, session, err := h.service.ExchangeEditLinkCode(c.Params("id"), req.Code)
+	if err != nil {
 		if errors.Is(err, service.ErrTokenInvalid) {
-			return SendError(c, fiber.StatusUnauthorized, ERR_TOKEN_INVALID, "Invalid or expired token")
+			return SendError(c, fiber.StatusUnauthorized, ERR_TOKEN_INVALID, "Invalid or expired link")
 		}
-		logger.Errorf("token validation error: %v", err)
-		return SendError(c, fiber.StatusInternalServerError, ERR_INTERNAL, "Token validation failed")
+		logger.ErrorContext(c.Context(), "failed to exchange edit link code", "lottery_id", c.Params("id"), "error", err)
+		return SendInternalError(c)
 	}
 
-	return c.Next()
+	return c.JSON(ExchangeSessionResponse{
+		Token:     token,
+		Role:      session.Role,
+		Name:      session.Name,
+		ExpiresAt: session.ExpiresAt,
+	})
+}
+
+// getMyEntries lists the verified Mini App user's active entries and wins.
+func (h *Handler) getMyEntries(c fiber.Ctx) error {
+	user := initDataUser(c)
+	if user == nil {
+		return SendError(c, fiber.StatusUnauthorized, ERR_UNAUTHORIZED, "Telegram init data required")
+	}
+
+	entries, err := h.service.GetUserEntries(user.ID)
+	if err != nil {
+		logger.ErrorContext(c.Context(), "failed to get user entries", "error", err)
+		return SendInternalError(c)
+	}
+	return c.JSON(entries)
 }
 
 func (h *Handler) getStats(c fiber.Ctx) error {
 	stats, err := h.service.GetLotteryStats()
 	if err != nil {
-		logger.Errorf("failed to get lottery stats: %v", err)
+		logger.ErrorContext(c.Context(), "failed to get lottery stats", "error", err)
 		return SendInternalError(c)
 	}
 	return c.JSON(stats)
@@ -163,7 +274,13 @@ func (h *Handler) getLottery(c fiber.Ctx) error {
 		if errors.Is(err, service.ErrLotteryNotFound) {
 			return SendError(c, fiber.StatusNotFound, ERR_NOT_FOUND, "Lottery not found")
 		}
-		logger.Errorf("failed to get lottery snapshot %s: %v", id, err)
+		logger.ErrorContext(c.Context(), "failed to get lottery snapshot", "lottery_id", id, "error", err)
+		return SendInternalError(c)
+	}
+
+	weights, err := h.service.GetWeightSummary(snapshot.Lottery)
+	if err != nil {
+		logger.ErrorContext(c.Context(), "failed to get weight summary", "lottery_id", id, "error", err)
 		return SendInternalError(c)
 	}
 
@@ -171,6 +288,7 @@ func (h *Handler) getLottery(c fiber.Ctx) error {
 		Lottery: snapshot.Lottery,
 		Prizes:  snapshot.Prizes,
 		Winners: snapshot.Winners,
+		Weights: weights,
 	})
 }
 
@@ -203,15 +321,21 @@ func (h *Handler) createLottery(c fiber.Ctx) error {
 		DrawTime:          drawTime,
 		MaxEntries:        req.MaxEntries,
 		Prizes:            prizes,
-		CreatorID:         req.CreatorID,
+		CreatorID:         fiber.Locals[int64](c, creatorIDKey),
 		IsWeightsDisabled: req.IsWeightsDisabled,
 	})
 	if err != nil {
-		if errors.Is(err, service.ErrLotteryConflict) {
+		switch {
+		case errors.Is(err, service.ErrLotteryConflict):
 			return SendError(c, fiber.StatusConflict, ERR_CONFLICT, "Lottery already exists")
+		case errors.Is(err, service.ErrPermissionDenied):
+			return SendError(c, fiber.StatusForbidden, ERR_FORBIDDEN, "Not the creator of this lottery")
+		case errors.Is(err, service.ErrUserBanned):
+			return SendError(c, fiber.StatusForbidden, ERR_USER_BANNED, "User is banned")
+		default:
+			logger.ErrorContext(c.Context(), "failed to create lottery", "error", err)
+			return SendInternalError(c)
 		}
-		logger.Errorf("failed to create lottery %s: %v", id, err)
-		return SendInternalError(c)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(LotteryResponse{Lottery: lottery, Prizes: createdPrizes})
@@ -239,7 +363,7 @@ func (h *Handler) updateLottery(c fiber.Ctx) error {
 		prizes = append(prizes, models.Prize{Name: p.Name, Quantity: p.Quantity})
 	}
 
-	lottery, updatedPrizes, err := h.service.UpdateLottery(id, service.UpdateLotteryInput{
+	lottery, updatedPrizes, err := h.service.UpdateLottery(id, sessionUser(c), service.UpdateLotteryInput{
 		Title:             req.Title,
 		Description:       req.Description,
 		DrawMode:          req.DrawMode,
@@ -256,7 +380,7 @@ func (h *Handler) updateLottery(c fiber.Ctx) error {
 		case errors.Is(err, service.ErrLotteryEnded):
 			return SendError(c, fiber.StatusBadRequest, ERR_LOTTERY_ENDED, "Cannot modify completed lottery")
 		default:
-			logger.Errorf("failed to update lottery %s: %v", id, err)
+			logger.ErrorContext(c.Context(), "failed to update lottery", "error", err)
 			return SendInternalError(c)
 		}
 	}
@@ -267,16 +391,26 @@ func (h *Handler) updateLottery(c fiber.Ctx) error {
 func (h *Handler) joinLottery(c fiber.Ctx) error {
 	id := c.Params("id")
 
-	var req JoinRequest
-	if err := c.Bind().Body(&req); err != nil {
-		return SendError(c, fiber.StatusBadRequest, ERR_BAD_REQUEST, "Invalid request body")
+	user := initDataUser(c)
+	if user == nil {
+		return SendError(c, fiber.StatusUnauthorized, ERR_UNAUTHORIZED, "Telegram init data required")
+	}
+
+	if len(c.Body()) > 0 {
+		var req JoinRequest
+		if err := c.Bind().Body(&req); err != nil {
+			return SendError(c, fiber.StatusBadRequest, ERR_BAD_REQUEST, "Invalid request body")
+		}
+		if req.UserID != 0 || req.Username != "" || req.FirstName != "" || req.LastName != "" {
+			return SendError(c, fiber.StatusBadRequest, ERR_BAD_REQUEST, "User identity must not be set in the request body")
+		}
 	}
 
 	_, participant, err := h.service.JoinLottery(id, service.JoinInput{
-		UserID:    req.UserID,
-		Username:  req.Username,
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
+		UserID:    user.ID,
+		Username:  user.Username,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
 	})
 	if err != nil {
 		switch {
@@ -288,8 +422,10 @@ func (h *Handler) joinLottery(c fiber.Ctx) error {
 			return SendError(c, fiber.StatusBadRequest, ERR_LOTTERY_FULL, "Lottery is full")
 		case errors.Is(err, service.ErrParticipantExists):
 			return SendError(c, fiber.StatusConflict, ERR_CONFLICT, "User already joined")
+		case errors.Is(err, service.ErrUserBanned):
+			return SendError(c, fiber.StatusForbidden, ERR_USER_BANNED, "User is banned")
 		default:
-			logger.Errorf("failed to join lottery %s: %v", id, err)
+			logger.ErrorContext(c.Context(), "failed to join lottery", "lottery_id", id, "error", err)
 			return SendInternalError(c)
 		}
 	}
@@ -309,7 +445,7 @@ func (h *Handler) addParticipant(c fiber.Ctx) error {
 		return SendError(c, fiber.StatusBadRequest, ERR_BAD_REQUEST, "User ID is required")
 	}
 
-	participant, err := h.service.AddParticipant(id, service.JoinInput{
+	participant, err := h.service.AddParticipant(id, sessionUser(c), service.JoinInput{
 		UserID: req.UserID,
 	})
 	if err != nil {
@@ -320,8 +456,10 @@ func (h *Handler) addParticipant(c fiber.Ctx) error {
 			return SendError(c, fiber.StatusBadRequest, ERR_LOTTERY_ENDED, "Lottery already completed")
 		case errors.Is(err, service.ErrParticipantExists):
 			return SendError(c, fiber.StatusConflict, ERR_CONFLICT, "User already joined")
+		case errors.Is(err, service.ErrUserBanned):
+			return SendError(c, fiber.StatusForbidden, ERR_USER_BANNED, "User is banned")
 		default:
-			logger.Errorf("failed to add participant locally lottery=%s: %v", id, err)
+			logger.ErrorContext(c.Context(), "failed to add participant", "participant_id", req.UserID, "error", err)
 			return SendInternalError(c)
 		}
 	}
@@ -329,16 +467,35 @@ func (h *Handler) addParticipant(c fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(participant)
 }
 
+// getParticipants returns one page of participants. Pass next_cursor from the
+// response as "cursor", with the same q, sort, order and filter, to get the
+// next page.
 func (h *Handler) getParticipants(c fiber.Ctx) error {
 	id := c.Params("id")
 
-	participants, err := h.service.GetParticipants(id)
+	limit, err := strconv.Atoi(c.Query("limit", "0"))
+	if err != nil || limit < 0 {
+		return SendError(c, fiber.StatusBadRequest, ERR_BAD_REQUEST, "Invalid limit")
+	}
+
+	page, err := h.service.ListParticipants(models.ParticipantQuery{
+		LotteryID: id,
+		Search:    c.Query("q"),
+		Sort:      c.Query("sort"),
+		Desc:      c.Query("order") == "desc",
+		Filter:    c.Query("filter"),
+		Cursor:    c.Query("cursor"),
+		Limit:     limit,
+	})
 	if err != nil {
-		logger.Errorf("failed to get participants for lottery %s: %v", id, err)
+		if errors.Is(err, service.ErrInvalidQuery) {
+			return SendError(c, fiber.StatusBadRequest, ERR_BAD_REQUEST, "Invalid sort, filter or cursor")
+		}
+		logger.ErrorContext(c.Context(), "failed to list participants", "error", err)
 		return SendInternalError(c)
 	}
 
-	return c.JSON(participants)
+	return c.JSON(page)
 }
 
 func (h *Handler) updateParticipantWeight(c fiber.Ctx) error {
@@ -357,8 +514,11 @@ func (h *Handler) updateParticipantWeight(c fiber.Ctx) error {
 		return SendError(c, fiber.StatusBadRequest, ERR_BAD_REQUEST, "Weight must be non-negative")
 	}
 
-	if err := h.service.UpdateParticipantWeight(lotteryID, userID, req.Weight); err != nil {
-		logger.Errorf("failed to update participant weight lottery=%s user=%d: %v", lotteryID, userID, err)
+	if err := h.service.UpdateParticipantWeight(lotteryID, sessionUser(c), userID, req.Weight); err != nil {
+		if errors.Is(err, service.ErrParticipantNotFound) {
+			return SendError(c, fiber.StatusNotFound, ERR_NOT_FOUND, "Participant not found")
+		}
+		logger.ErrorContext(c.Context(), "failed to update participant weight", "participant_id", userID, "error", err)
 		return SendInternalError(c)
 	}
 
@@ -379,8 +539,8 @@ func (h *Handler) deletePrizeWeight(c fiber.Ctx) error {
 		return SendError(c, fiber.StatusBadRequest, ERR_BAD_REQUEST, "Invalid prize ID")
 	}
 
-	if err := h.service.DeletePrizeWeight(lotteryID, userID, prizeID); err != nil {
-		logger.Errorf("failed to delete prize weight lottery=%s user=%d prize=%d: %v", lotteryID, userID, prizeID, err)
+	if err := h.service.DeletePrizeWeight(lotteryID, sessionUser(c), userID, prizeID); err != nil {
+		logger.ErrorContext(c.Context(), "failed to delete prize weight", "participant_id", userID, "prize_id", prizeID, "error", err)
 		return SendInternalError(c)
 	}
 
@@ -403,8 +563,18 @@ func (h *Handler) updatePrizeWeight(c fiber.Ctx) error {
 		return SendError(c, fiber.StatusBadRequest, ERR_BAD_REQUEST, "Weight must be non-negative")
 	}
 
-	if err := h.service.SetPrizeWeight(lotteryID, userID, req.PrizeID, req.Weight); err != nil {
-		logger.Errorf("failed to set prize weight lottery=%s user=%d prize=%d: %v", lotteryID, userID, req.PrizeID, err)
+	if err := h.service.SetPrizeWeight(lotteryID, sessionUser(c), userID, req.PrizeID, req.Weight); err != nil {
+		switch {
+		case errors.Is(err, service.ErrLotteryNotFound):
+			return SendError(c, fiber.StatusNotFound, ERR_NOT_FOUND, "Lottery not found")
+		case errors.Is(err, service.ErrLotteryEnded):
+			return SendError(c, fiber.StatusBadRequest, ERR_LOTTERY_ENDED, "Lottery already completed")
+		case errors.Is(err, service.ErrParticipantNotFound):
+			return SendError(c, fiber.StatusNotFound, ERR_NOT_FOUND, "Participant not found")
+		case errors.Is(err, service.ErrPrizeNotFound):
+			return SendError(c, fiber.StatusNotFound, ERR_NOT_FOUND, "Prize not found")
+		}
+		logger.ErrorContext(c.Context(), "failed to set prize weight", "participant_id", userID, "prize_id", req.PrizeID, "error", err)
 		return SendInternalError(c)
 	}
 
@@ -419,8 +589,11 @@ func (h *Handler) removeParticipant(c fiber.Ctx) error {
 		return SendError(c, fiber.StatusBadRequest, ERR_BAD_REQUEST, "Invalid user ID")
 	}
 
-	if err := h.service.RemoveParticipant(lotteryID, userID); err != nil {
-		logger.Errorf("failed to remove participant lottery=%s user=%d: %v", lotteryID, userID, err)
+	if err := h.service.RemoveParticipant(lotteryID, sessionUser(c), userID); err != nil {
+		if errors.Is(err, service.ErrParticipantNotFound) {
+			return SendError(c, fiber.StatusNotFound, ERR_NOT_FOUND, "Participant not found")
+		}
+		logger.ErrorContext(c.Context(), "failed to remove participant", "participant_id", userID, "error", err)
 		return SendInternalError(c)
 	}
 
@@ -438,7 +611,7 @@ func (h *Handler) getResults(c fiber.Ctx) error {
 		case errors.Is(err, service.ErrLotteryNotDrawn):
 			return SendError(c, fiber.StatusBadRequest, ERR_LOTTERY_NOT_ACTIVE, "Lottery not yet drawn")
 		default:
-			logger.Errorf("failed to get results for lottery %s: %v", id, err)
+			logger.ErrorContext(c.Context(), "failed to get results", "lottery_id", id, "error", err)
 			return SendInternalError(c)
 		}
 	}
@@ -449,7 +622,7 @@ func (h *Handler) getResults(c fiber.Ctx) error {
 func (h *Handler) drawLottery(c fiber.Ctx) error {
 	id := c.Params("id")
 
-	winners, err := h.service.DrawLottery(id)
+	winners, err := h.service.DrawLottery(id, sessionUser(c))
 	if err != nil {
 		switch {
 		case errors.Is(err, service.ErrLotteryNotFound):
@@ -459,7 +632,7 @@ func (h *Handler) drawLottery(c fiber.Ctx) error {
 		case errors.Is(err, service.ErrLotteryNotActive):
 			return SendError(c, fiber.StatusBadRequest, ERR_LOTTERY_NOT_ACTIVE, "Lottery is not active")
 		default:
-			logger.Errorf("failed to draw lottery %s: %v", id, err)
+			logger.ErrorContext(c.Context(), "failed to draw lottery", "error", err)
 			return SendInternalError(c)
 		}
 	}
@@ -467,11 +640,98 @@ func (h *Handler) drawLottery(c fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true, "winners": winners})
 }
 
-func StartServer(svc *service.LotteryService) {
+// auditReaderAuth lets owners in with their edit session and bot admins with
+// Mini App init data. Whether an init data user is an admin is checked by the
+// service.
+func auditReaderAuth(miniAppAuth, ownerOnly fiber.Handler) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if strings.HasPrefix(c.Get(fiber.HeaderAuthorization), initDataAuthScheme) {
+			return miniAppAuth(c)
+		}
+		return ownerOnly(c)
+	}
+}
+
+// getAuditLog pages through the audit log, newest first. Pass the smallest id
+// of the previous page as "before" to get the next one.
+func (h *Handler) getAuditLog(c fiber.Ctx) error {
+	id := c.Params("id")
+
+	requesterID := sessionUser(c)
+	if user := initDataUser(c); user != nil {
+		requesterID = user.ID
+	}
+
+	beforeID, limit, problem := auditPageParams(c)
+	if problem != "" {
+		return SendError(c, fiber.StatusBadRequest, ERR_BAD_REQUEST, problem)
+	}
+
+	entries, err := h.service.GetAuditLog(id, requesterID, beforeID, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrLotteryNotFound):
+			return SendError(c, fiber.StatusNotFound, ERR_NOT_FOUND, "Lottery not found")
+		case errors.Is(err, service.ErrPermissionDenied):
+			return SendError(c, fiber.StatusForbidden, ERR_FORBIDDEN, "Only owners and admins can read the audit log")
+		default:
+			logger.ErrorContext(c.Context(), "failed to get audit log", "lottery_id", id, "error", err)
+			return SendInternalError(c)
+		}
+	}
+
+	return c.JSON(fiber.Map{"entries": entries})
+}
+
+// getFullAuditLog pages through the audit log of every lottery, including
+// the entries that belong to none such as bans, for bot admins.
+func (h *Handler) getFullAuditLog(c fiber.Ctx) error {
+	user := initDataUser(c)
+	if user == nil {
+		return SendError(c, fiber.StatusUnauthorized, ERR_UNAUTHORIZED, "Telegram init data required")
+	}
+
+	beforeID, limit, problem := auditPageParams(c)
+	if problem != "" {
+		return SendError(c, fiber.StatusBadRequest, ERR_BAD_REQUEST, problem)
+	}
+
+	entries, err := h.service.GetFullAuditLog(user.ID, beforeID, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrPermissionDenied) {
+			return SendError(c, fiber.StatusForbidden, ERR_FORBIDDEN, "Only admins can read the full audit log")
+		}
+		logger.ErrorContext(c.Context(), "failed to get full audit log", "error", err)
+		return SendInternalError(c)
+	}
+
+	return c.JSON(fiber.Map{"entries": entries})
+}
+
+// auditPageParams reads the before cursor and limit of an audit log page, or
+// describes what is wrong with them.
+func auditPageParams(c fiber.Ctx) (beforeID int64, limit int, problem string) {
+	beforeID, err := strconv.ParseInt(c.Query("before", "0"), 10, 64)
+	if err != nil || beforeID < 0 {
+		return 0, 0, "Invalid before cursor"
+	}
+	limit, err = strconv.Atoi(c.Query("limit", "0"))
+	if err != nil || limit < 0 {
+		return 0, 0, "Invalid limit"
+	}
+	return beforeID, limit, ""
+}
+
+// StartServer serves the web app and API. When webhook is not nil, Telegram
+// updates are also accepted on WebhookPath.
+func StartServer(svc *service.LotteryService, cfg *config.Config, webhook *Webhook) {
 	app := fiber.New(fiber.Config{AppName: "Lucky TG Bot API"})
 	app.Use(recover.New())
-	app.Use(compress.New(compress.Config{Level: compress.LevelBestSpeed}))
-	app.Use(etag.New())
+	app.Use(requestid.New())
+	app.Use(requestLogger)
+	app.Use(requestMetrics)
+	app.Use(compress.New(compress.Config{Level: compress.LevelBestSpeed, Next: isStreamed}))
+	app.Use(etag.New(etag.Config{Next: isStreamed}))
 	app.Use(favicon.New(favicon.Config{File: "./web/dist/favicon.ico"}))
 
 	app.Get(healthcheck.LivenessEndpoint, healthcheck.New())
@@ -479,12 +739,16 @@ func StartServer(svc *service.LotteryService) {
 		Probe: func(c fiber.Ctx) bool {
 			ctx, cancel := context.WithTimeout(c.Context(), readinessTimeout)
 			defer cancel()
-			return database.GetDB().PingContext(ctx) == nil
+			return svc.Ping(ctx) == nil
 		},
 	}))
 
+	if webhook != nil {
+		app.Post(WebhookPath, webhook.handle)
+	}
+
 	app.Use("/assets", static.New("./web/dist/assets"))
-	SetupRoutes(app, svc)
+	SetupRoutes(app, svc, cfg)
 	app.Get("/robots.txt", func(c fiber.Ctx) error {
 		return c.SendFile("./web/dist/robots.txt")
 	})
@@ -492,13 +756,24 @@ func StartServer(svc *service.LotteryService) {
 		return c.SendFile("./web/dist/index.html")
 	})
 
-	port := os.Getenv("API_PORT")
-	if port == "" {
-		port = "3000"
+	if cfg.Server.MetricsAddr != "" {
+		go serveMetrics(cfg.Server.MetricsAddr)
+	}
+
+	logger.Info("starting HTTP server", "port", cfg.Server.Port)
+	if err := app.Listen(":" + cfg.Server.Port); err != nil {
+		logger.Fatal("failed to start server", "error", err)
 	}
+}
+
+// serveMetrics serves /metrics on an address of its own, which is meant to be
+// reachable only by the scraper.
+func serveMetrics(addr string) {
+	app := fiber.New(fiber.Config{AppName: "Lucky TG Bot Metrics"})
+	app.Get("/metrics", metrics.Handler())
 
-	logger.Infof("starting HTTP server on port %s", port)
-	if err := app.Listen(":" + port); err != nil {
-		logger.Fatalf("failed to start server: %v", err)
+	logger.Info("starting metrics server", "addr", addr)
+	if err := app.Listen(addr); err != nil {
+		logger.Fatal("failed to start metrics server", "error", err)
 	}
 }
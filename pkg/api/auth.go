@@ -0,0 +1,112 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+const (
+	initDataAuthScheme = "tma "
+	initDataMaxAge     = 24 * time.Hour
+	initDataUserKey    = "init_data_user"
+)
+
+var (
+	errInitDataMissing = errors.New("init data missing")
+	errInitDataInvalid = errors.New("init data signature mismatch")
+	errInitDataExpired = errors.New("init data expired")
+)
+
+// TelegramUser is the identity carried in verified Mini App init data.
+type TelegramUser struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// initDataAuth verifies the Mini App init data sent as "Authorization: tma
+// <initData>" and stores the signed user in the request locals.
+func initDataAuth(botToken string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		header := c.Get(fiber.HeaderAuthorization)
+		if !strings.HasPrefix(header, initDataAuthScheme) {
+			return SendError(c, fiber.StatusUnauthorized, ERR_UNAUTHORIZED, "Telegram init data required")
+		}
+
+		user, err := validateInitData(strings.TrimPrefix(header, initDataAuthScheme), botToken, time.Now())
+		if err != nil {
+			return SendError(c, fiber.StatusUnauthorized, ERR_INIT_DATA_INVALID, "Invalid Telegram init data")
+		}
+
+		c.Locals(initDataUserKey, user)
+		withLogAttrs(c, "user_id", user.ID)
+		return c.Next()
+	}
+}
+
+func initDataUser(c fiber.Ctx) *TelegramUser {
+	user, _ := c.Locals(initDataUserKey).(*TelegramUser)
+	return user
+}
+
+// validateInitData checks the init data hash as described in
+// https://core.telegram.org/bots/webapps#validating-data-received-via-the-mini-app
+func validateInitData(initData, botToken string, now time.Time) (*TelegramUser, error) {
+	if initData == "" || botToken == "" {
+		return nil, errInitDataMissing
+	}
+
+	values, err := url.ParseQuery(initData)
+	if err != nil {
+		return nil, errInitDataInvalid
+	}
+
+	hash := values.Get("hash")
+	if hash == "" {
+		return nil, errInitDataInvalid
+	}
+
+	pairs := make([]string, 0, len(values))
+	for key := range values {
+		if key == "hash" {
+			continue
+		}
+		pairs = append(pairs, key+"="+values.Get(key))
+	}
+	sort.Strings(pairs)
+
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(botToken))
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte(strings.Join(pairs, "\n")))
+
+	expected, err := hex.DecodeString(hash)
+	if err != nil || !hmac.Equal(mac.Sum(nil), expected) {
+		return nil, errInitDataInvalid
+	}
+
+	authDate, err := strconv.ParseInt(values.Get("auth_date"), 10, 64)
+	if err != nil {
+		return nil, errInitDataInvalid
+	}
+	if now.Sub(time.Unix(authDate, 0)) > initDataMaxAge {
+		return nil, errInitDataExpired
+	}
+
+	var user TelegramUser
+	if err := json.Unmarshal([]byte(values.Get("user")), &user); err != nil || user.ID == 0 {
+		return nil, errInitDataInvalid
+	}
+	return &user, nil
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	"github.com/realSunyz/lucky-tgbot/pkg/service"
+)
+
+// adminBackup takes a database backup for a bot admin signed in through the
+// Mini App. It runs without the write timeout since large databases take a
+// while to copy.
+func (h *Handler) adminBackup(c fiber.Ctx) error {
+	user := initDataUser(c)
+	if user == nil {
+		return SendError(c, fiber.StatusUnauthorized, ERR_UNAUTHORIZED, "Telegram init data required")
+	}
+
+	backup, err := h.service.AdminBackup(user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPermissionDenied):
+			return SendError(c, fiber.StatusForbidden, ERR_FORBIDDEN, "Only admins can take backups")
+		case errors.Is(err, service.ErrBackupsDisabled):
+			return SendError(c, fiber.StatusNotFound, ERR_NOT_FOUND, "Backups are not configured")
+		default:
+			logger.ErrorContext(c.Context(), "failed to back up database", "error", err)
+			return SendInternalError(c)
+		}
+	}
+
+	return c.JSON(backup)
+}
@@ -3,14 +3,18 @@ package api
 import (
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	"github.com/realSunyz/lucky-tgbot/pkg/metrics"
 	"github.com/realSunyz/lucky-tgbot/pkg/service"
 )
 
-func StartTimedDrawChecker(svc *service.LotteryService) {
+func StartTimedDrawChecker(svc *service.LotteryService, interval time.Duration) {
 	run := func() {
+		timer := prometheus.NewTimer(metrics.AutoDrawCheckDuration)
+		defer timer.ObserveDuration()
 		if err := svc.CheckAutoDrawLotteries(); err != nil {
-			logger.Errorf("auto draw checker failed: %v", err)
+			logger.Error("auto draw checker failed", "error", err)
 		}
 	}
 
@@ -18,7 +22,7 @@ func StartTimedDrawChecker(svc *service.LotteryService) {
 		// Run once on startup to avoid waiting for the first tick.
 		run()
 
-		ticker := time.NewTicker(1 * time.Minute)
+		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 		for range ticker.C {
 			run()
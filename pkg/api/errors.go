@@ -19,6 +19,9 @@ const (
 	ERR_TOKEN_INVALID      = "ERR_TOKEN_INVALID"
 	ERR_RATE_LIMITED       = "ERR_RATE_LIMITED"
 	ERR_REQUEST_TIMEOUT    = "ERR_REQUEST_TIMEOUT"
+	ERR_INIT_DATA_INVALID  = "ERR_INIT_DATA_INVALID"
+	ERR_FORBIDDEN          = "ERR_FORBIDDEN"
+	ERR_USER_BANNED        = "ERR_USER_BANNED"
 )
 
 func SendError(c fiber.Ctx, status int, code string, message string) error {
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/realSunyz/lucky-tgbot/pkg/events"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	"github.com/realSunyz/lucky-tgbot/pkg/service"
+)
+
+const (
+	// eventKeepAlive is how often an idle stream sends a comment so proxies
+	// do not close it.
+	eventKeepAlive = 20 * time.Second
+	// eventRetry is how long browsers wait before reconnecting, in ms.
+	eventRetry = 3000
+)
+
+// lotteryEvents streams the public changes to a lottery as Server-Sent Events.
+// The stream ends after the winners are sent or the lottery is deleted, and
+// when the client falls too far behind, in which case it should reload.
+func (h *Handler) lotteryEvents(c fiber.Ctx) error {
+	id := c.Params("id")
+
+	sub, err := h.service.SubscribeEvents(id, c.IP())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrLotteryNotFound):
+			return SendError(c, fiber.StatusNotFound, ERR_NOT_FOUND, "Lottery not found")
+		case errors.Is(err, service.ErrLotteryEnded):
+			return SendError(c, fiber.StatusConflict, ERR_LOTTERY_ENDED, "Lottery already completed")
+		case errors.Is(err, events.ErrClientLimit):
+			return SendError(c, fiber.StatusTooManyRequests, ERR_RATE_LIMITED, "Too many open event streams from this client")
+		case errors.Is(err, events.ErrTooManySubscribers), errors.Is(err, events.ErrBrokerFull):
+			return SendError(c, fiber.StatusServiceUnavailable, ERR_RATE_LIMITED, "Too many open event streams")
+		default:
+			logger.ErrorContext(c.Context(), "failed to subscribe to lottery events", "lottery_id", id, "error", err)
+			return SendInternalError(c)
+		}
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	// Keep nginx from buffering the stream
+	c.Set("X-Accel-Buffering", "no")
+
+	ctx := c.Context()
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+
+		fmt.Fprintf(w, "retry: %d\n\n", eventRetry)
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		keepAlive := time.NewTicker(eventKeepAlive)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case event, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				if err := writeEvent(w, event); err != nil {
+					logger.DebugContext(ctx, "event stream closed", "lottery_id", id, "error", err)
+					return
+				}
+				if event.Type == events.TypeWinners || event.Type == events.TypeDeleted {
+					return
+				}
+			case <-keepAlive.C:
+				if _, err := w.WriteString(": keep-alive\n\n"); err != nil {
+					return
+				}
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		}
+	})
+}
+
+func writeEvent(w *bufio.Writer, event events.Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// isStreamed reports whether the response to c is written as a stream, which
+// middleware must not buffer.
+func isStreamed(c fiber.Ctx) bool {
+	path := c.Path()
+	return strings.HasSuffix(path, "/export") || strings.HasSuffix(path, "/events")
+}
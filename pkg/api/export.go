@@ -0,0 +1,34 @@
+package api
+
+import (
+	"bufio"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	"github.com/realSunyz/lucky-tgbot/pkg/service"
+)
+
+// exportLottery streams the participants or winners of a lottery as a file
+// download. Errors after the first byte is sent can only be logged.
+func (h *Handler) exportLottery(c fiber.Ctx) error {
+	id := c.Params("id")
+	format := strings.ToLower(c.Query("format", service.ExportFormatCSV))
+	what := strings.ToLower(c.Query("what", service.ExportParticipants))
+	if err := service.ValidateExport(format, what); err != nil {
+		return SendError(c, fiber.StatusBadRequest, ERR_BAD_REQUEST, "format must be csv or json and what must be participants or winners")
+	}
+
+	c.Attachment(service.ExportFilename(id, format, what))
+	if format == service.ExportFormatCSV {
+		// Fiber's CSV content type would carry the charset twice.
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	}
+
+	ctx := c.Context()
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		if err := h.service.Export(w, id, format, what); err != nil {
+			logger.ErrorContext(ctx, "failed to export lottery", "lottery_id", id, "format", format, "what", what, "error", err)
+		}
+	})
+}
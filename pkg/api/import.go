@@ -0,0 +1,63 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	"github.com/realSunyz/lucky-tgbot/pkg/service"
+)
+
+// importParticipants adds participants from a CSV or JSON body, chosen by the
+// format query parameter or else the content type, and returns a per-row
+// report.
+func (h *Handler) importParticipants(c fiber.Ctx) error {
+	id := c.Params("id")
+
+	format := strings.ToLower(c.Query("format"))
+	if format == "" {
+		format = service.ExportFormatJSON
+		if strings.HasPrefix(c.Get(fiber.HeaderContentType), "text/csv") {
+			format = service.ExportFormatCSV
+		}
+	}
+
+	var rows []service.ImportRow
+	var err error
+	switch format {
+	case service.ExportFormatCSV:
+		rows, err = service.ParseImportCSV(bytes.NewReader(c.Body()))
+	case service.ExportFormatJSON:
+		rows, err = service.ParseImportJSON(bytes.NewReader(c.Body()))
+	default:
+		return SendError(c, fiber.StatusBadRequest, ERR_BAD_REQUEST, "format must be csv or json")
+	}
+	if err != nil {
+		if errors.Is(err, service.ErrImportTooLarge) {
+			return SendError(c, fiber.StatusRequestEntityTooLarge, ERR_BAD_REQUEST, "Too many rows to import")
+		}
+		return SendError(c, fiber.StatusBadRequest, ERR_BAD_REQUEST, err.Error())
+	}
+	if len(rows) == 0 {
+		return SendError(c, fiber.StatusBadRequest, ERR_BAD_REQUEST, "No participants to import")
+	}
+
+	report, err := h.service.ImportParticipants(id, sessionUser(c), rows)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrLotteryNotFound):
+			return SendError(c, fiber.StatusNotFound, ERR_NOT_FOUND, "Lottery not found")
+		case errors.Is(err, service.ErrLotteryEnded):
+			return SendError(c, fiber.StatusBadRequest, ERR_LOTTERY_ENDED, "Lottery already completed")
+		default:
+			logger.ErrorContext(c.Context(), "failed to import participants", "lottery_id", id, "rows", len(rows), "error", err)
+			return SendInternalError(c)
+		}
+	}
+
+	logger.InfoContext(c.Context(), "participants imported", "lottery_id", id,
+		"added", report.Added, "duplicates", report.Duplicates, "invalid", report.Invalid)
+	return c.JSON(report)
+}
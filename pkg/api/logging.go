@@ -0,0 +1,46 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+)
+
+// requestLogger stores the request ID in the request context, so every log
+// line written while handling the request carries it, and logs the request
+// once it completes.
+func requestLogger(c fiber.Ctx) error {
+	start := time.Now()
+	c.SetContext(logger.With(c.Context(), "request_id", requestid.FromContext(c)))
+
+	err := c.Next()
+
+	logger.DebugContext(c.Context(), "http request",
+		"method", c.Method(),
+		"path", c.Path(),
+		"status", responseStatus(c, err),
+		"duration", time.Since(start),
+	)
+	return err
+}
+
+// responseStatus returns the status code the error handler will send for err.
+func responseStatus(c fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+// withLogAttrs adds attributes such as the lottery or user ID to the log
+// context of the current request.
+func withLogAttrs(c fiber.Ctx, args ...any) {
+	c.SetContext(logger.With(c.Context(), args...))
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/realSunyz/lucky-tgbot/pkg/metrics"
+)
+
+// requestMetrics records the latency of every request, labelled with the
+// matched route pattern rather than the raw path to keep the label set small.
+func requestMetrics(c fiber.Ctx) error {
+	start := time.Now()
+	err := c.Next()
+
+	metrics.HTTPRequestDuration.
+		WithLabelValues(c.Method(), c.Route().Path, strconv.Itoa(responseStatus(c, err))).
+		Observe(time.Since(start).Seconds())
+	return err
+}
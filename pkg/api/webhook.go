@@ -0,0 +1,41 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/gofiber/fiber/v3"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+)
+
+const (
+	WebhookPath         = "/telegram/webhook"
+	webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+)
+
+// Webhook delivers Telegram updates received on the API server to the bot.
+type Webhook struct {
+	// Ctx outlives the request and is handed to the bot handlers.
+	Ctx         context.Context
+	Bot         *bot.Bot
+	SecretToken string
+}
+
+func (w *Webhook) handle(c fiber.Ctx) error {
+	secret := c.Get(webhookSecretHeader)
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(w.SecretToken)) != 1 {
+		return SendError(c, fiber.StatusUnauthorized, ERR_UNAUTHORIZED, "Invalid webhook secret")
+	}
+
+	var update tgmodels.Update
+	if err := json.Unmarshal(c.Body(), &update); err != nil {
+		logger.WarnContext(c.Context(), "failed to decode webhook update", "error", err)
+		return SendError(c, fiber.StatusBadRequest, ERR_BAD_REQUEST, "Invalid update")
+	}
+
+	w.Bot.ProcessUpdate(w.Ctx, &update)
+	return c.SendStatus(fiber.StatusOK)
+}
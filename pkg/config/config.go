@@ -0,0 +1,348 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	UpdateModePolling = "polling"
+	UpdateModeWebhook = "webhook"
+
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+
+	DatabaseDriverSQLite   = "sqlite"
+	DatabaseDriverPostgres = "postgres"
+)
+
+// Config holds every deployment setting. Values come from the defaults, then
+// the YAML file, then environment variables.
+type Config struct {
+	Telegram TelegramConfig `yaml:"telegram"`
+	Database DatabaseConfig `yaml:"database"`
+	Server   ServerConfig   `yaml:"server"`
+	Lottery  LotteryConfig  `yaml:"lottery"`
+	Worker   WorkerConfig   `yaml:"worker"`
+	Log      LogConfig      `yaml:"log"`
+}
+
+type TelegramConfig struct {
+	Token         string  `yaml:"token"`
+	AdminUserIDs  []int64 `yaml:"admin_user_ids"`
+	UpdateMode    string  `yaml:"update_mode"`
+	WebhookURL    string  `yaml:"webhook_url"`
+	WebhookSecret string  `yaml:"webhook_secret"`
+}
+
+type DatabaseConfig struct {
+	// Driver is sqlite or postgres. Several bot replicas can share one
+	// PostgreSQL database, while SQLite is limited to a single process.
+	Driver string `yaml:"driver"`
+	// Path is the SQLite database file.
+	Path string `yaml:"path"`
+	// URL is the PostgreSQL connection string.
+	URL string `yaml:"url"`
+	// MaxOpenConns caps the PostgreSQL connection pool.
+	MaxOpenConns int `yaml:"max_open_conns"`
+	// Backup configures snapshots of the SQLite database.
+	Backup BackupConfig `yaml:"backup"`
+}
+
+// BackupConfig enables SQLite backups when Dir is set. Backups are taken
+// every Interval, or only on demand when it is zero, and the newest Keep are
+// kept.
+type BackupConfig struct {
+	Dir      string        `yaml:"dir"`
+	Interval time.Duration `yaml:"interval"`
+	Keep     int           `yaml:"keep"`
+}
+
+type ServerConfig struct {
+	Port string `yaml:"port"`
+	// MetricsAddr is where /metrics is served, apart from the public app.
+	// Empty disables it.
+	MetricsAddr  string        `yaml:"metrics_addr"`
+	WebDomain    string        `yaml:"web_domain"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	JoinLimit    RateLimit     `yaml:"join_limit"`
+	EditLimit    RateLimit     `yaml:"edit_limit"`
+	DrawLimit    RateLimit     `yaml:"draw_limit"`
+	// EventStreams caps the open event streams per client, per lottery and
+	// in total.
+	EventStreams StreamLimit `yaml:"event_streams"`
+}
+
+// RateLimit allows Max requests per client and lottery within Window.
+type RateLimit struct {
+	Max    int           `yaml:"max"`
+	Window time.Duration `yaml:"window"`
+}
+
+type StreamLimit struct {
+	PerClient  int `yaml:"per_client"`
+	PerLottery int `yaml:"per_lottery"`
+	Total      int `yaml:"total"`
+}
+
+type LotteryConfig struct {
+	CreateCooldown    time.Duration `yaml:"create_cooldown"`
+	MaxDailyCreates   int           `yaml:"max_daily_creates"`
+	CreateTokenTTL    time.Duration `yaml:"create_token_ttl"`
+	EditSessionTTL    time.Duration `yaml:"edit_session_ttl"`
+	DraftExpiry       time.Duration `yaml:"draft_expiry"`
+	DrawCheckInterval time.Duration `yaml:"draw_check_interval"`
+}
+
+type WorkerConfig struct {
+	CleanupInterval time.Duration `yaml:"cleanup_interval"`
+}
+
+type LogConfig struct {
+	// Level is one of debug, info, warn or error.
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// Default returns the settings the bot used before they were configurable.
+func Default() *Config {
+	return &Config{
+		Telegram: TelegramConfig{
+			UpdateMode: UpdateModePolling,
+		},
+		Database: DatabaseConfig{
+			Driver:       DatabaseDriverSQLite,
+			Path:         "lottery.db",
+			MaxOpenConns: 10,
+			Backup: BackupConfig{
+				Interval: 24 * time.Hour,
+				Keep:     7,
+			},
+		},
+		Server: ServerConfig{
+			Port:         "3000",
+			MetricsAddr:  "127.0.0.1:9091",
+			WriteTimeout: 8 * time.Second,
+			JoinLimit:    RateLimit{Max: 60, Window: time.Minute},
+			EditLimit:    RateLimit{Max: 30, Window: time.Minute},
+			DrawLimit:    RateLimit{Max: 20, Window: time.Minute},
+			EventStreams: StreamLimit{PerClient: 10, PerLottery: 200, Total: 2000},
+		},
+		Lottery: LotteryConfig{
+			CreateCooldown:    time.Minute,
+			MaxDailyCreates:   10,
+			CreateTokenTTL:    30 * time.Minute,
+			EditSessionTTL:    time.Hour,
+			DraftExpiry:       time.Hour,
+			DrawCheckInterval: time.Minute,
+		},
+		Worker: WorkerConfig{
+			CleanupInterval: time.Hour,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: LogFormatText,
+		},
+	}
+}
+
+// Load reads the YAML file at path, if any, applies environment overrides and
+// validates the result. An empty path skips the file.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		// Unknown keys are rejected so a misspelt setting is not silently
+		// left at its default
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	cfg.normalize()
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) applyEnv() error {
+	setString := func(key string, target *string) {
+		if value, ok := os.LookupEnv(key); ok && value != "" {
+			*target = value
+		}
+	}
+
+	setString("TELEGRAM_BOT_TOKEN", &c.Telegram.Token)
+	setString("BOT_UPDATE_MODE", &c.Telegram.UpdateMode)
+	setString("WEBHOOK_URL", &c.Telegram.WebhookURL)
+	setString("WEBHOOK_SECRET", &c.Telegram.WebhookSecret)
+	setString("DATABASE_DRIVER", &c.Database.Driver)
+	setString("DATABASE_PATH", &c.Database.Path)
+	setString("DATABASE_URL", &c.Database.URL)
+	setString("BACKUP_DIR", &c.Database.Backup.Dir)
+	setString("API_PORT", &c.Server.Port)
+	setString("METRICS_ADDR", &c.Server.MetricsAddr)
+	setString("WEB_DOMAIN", &c.Server.WebDomain)
+	setString("LOG_LEVEL", &c.Log.Level)
+	setString("LOG_FORMAT", &c.Log.Format)
+
+	if raw, ok := os.LookupEnv("ADMIN_USER_IDS"); ok && raw != "" {
+		ids, err := parseUserIDs(raw)
+		if err != nil {
+			return fmt.Errorf("invalid ADMIN_USER_IDS: %w", err)
+		}
+		c.Telegram.AdminUserIDs = ids
+	}
+	return nil
+}
+
+func (c *Config) normalize() {
+	c.Server.WebDomain = strings.TrimSuffix(c.Server.WebDomain, "/")
+	c.Telegram.WebhookURL = strings.TrimSuffix(c.Telegram.WebhookURL, "/")
+	if c.Telegram.WebhookURL == "" {
+		// The webhook is served by the same app as the web pages
+		c.Telegram.WebhookURL = c.Server.WebDomain
+	}
+	c.Database.Driver = strings.ToLower(c.Database.Driver)
+	c.Log.Level = strings.ToLower(c.Log.Level)
+	c.Log.Format = strings.ToLower(c.Log.Format)
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Telegram.Token == "" {
+		errs = append(errs, errors.New("telegram.token (TELEGRAM_BOT_TOKEN) is required"))
+	}
+	switch c.Telegram.UpdateMode {
+	case UpdateModePolling:
+	case UpdateModeWebhook:
+		if c.Telegram.WebhookURL == "" {
+			errs = append(errs, errors.New("telegram.webhook_url or server.web_domain is required in webhook mode"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("telegram.update_mode must be %q or %q, got %q", UpdateModePolling, UpdateModeWebhook, c.Telegram.UpdateMode))
+	}
+
+	switch c.Database.Driver {
+	case DatabaseDriverSQLite:
+		if c.Database.Path == "" {
+			errs = append(errs, errors.New("database.path must not be empty"))
+		}
+	case DatabaseDriverPostgres:
+		if c.Database.URL == "" {
+			errs = append(errs, errors.New("database.url (DATABASE_URL) is required for postgres"))
+		}
+		if c.Database.MaxOpenConns <= 0 {
+			errs = append(errs, errors.New("database.max_open_conns must be positive"))
+		}
+		if c.Database.Backup.Dir != "" {
+			errs = append(errs, errors.New("database.backup is only supported with sqlite; back up PostgreSQL with its own tools"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("database.driver must be %q or %q, got %q", DatabaseDriverSQLite, DatabaseDriverPostgres, c.Database.Driver))
+	}
+	if c.Database.Backup.Interval < 0 {
+		errs = append(errs, errors.New("database.backup.interval must not be negative"))
+	}
+	if c.Database.Backup.Keep <= 0 {
+		errs = append(errs, errors.New("database.backup.keep must be positive"))
+	}
+
+	if _, err := strconv.ParseUint(c.Server.Port, 10, 16); err != nil {
+		errs = append(errs, fmt.Errorf("server.port %q is not a valid port", c.Server.Port))
+	}
+	if c.Server.WriteTimeout <= 0 {
+		errs = append(errs, errors.New("server.write_timeout must be positive"))
+	}
+	limits := []struct {
+		name  string
+		limit RateLimit
+	}{
+		{"join_limit", c.Server.JoinLimit},
+		{"edit_limit", c.Server.EditLimit},
+		{"draw_limit", c.Server.DrawLimit},
+	}
+	for _, l := range limits {
+		if l.limit.Max <= 0 || l.limit.Window <= 0 {
+			errs = append(errs, fmt.Errorf("server.%s needs a positive max and window", l.name))
+		}
+	}
+
+	if c.Server.EventStreams.PerLottery <= 0 || c.Server.EventStreams.Total < c.Server.EventStreams.PerLottery {
+		errs = append(errs, errors.New("server.event_streams needs a positive per_lottery no greater than total"))
+	}
+	if c.Server.EventStreams.PerClient <= 0 {
+		errs = append(errs, errors.New("server.event_streams.per_client must be positive"))
+	}
+
+	if c.Lottery.CreateCooldown < 0 {
+		errs = append(errs, errors.New("lottery.create_cooldown must not be negative"))
+	}
+	if c.Lottery.MaxDailyCreates <= 0 {
+		errs = append(errs, errors.New("lottery.max_daily_creates must be positive"))
+	}
+	if c.Lottery.CreateTokenTTL <= 0 {
+		errs = append(errs, errors.New("lottery.create_token_ttl must be positive"))
+	}
+	if c.Lottery.EditSessionTTL <= 0 {
+		errs = append(errs, errors.New("lottery.edit_session_ttl must be positive"))
+	}
+	if c.Lottery.DraftExpiry < c.Lottery.CreateTokenTTL {
+		errs = append(errs, errors.New("lottery.draft_expiry must not be shorter than lottery.create_token_ttl"))
+	}
+	if c.Lottery.DrawCheckInterval <= 0 {
+		errs = append(errs, errors.New("lottery.draw_check_interval must be positive"))
+	}
+
+	if c.Worker.CleanupInterval <= 0 {
+		errs = append(errs, errors.New("worker.cleanup_interval must be positive"))
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		errs = append(errs, fmt.Errorf("log.level must be debug, info, warn or error, got %q", c.Log.Level))
+	}
+	if c.Log.Format != LogFormatText && c.Log.Format != LogFormatJSON {
+		errs = append(errs, fmt.Errorf("log.format must be %q or %q, got %q", LogFormatText, LogFormatJSON, c.Log.Format))
+	}
+
+	return errors.Join(errs...)
+}
+
+func parseUserIDs(raw string) ([]int64, error) {
+	var ids []int64
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a user ID", field)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
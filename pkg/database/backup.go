@@ -0,0 +1,130 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+)
+
+// Snapshot writes a consistent copy of the SQLite database at dbPath to
+// path, which must not exist yet. It reads through a connection of its own,
+// so with WAL enabled the app keeps reading and writing meanwhile.
+func Snapshot(dbPath, path string) error {
+	db, err := openReadOnly(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	return nil
+}
+
+// CheckIntegrity fails unless PRAGMA integrity_check finds the SQLite
+// database at path intact.
+func CheckIntegrity(path string) error {
+	db, err := openReadOnly(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return fmt.Errorf("failed to check integrity: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return err
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("integrity check failed: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Restore replaces the SQLite database at dbPath with the snapshot once the
+// snapshot passes an integrity check. It must run before the database is
+// opened. The replaced database is kept next to it with a .before-restore
+// suffix.
+func Restore(snapshot, dbPath string) error {
+	if err := CheckIntegrity(snapshot); err != nil {
+		return fmt.Errorf("snapshot %s is not usable: %w", snapshot, err)
+	}
+
+	// Copy first so a failed copy leaves the current database untouched
+	tmpPath := dbPath + ".restore"
+	if err := copyFile(snapshot, tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to copy snapshot: %w", err)
+	}
+
+	// The WAL moves with the replaced database, so it is not applied to the
+	// snapshot and the kept copy stays complete
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		_ = os.Remove(dbPath + ".before-restore" + suffix)
+		err := os.Rename(dbPath+suffix, dbPath+".before-restore"+suffix)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			_ = os.Remove(tmpPath)
+			return fmt.Errorf("failed to move the current database aside: %w", err)
+		}
+	}
+	if err := os.Rename(tmpPath, dbPath); err != nil {
+		return fmt.Errorf("failed to move the snapshot into place: %w", err)
+	}
+
+	logger.Info("database restored from snapshot", "snapshot", snapshot, "path", dbPath)
+	return nil
+}
+
+func openReadOnly(path string) (*sql.DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
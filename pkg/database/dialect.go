@@ -0,0 +1,142 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// dialect holds what differs between the SQL of the supported databases.
+// Queries are written with ? placeholders and in the subset of SQL that both
+// understand; everything else goes through the dialect.
+type dialect struct {
+	name string
+	// migrations is the directory of the dialect's schema migrations.
+	migrations string
+	// schemaMigrations creates the table that records applied migrations.
+	schemaMigrations string
+	// lockSchema and unlockSchema keep several processes from migrating the
+	// same database at once.
+	lockSchema   string
+	unlockSchema string
+	// numberedParams rewrites ? placeholders as $1, $2 and so on.
+	numberedParams bool
+	// like matches a pattern ignoring case.
+	like string
+	// forUpdate is appended to a SELECT that locks the rows it reads until
+	// the transaction ends.
+	forUpdate string
+	// isDistinct is the comparison that treats NULL as an ordinary value.
+	isDistinct string
+	// jsonText extracts a top-level field of a JSON text column.
+	jsonText func(column, key string) string
+	// maintain runs the database's periodic housekeeping.
+	maintain func(q querier) error
+}
+
+// SQLite serializes all access through a single connection, so a transaction
+// already holds the only lock there is.
+var sqliteDialect = &dialect{
+	name:       "sqlite",
+	migrations: "migrations/sqlite",
+	schemaMigrations: `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at DATETIME NOT NULL
+		)
+	`,
+	like:       "LIKE",
+	isDistinct: "IS NOT",
+	jsonText: func(column, key string) string {
+		return fmt.Sprintf("json_extract(%s, '$.%s')", column, key)
+	},
+	maintain: func(q querier) error {
+		if _, err := q.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+			return fmt.Errorf("failed to checkpoint WAL: %w", err)
+		}
+		if _, err := q.Exec(`PRAGMA optimize`); err != nil {
+			return fmt.Errorf("failed to optimize: %w", err)
+		}
+		return nil
+	},
+}
+
+// schemaLockKey is the PostgreSQL advisory lock held while migrating.
+const schemaLockKey = 7315463001
+
+var postgresDialect = &dialect{
+	name:       "postgres",
+	migrations: "migrations/postgres",
+	schemaMigrations: `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL
+		)
+	`,
+	lockSchema:     `SELECT pg_advisory_lock(` + strconv.Itoa(schemaLockKey) + `)`,
+	unlockSchema:   `SELECT pg_advisory_unlock(` + strconv.Itoa(schemaLockKey) + `)`,
+	numberedParams: true,
+	like:           "ILIKE",
+	forUpdate:      " FOR UPDATE",
+	isDistinct:     "IS DISTINCT FROM",
+	jsonText: func(column, key string) string {
+		return fmt.Sprintf("(%s::jsonb ->> '%s')", column, key)
+	},
+	// Autovacuum keeps the planner statistics current.
+	maintain: func(querier) error { return nil },
+}
+
+// rebind rewrites the ? placeholders of query for the dialect. Question marks
+// inside string literals are left alone.
+func (d *dialect) rebind(query string) string {
+	if !d.numberedParams || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+	n := 0
+	inString := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			inString = !inString
+		case c == '?' && !inString:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// boundQuerier rebinds every query for its dialect before running it.
+type boundQuerier struct {
+	q querier
+	d *dialect
+}
+
+func bind(q querier, d *dialect) querier {
+	if !d.numberedParams {
+		return q
+	}
+	return &boundQuerier{q: q, d: d}
+}
+
+func (b *boundQuerier) Exec(query string, args ...any) (sql.Result, error) {
+	return b.q.Exec(b.d.rebind(query), args...)
+}
+
+func (b *boundQuerier) Query(query string, args ...any) (*sql.Rows, error) {
+	return b.q.Query(b.d.rebind(query), args...)
+}
+
+func (b *boundQuerier) QueryRow(query string, args ...any) *sql.Row {
+	return b.q.QueryRow(b.d.rebind(query), args...)
+}
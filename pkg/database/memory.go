@@ -0,0 +1,1094 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/realSunyz/lucky-tgbot/pkg/models"
+)
+
+// MemoryRepository keeps everything in memory. It behaves like the SQLite
+// repository, including cascading deletes, so the service can be exercised
+// without a database file. Transactions hold an exclusive lock and roll back
+// by restoring a copy of the data.
+type MemoryRepository struct {
+	store *memoryStore
+	// inTx is set on the repository passed to InTx, which already holds the
+	// lock.
+	inTx bool
+}
+
+type memoryStore struct {
+	mu    sync.Mutex
+	state *memoryState
+}
+
+type prizeWeightKey struct {
+	lotteryID string
+	userID    int64
+	prizeID   int64
+}
+
+type memorySession struct {
+	models.EditSession
+	linkCodeHash string
+	tokenHash    string
+}
+
+type memoryState struct {
+	lotteries    map[string]models.Lottery
+	prizes       []models.Prize
+	participants []models.Participant
+	prizeWeights map[prizeWeightKey]int
+	winners      []models.Winner
+	organizers   []models.Organizer
+	sessions     []memorySession
+	messages     []models.LotteryMessage
+	bans         map[int64]models.BannedUser
+	audit        []models.AuditEntry
+	lastID       int64
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{store: &memoryStore{state: &memoryState{
+		lotteries:    make(map[string]models.Lottery),
+		prizeWeights: make(map[prizeWeightKey]int),
+		bans:         make(map[int64]models.BannedUser),
+	}}}
+}
+
+// clone copies the state for a rollback. Records are copied by value and the
+// values they point to are never changed in place, so copying the containers
+// is enough.
+func (st *memoryState) clone() *memoryState {
+	return &memoryState{
+		lotteries:    maps.Clone(st.lotteries),
+		prizes:       slices.Clone(st.prizes),
+		participants: slices.Clone(st.participants),
+		prizeWeights: maps.Clone(st.prizeWeights),
+		winners:      slices.Clone(st.winners),
+		organizers:   slices.Clone(st.organizers),
+		sessions:     slices.Clone(st.sessions),
+		messages:     slices.Clone(st.messages),
+		bans:         maps.Clone(st.bans),
+		audit:        slices.Clone(st.audit),
+		lastID:       st.lastID,
+	}
+}
+
+// nextID hands out row IDs. One sequence is shared by every table, which
+// keeps IDs increasing within each of them.
+func (st *memoryState) nextID() int64 {
+	st.lastID++
+	return st.lastID
+}
+
+// lock takes the store lock unless the caller is inside InTx, and returns the
+// state together with the matching unlock.
+func (r *MemoryRepository) lock() (*memoryState, func()) {
+	if r.inTx {
+		return r.store.state, func() {}
+	}
+	r.store.mu.Lock()
+	return r.store.state, r.store.mu.Unlock
+}
+
+func (r *MemoryRepository) InTx(fn func(tx LotteryRepository) error) error {
+	if r.inTx {
+		return fn(r)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	snapshot := r.store.state.clone()
+	if err := fn(&MemoryRepository{store: r.store, inTx: true}); err != nil {
+		r.store.state = snapshot
+		return err
+	}
+	return nil
+}
+
+func (r *MemoryRepository) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (r *MemoryRepository) Maintain() error {
+	return nil
+}
+
+func (r *MemoryRepository) GenerateLotteryID() (string, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	for i := 0; i < 10; i++ {
+		id := fmt.Sprintf("%06d", time.Now().UnixNano()%1000000)
+		if _, exists := st.lotteries[id]; !exists {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate unique lottery ID")
+}
+
+func (r *MemoryRepository) CreateLottery(lottery *models.Lottery) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	if _, exists := st.lotteries[lottery.ID]; exists {
+		return fmt.Errorf("lottery %s already exists", lottery.ID)
+	}
+	if lottery.CreatedAt.IsZero() {
+		lottery.CreatedAt = time.Now().UTC()
+	}
+	if lottery.Status == "" {
+		lottery.Status = "draft"
+	}
+	st.lotteries[lottery.ID] = *lottery
+	return nil
+}
+
+func (r *MemoryRepository) GetLottery(id string) (*models.Lottery, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	lottery, ok := st.lotteries[id]
+	if !ok {
+		return nil, nil
+	}
+	return &lottery, nil
+}
+
+// LockLottery needs no lock of its own, since a transaction holds the whole
+// store.
+func (r *MemoryRepository) LockLottery(id string) (*models.Lottery, error) {
+	return r.GetLottery(id)
+}
+
+func (r *MemoryRepository) UpdateLottery(lottery *models.Lottery) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	current, ok := st.lotteries[lottery.ID]
+	if !ok {
+		return nil
+	}
+	updated := *lottery
+	updated.CreatorID = current.CreatorID
+	updated.CreatedAt = current.CreatedAt
+	st.lotteries[lottery.ID] = updated
+	return nil
+}
+
+func (r *MemoryRepository) DeleteLottery(id string) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	st.deleteLottery(id)
+	return nil
+}
+
+func (st *memoryState) deleteLottery(id string) {
+	delete(st.lotteries, id)
+	inLottery := func(lotteryID string) bool { return lotteryID == id }
+	st.prizes = slices.DeleteFunc(st.prizes, func(p models.Prize) bool { return inLottery(p.LotteryID) })
+	st.participants = slices.DeleteFunc(st.participants, func(p models.Participant) bool { return inLottery(p.LotteryID) })
+	maps.DeleteFunc(st.prizeWeights, func(k prizeWeightKey, _ int) bool { return inLottery(k.lotteryID) })
+	st.winners = slices.DeleteFunc(st.winners, func(w models.Winner) bool { return inLottery(w.LotteryID) })
+	st.organizers = slices.DeleteFunc(st.organizers, func(o models.Organizer) bool { return inLottery(o.LotteryID) })
+	st.sessions = slices.DeleteFunc(st.sessions, func(s memorySession) bool { return inLottery(s.LotteryID) })
+	st.messages = slices.DeleteFunc(st.messages, func(m models.LotteryMessage) bool { return inLottery(m.LotteryID) })
+}
+
+func (r *MemoryRepository) CountUserLotteriesCreatedSince(creatorID int64, since time.Time) (int, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	count := 0
+	for _, l := range st.lotteries {
+		if l.CreatorID == creatorID && !l.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// creatorLotteries returns the lotteries of a creator that match, newest
+// first.
+func (st *memoryState) creatorLotteries(creatorID int64, match func(models.Lottery) bool) []models.Lottery {
+	var lotteries []models.Lottery
+	for _, l := range st.lotteries {
+		if l.CreatorID == creatorID && match(l) {
+			lotteries = append(lotteries, l)
+		}
+	}
+	slices.SortFunc(lotteries, func(a, b models.Lottery) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.ID, a.ID)
+	})
+	return lotteries
+}
+
+func (r *MemoryRepository) SearchCreatorLotteries(creatorID int64, title string, limit int) ([]models.Lottery, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	lotteries := st.creatorLotteries(creatorID, func(l models.Lottery) bool {
+		return l.Status == "active" && containsFold(l.Title, title)
+	})
+	return lotteries[:min(limit, len(lotteries))], nil
+}
+
+func (r *MemoryRepository) ListCreatorLotteries(creatorID int64, limit, offset int) ([]models.Lottery, int, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	lotteries := st.creatorLotteries(creatorID, func(models.Lottery) bool { return true })
+	total := len(lotteries)
+	lotteries = lotteries[min(offset, total):]
+	return lotteries[:min(limit, len(lotteries))], total, nil
+}
+
+func (r *MemoryRepository) DueLotteryIDs(now time.Time) ([]string, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	var ids []string
+	for _, l := range st.lotteries {
+		if l.Status != "active" {
+			continue
+		}
+		timed := l.DrawMode == "timed" && l.DrawTime != nil && !l.DrawTime.After(now)
+		full := l.DrawMode == "full" && l.MaxEntries != nil && l.Participants >= *l.MaxEntries
+		if timed || full {
+			ids = append(ids, l.ID)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (r *MemoryRepository) DeleteDraftsCreatedBefore(cutoff time.Time) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	for _, l := range st.lotteries {
+		if l.Status == "draft" && l.CreatedAt.Before(cutoff) {
+			st.deleteLottery(l.ID)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) GetLotteryStats() (*models.LotteryStats, error) {
+	st, unlock := r.lock()
+	defer unlock()
+	return st.lotteryStats(), nil
+}
+
+func (st *memoryState) lotteryStats() *models.LotteryStats {
+	now := time.Now().UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	stats := &models.LotteryStats{TotalCount: len(st.lotteries)}
+	for _, l := range st.lotteries {
+		switch l.Status {
+		case "draft":
+			stats.DraftCount++
+		case "active":
+			stats.ActiveCount++
+			if l.DrawMode == "timed" && l.DrawTime != nil && l.DrawTime.After(now) {
+				stats.ScheduledCount++
+			}
+		case "completed":
+			stats.CompletedCount++
+		}
+		if !l.CreatedAt.Before(dayStart) {
+			stats.TodayCount++
+		}
+	}
+	return stats
+}
+
+func (r *MemoryRepository) GetAdminStats() (*models.AdminStats, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	creators := make(map[int64]bool)
+	for _, l := range st.lotteries {
+		creators[l.CreatorID] = true
+	}
+	now := time.Now().UTC()
+	sessions := 0
+	for _, s := range st.sessions {
+		if s.ExpiresAt.After(now) {
+			sessions++
+		}
+	}
+
+	return &models.AdminStats{
+		LotteryStats:     *st.lotteryStats(),
+		ParticipantCount: len(st.participants),
+		WinnerCount:      len(st.winners),
+		CreatorCount:     len(creators),
+		EditSessionCount: sessions,
+		BannedUserCount:  len(st.bans),
+	}, nil
+}
+
+func (r *MemoryRepository) GetPrizes(lotteryID string) ([]models.Prize, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	var prizes []models.Prize
+	for _, p := range st.prizes {
+		if p.LotteryID == lotteryID {
+			prizes = append(prizes, p)
+		}
+	}
+	return prizes, nil
+}
+
+func (r *MemoryRepository) ReplacePrizes(lotteryID string, prizes []models.Prize) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	removed := make(map[int64]bool)
+	st.prizes = slices.DeleteFunc(st.prizes, func(p models.Prize) bool {
+		if p.LotteryID == lotteryID {
+			removed[p.ID] = true
+			return true
+		}
+		return false
+	})
+	maps.DeleteFunc(st.prizeWeights, func(k prizeWeightKey, _ int) bool { return removed[k.prizeID] })
+	st.winners = slices.DeleteFunc(st.winners, func(w models.Winner) bool { return removed[w.PrizeID] })
+
+	for _, prize := range prizes {
+		st.prizes = append(st.prizes, models.Prize{ID: st.nextID(), LotteryID: lotteryID, Name: prize.Name, Quantity: prize.Quantity})
+	}
+	return nil
+}
+
+func (st *memoryState) participantIndex(lotteryID string, userID int64) int {
+	return slices.IndexFunc(st.participants, func(p models.Participant) bool {
+		return p.LotteryID == lotteryID && p.UserID == userID
+	})
+}
+
+// withPrizeWeights returns a copy of the participant with their prize weights
+// filled in.
+func (st *memoryState) withPrizeWeights(p models.Participant) models.Participant {
+	p.PrizeWeights = make(map[int64]int)
+	for k, weight := range st.prizeWeights {
+		if k.lotteryID == p.LotteryID && k.userID == p.UserID {
+			p.PrizeWeights[k.prizeID] = weight
+		}
+	}
+	return p
+}
+
+func (st *memoryState) hasPrizeWeights(p models.Participant) bool {
+	for k := range st.prizeWeights {
+		if k.lotteryID == p.LotteryID && k.userID == p.UserID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryRepository) AddParticipant(p *models.Participant) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	if st.participantIndex(p.LotteryID, p.UserID) >= 0 {
+		return ErrParticipantExists
+	}
+	p.ID = st.nextID()
+	p.JoinedAt = time.Now().UTC()
+
+	stored := *p
+	stored.PrizeWeights = nil
+	st.participants = append(st.participants, stored)
+	if l, ok := st.lotteries[p.LotteryID]; ok {
+		l.Participants++
+		st.lotteries[p.LotteryID] = l
+	}
+	return nil
+}
+
+func (r *MemoryRepository) GetParticipant(lotteryID string, userID int64) (*models.Participant, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	i := st.participantIndex(lotteryID, userID)
+	if i < 0 {
+		return nil, nil
+	}
+	p := st.withPrizeWeights(st.participants[i])
+	return &p, nil
+}
+
+func (r *MemoryRepository) GetAllParticipants(lotteryID string) ([]models.Participant, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	var participants []models.Participant
+	for _, p := range st.participants {
+		if p.LotteryID == lotteryID {
+			participants = append(participants, st.withPrizeWeights(p))
+		}
+	}
+	return participants, nil
+}
+
+func (r *MemoryRepository) ListParticipants(q models.ParticipantQuery) ([]models.Participant, string, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	byWeight := q.Sort == models.ParticipantSortWeight
+	var after func(models.Participant) bool
+	if q.Cursor != "" {
+		weight, id, err := decodeParticipantCursor(q.Cursor, byWeight)
+		if err != nil {
+			return nil, "", err
+		}
+		cursor := models.Participant{ID: id, Weight: weight}
+		after = func(p models.Participant) bool { return compareParticipants(p, cursor, byWeight, q.Desc) > 0 }
+	}
+
+	var matched []models.Participant
+	for _, p := range st.participants {
+		if st.participantMatches(p, q) && (after == nil || after(p)) {
+			matched = append(matched, p)
+		}
+	}
+	slices.SortFunc(matched, func(a, b models.Participant) int {
+		return compareParticipants(a, b, byWeight, q.Desc)
+	})
+
+	var next string
+	if len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+		next = encodeParticipantCursor(matched[len(matched)-1], byWeight)
+	}
+	for i := range matched {
+		matched[i] = st.withPrizeWeights(matched[i])
+	}
+	return matched, next, nil
+}
+
+// compareParticipants orders participants the way ListParticipants pages
+// through them.
+func compareParticipants(a, b models.Participant, byWeight, desc bool) int {
+	c := 0
+	if byWeight {
+		c = cmp.Compare(a.Weight, b.Weight)
+	}
+	if c == 0 {
+		c = cmp.Compare(a.ID, b.ID)
+	}
+	if desc {
+		return -c
+	}
+	return c
+}
+
+func (r *MemoryRepository) CountParticipants(q models.ParticipantQuery) (total int, matched int, err error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	for _, p := range st.participants {
+		if p.LotteryID != q.LotteryID {
+			continue
+		}
+		total++
+		if st.participantMatches(p, q) {
+			matched++
+		}
+	}
+	return total, matched, nil
+}
+
+// participantMatches applies the search and filter of q. Search is a
+// case-insensitive substring match on the names, or an exact user ID.
+func (st *memoryState) participantMatches(p models.Participant, q models.ParticipantQuery) bool {
+	if p.LotteryID != q.LotteryID {
+		return false
+	}
+
+	if search := strings.TrimSpace(q.Search); search != "" {
+		name := strings.TrimPrefix(search, "@")
+		found := containsFold(p.Username, name) || containsFold(p.FirstName, name) ||
+			containsFold(p.LastName, name) || containsFold(p.FirstName+" "+p.LastName, name)
+		if userID, err := strconv.ParseInt(search, 10, 64); err == nil && p.UserID == userID {
+			found = true
+		}
+		if !found {
+			return false
+		}
+	}
+
+	switch q.Filter {
+	case models.ParticipantFilterCustomWeight:
+		return p.Weight != 1
+	case models.ParticipantFilterCustomPrizeWeight:
+		return st.hasPrizeWeights(p)
+	}
+	return true
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func (r *MemoryRepository) SetParticipantWeight(lotteryID string, userID int64, weight int) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	if i := st.participantIndex(lotteryID, userID); i >= 0 {
+		st.participants[i].Weight = weight
+	}
+	return nil
+}
+
+func (r *MemoryRepository) RemoveParticipant(lotteryID string, userID int64) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	i := st.participantIndex(lotteryID, userID)
+	if i < 0 {
+		return nil
+	}
+	st.participants = slices.Delete(st.participants, i, i+1)
+	if l, ok := st.lotteries[lotteryID]; ok {
+		l.Participants = max(l.Participants-1, 0)
+		st.lotteries[lotteryID] = l
+	}
+	maps.DeleteFunc(st.prizeWeights, func(k prizeWeightKey, _ int) bool {
+		return k.lotteryID == lotteryID && k.userID == userID
+	})
+	return nil
+}
+
+func (r *MemoryRepository) FillParticipantProfile(userID int64, username, firstName, lastName string) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	for i, p := range st.participants {
+		if p.UserID == userID && p.Username == "" && p.FirstName == "" && p.LastName == "" {
+			st.participants[i].Username = username
+			st.participants[i].FirstName = firstName
+			st.participants[i].LastName = lastName
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) GetPrizeWeight(lotteryID string, userID int64, prizeID int64) (*int, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	weight, ok := st.prizeWeights[prizeWeightKey{lotteryID, userID, prizeID}]
+	if !ok {
+		return nil, nil
+	}
+	return &weight, nil
+}
+
+func (r *MemoryRepository) SetPrizeWeight(lotteryID string, userID int64, prizeID int64, weight int) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	st.prizeWeights[prizeWeightKey{lotteryID, userID, prizeID}] = weight
+	return nil
+}
+
+func (r *MemoryRepository) DeletePrizeWeight(lotteryID string, userID int64, prizeID int64) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	delete(st.prizeWeights, prizeWeightKey{lotteryID, userID, prizeID})
+	return nil
+}
+
+func (r *MemoryRepository) GetWeightDistribution(lotteryID string) ([]models.WeightBucket, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	counts := make(map[int]int)
+	for _, p := range st.participants {
+		if p.LotteryID == lotteryID {
+			counts[p.Weight]++
+		}
+	}
+	return weightBuckets(counts), nil
+}
+
+func (r *MemoryRepository) GetPrizeWeightDistributions(lotteryID string) ([]models.PrizeWeightDistribution, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	var distributions []models.PrizeWeightDistribution
+	for _, prize := range st.prizes {
+		if prize.LotteryID != lotteryID {
+			continue
+		}
+		counts := make(map[int]int)
+		overridden := false
+		for _, p := range st.participants {
+			if p.LotteryID != lotteryID {
+				continue
+			}
+			weight, ok := st.prizeWeights[prizeWeightKey{lotteryID, p.UserID, prize.ID}]
+			if ok {
+				overridden = true
+			} else {
+				weight = p.Weight
+			}
+			counts[weight]++
+		}
+		if overridden {
+			distributions = append(distributions, models.PrizeWeightDistribution{
+				PrizeID:      prize.ID,
+				Distribution: weightBuckets(counts),
+			})
+		}
+	}
+	return distributions, nil
+}
+
+func weightBuckets(counts map[int]int) []models.WeightBucket {
+	buckets := make([]models.WeightBucket, 0, len(counts))
+	for weight, n := range counts {
+		buckets = append(buckets, models.WeightBucket{Weight: weight, Participants: n})
+	}
+	slices.SortFunc(buckets, func(a, b models.WeightBucket) int {
+		return a.Weight - b.Weight
+	})
+	return buckets
+}
+
+func (r *MemoryRepository) GetUserActiveEntries(userID int64, limit int) ([]models.UserEntry, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	var entries []models.UserEntry
+	for _, p := range st.participants {
+		l, ok := st.lotteries[p.LotteryID]
+		if p.UserID == userID && ok && l.Status == "active" {
+			entries = append(entries, models.UserEntry{Lottery: l, JoinedAt: p.JoinedAt})
+		}
+	}
+	slices.SortStableFunc(entries, func(a, b models.UserEntry) int { return b.JoinedAt.Compare(a.JoinedAt) })
+	return entries[:min(limit, len(entries))], nil
+}
+
+func (r *MemoryRepository) CreateWinners(winners []models.Winner) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	for i := range winners {
+		winners[i].ID = st.nextID()
+		st.winners = append(st.winners, winners[i])
+	}
+	return nil
+}
+
+func (r *MemoryRepository) GetWinners(lotteryID string) ([]models.Winner, error) {
+	return r.GetWinnersAfter(lotteryID, 0, math.MaxInt)
+}
+
+func (r *MemoryRepository) GetWinnersAfter(lotteryID string, afterID int64, limit int) ([]models.Winner, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	var winners []models.Winner
+	for _, w := range st.winners {
+		if len(winners) == limit {
+			break
+		}
+		if w.LotteryID == lotteryID && w.ID > afterID {
+			winners = append(winners, w)
+		}
+	}
+	return winners, nil
+}
+
+func (r *MemoryRepository) GetUserWins(userID int64, limit int) ([]models.UserWin, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	var wins []models.UserWin
+	for i := len(st.winners) - 1; i >= 0 && len(wins) < limit; i-- {
+		w := st.winners[i]
+		if l, ok := st.lotteries[w.LotteryID]; ok && w.UserID == userID {
+			wins = append(wins, models.UserWin{LotteryID: w.LotteryID, Title: l.Title, PrizeName: w.PrizeName})
+		}
+	}
+	return wins, nil
+}
+
+func (r *MemoryRepository) ClearAfterDraw(lotteryID string) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	maps.DeleteFunc(st.prizeWeights, func(k prizeWeightKey, _ int) bool { return k.lotteryID == lotteryID })
+	st.participants = slices.DeleteFunc(st.participants, func(p models.Participant) bool { return p.LotteryID == lotteryID })
+	st.sessions = slices.DeleteFunc(st.sessions, func(s memorySession) bool { return s.LotteryID == lotteryID })
+	return nil
+}
+
+func (st *memoryState) organizerIndex(lotteryID string, userID int64) int {
+	return slices.IndexFunc(st.organizers, func(o models.Organizer) bool {
+		return o.LotteryID == lotteryID && o.UserID == userID
+	})
+}
+
+func (r *MemoryRepository) GetOrganizerRole(lotteryID string, userID int64) (string, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	if i := st.organizerIndex(lotteryID, userID); i >= 0 {
+		return st.organizers[i].Role, nil
+	}
+	return "", nil
+}
+
+func (r *MemoryRepository) GetOrganizers(lotteryID string) ([]models.Organizer, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	var organizers []models.Organizer
+	for _, o := range st.organizers {
+		if o.LotteryID == lotteryID {
+			organizers = append(organizers, o)
+		}
+	}
+	return organizers, nil
+}
+
+func (r *MemoryRepository) SetOrganizer(lotteryID string, userID int64, role string) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	if i := st.organizerIndex(lotteryID, userID); i >= 0 {
+		st.organizers[i].Role = role
+		return nil
+	}
+	st.organizers = append(st.organizers, models.Organizer{LotteryID: lotteryID, UserID: userID, Role: role, CreatedAt: time.Now().UTC()})
+	return nil
+}
+
+func (r *MemoryRepository) RemoveOrganizer(lotteryID string, userID int64) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	if i := st.organizerIndex(lotteryID, userID); i >= 0 {
+		st.organizers = slices.Delete(st.organizers, i, i+1)
+	}
+	return nil
+}
+
+func (r *MemoryRepository) CreateEditSession(session *models.EditSession, linkCodeHash string) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	session.ID = st.nextID()
+	session.CreatedAt = time.Now().UTC()
+	st.sessions = append(st.sessions, memorySession{EditSession: *session, linkCodeHash: linkCodeHash})
+	return nil
+}
+
+// liveSession finds an unexpired session of a lottery that matches.
+func (st *memoryState) liveSession(lotteryID string, now time.Time, match func(memorySession) bool) *memorySession {
+	for i := range st.sessions {
+		s := &st.sessions[i]
+		if s.LotteryID == lotteryID && s.ExpiresAt.After(now) && match(*s) {
+			return s
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) ExchangeEditLinkCode(lotteryID, linkCodeHash, tokenHash string) (*models.EditSession, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	now := time.Now().UTC()
+	s := st.liveSession(lotteryID, now, func(s memorySession) bool { return s.linkCodeHash == linkCodeHash })
+	if s == nil {
+		return nil, nil
+	}
+	s.linkCodeHash = ""
+	s.tokenHash = tokenHash
+	s.LastUsedAt = &now
+
+	session := s.EditSession
+	return &session, nil
+}
+
+func (r *MemoryRepository) ValidateEditSession(lotteryID, tokenHash string) (*models.EditSession, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	now := time.Now().UTC()
+	s := st.liveSession(lotteryID, now, func(s memorySession) bool { return s.tokenHash == tokenHash })
+	if s == nil {
+		return nil, nil
+	}
+	s.LastUsedAt = &now
+
+	session := s.EditSession
+	return &session, nil
+}
+
+func (r *MemoryRepository) GetEditSessions(lotteryID string) ([]models.EditSession, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	now := time.Now().UTC()
+	var sessions []models.EditSession
+	for _, s := range st.sessions {
+		if s.LotteryID == lotteryID && s.ExpiresAt.After(now) {
+			sessions = append(sessions, s.EditSession)
+		}
+	}
+	slices.SortStableFunc(sessions, func(a, b models.EditSession) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return sessions, nil
+}
+
+func (r *MemoryRepository) DeleteEditSession(lotteryID string, sessionID int64) (bool, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	n := len(st.sessions)
+	st.sessions = slices.DeleteFunc(st.sessions, func(s memorySession) bool {
+		return s.LotteryID == lotteryID && s.ID == sessionID
+	})
+	return len(st.sessions) < n, nil
+}
+
+func (r *MemoryRepository) DeleteUserEditSessions(lotteryID string, userID int64) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	st.sessions = slices.DeleteFunc(st.sessions, func(s memorySession) bool {
+		return s.LotteryID == lotteryID && s.UserID == userID
+	})
+	return nil
+}
+
+func (r *MemoryRepository) DeleteExpiredEditSessions(now time.Time) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	st.sessions = slices.DeleteFunc(st.sessions, func(s memorySession) bool { return s.ExpiresAt.Before(now) })
+	return nil
+}
+
+func (r *MemoryRepository) CreateLotteryMessage(m *models.LotteryMessage) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	for _, existing := range st.messages {
+		if existing.ChatID == m.ChatID && existing.MessageID == m.MessageID {
+			return fmt.Errorf("message %d in chat %d is already recorded", m.MessageID, m.ChatID)
+		}
+	}
+	m.ID = st.nextID()
+	m.CreatedAt = time.Now().UTC()
+	st.messages = append(st.messages, *m)
+	return nil
+}
+
+func (r *MemoryRepository) GetLotteryMessages(lotteryID string) ([]models.LotteryMessage, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	var messages []models.LotteryMessage
+	for _, m := range st.messages {
+		if m.LotteryID == lotteryID {
+			messages = append(messages, m)
+		}
+	}
+	return messages, nil
+}
+
+func (r *MemoryRepository) GetOutdatedLotteryMessages() ([]models.LotteryMessage, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	var messages []models.LotteryMessage
+	for _, m := range st.messages {
+		if l, ok := st.lotteries[m.LotteryID]; ok && l.Status == "active" && (m.Participants != l.Participants || m.RenderedRevision != m.Revision) {
+			messages = append(messages, m)
+		}
+	}
+	return messages, nil
+}
+
+func (r *MemoryRepository) MarkLotteryMessagesOutdated(lotteryID string) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	for i := range st.messages {
+		if st.messages[i].LotteryID == lotteryID {
+			st.messages[i].Revision++
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) MarkLotteryMessageRendered(id int64, participants, revision int) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	if i := slices.IndexFunc(st.messages, func(m models.LotteryMessage) bool { return m.ID == id }); i >= 0 {
+		st.messages[i].Participants = participants
+		st.messages[i].RenderedRevision = revision
+	}
+	return nil
+}
+
+func (r *MemoryRepository) IsUserBanned(userID int64) (bool, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	_, banned := st.bans[userID]
+	return banned, nil
+}
+
+func (r *MemoryRepository) GetBannedUser(userID int64) (*models.BannedUser, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	ban, ok := st.bans[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &ban, nil
+}
+
+func (r *MemoryRepository) BanUser(ban *models.BannedUser) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	if ban.CreatedAt.IsZero() {
+		ban.CreatedAt = time.Now().UTC()
+	}
+	st.bans[ban.UserID] = *ban
+	return nil
+}
+
+func (r *MemoryRepository) UnbanUser(userID int64) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	delete(st.bans, userID)
+	return nil
+}
+
+func (r *MemoryRepository) AppendAudit(entry *models.AuditEntry) error {
+	st, unlock := r.lock()
+	defer unlock()
+
+	entry.ID = st.nextID()
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+	stored := *entry
+	stored.OldValue = slices.Clone(entry.OldValue)
+	stored.NewValue = slices.Clone(entry.NewValue)
+	st.audit = append(st.audit, stored)
+	return nil
+}
+
+func (r *MemoryRepository) GetAuditLog(lotteryID string, beforeID int64, limit int) ([]models.AuditEntry, error) {
+	return r.auditLog(func(e models.AuditEntry) bool { return e.LotteryID == lotteryID }, beforeID, limit), nil
+}
+
+func (r *MemoryRepository) GetFullAuditLog(beforeID int64, limit int) ([]models.AuditEntry, error) {
+	return r.auditLog(func(models.AuditEntry) bool { return true }, beforeID, limit), nil
+}
+
+func (r *MemoryRepository) auditLog(match func(models.AuditEntry) bool, beforeID int64, limit int) []models.AuditEntry {
+	st, unlock := r.lock()
+	defer unlock()
+
+	var entries []models.AuditEntry
+	for i := len(st.audit) - 1; i >= 0 && len(entries) < limit; i-- {
+		e := st.audit[i]
+		if match(e) && (beforeID <= 0 || e.ID < beforeID) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+func (r *MemoryRepository) LastAuditEntry(lotteryID, action string) (*models.AuditEntry, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	for i := len(st.audit) - 1; i >= 0; i-- {
+		if e := st.audit[i]; e.LotteryID == lotteryID && e.Action == action {
+			return &e, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MemoryRepository) LastWeightChangeAfterJoin(lotteryID string) (*time.Time, error) {
+	st, unlock := r.lock()
+	defer unlock()
+
+	firstJoin := slices.IndexFunc(st.audit, func(e models.AuditEntry) bool {
+		return e.LotteryID == lotteryID && (e.Action == models.AuditParticipantJoin || e.Action == models.AuditParticipantAdd)
+	})
+	if firstJoin < 0 {
+		return nil, nil
+	}
+
+	for i := len(st.audit) - 1; i > firstJoin; i-- {
+		e := st.audit[i]
+		if e.LotteryID == lotteryID && isWeightChange(e) {
+			return &e.CreatedAt, nil
+		}
+	}
+	return nil, nil
+}
+
+// isWeightChange mirrors the conditions of the SQLite query.
+func isWeightChange(e models.AuditEntry) bool {
+	switch e.Action {
+	case models.AuditParticipantWeight, models.AuditPrizeWeightSet, models.AuditPrizeWeightDelete:
+		return true
+	case models.AuditParticipantAdd:
+		weight, ok := auditField(e.NewValue, "weight")
+		return ok && weight != nil && weight != float64(1)
+	case models.AuditLotteryUpdate:
+		before, _ := auditField(e.OldValue, "is_weights_disabled")
+		after, _ := auditField(e.NewValue, "is_weights_disabled")
+		return before != after
+	}
+	return false
+}
+
+func auditField(value json.RawMessage, key string) (any, bool) {
+	var fields map[string]any
+	if json.Unmarshal(value, &fields) != nil {
+		return nil, false
+	}
+	field, ok := fields[key]
+	return field, ok
+}
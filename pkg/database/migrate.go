@@ -0,0 +1,207 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+)
+
+// Migrations are applied in version order and never edited once released.
+// Each file is named <version>_<name>.sql and runs in its own transaction.
+// Every dialect has its own directory of migrations, and a schema change adds
+// a migration with the same version to each of them.
+//
+// A SQLite migration that rebuilds a table, which SQLite needs to change a
+// CHECK or foreign key constraint, starts with the directive line
+//
+//	-- migrate: rebuild
+//
+// Foreign keys are then turned off while it runs, so the old table can be
+// dropped and the new one renamed into place, and checked before it commits.
+// The usual steps are: create the new table, copy the rows, drop the old
+// table, rename the new one and recreate its indexes and triggers.
+//
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var migrationFiles embed.FS
+
+const rebuildDirective = "-- migrate: rebuild"
+
+var migrationName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.sql$`)
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+	rebuild bool
+}
+
+// loadMigrations reads the migrations in dir in version order.
+func loadMigrations(files fs.FS, dir string) ([]migration, error) {
+	paths, err := fs.Glob(files, dir+"/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]migration, 0, len(paths))
+	for _, p := range paths {
+		m := migrationName.FindStringSubmatch(path.Base(p))
+		if m == nil {
+			return nil, fmt.Errorf("migration %s is not named <version>_<name>.sql", p)
+		}
+		version, _ := strconv.Atoi(m[1])
+		data, err := fs.ReadFile(files, p)
+		if err != nil {
+			return nil, err
+		}
+		sqlText := string(data)
+		migrations = append(migrations, migration{
+			version: version,
+			name:    m[2],
+			sql:     sqlText,
+			rebuild: strings.HasPrefix(strings.TrimSpace(sqlText), rebuildDirective),
+		})
+	}
+
+	slices.SortFunc(migrations, func(a, b migration) int { return a.version - b.version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].version == migrations[i-1].version {
+			return nil, fmt.Errorf("duplicate migration version %d", migrations[i].version)
+		}
+	}
+	return migrations, nil
+}
+
+// migrate applies every migration newer than the database's schema version.
+// It runs on one connection, which also holds the dialect's schema lock so
+// that processes starting together apply each migration once.
+func migrate(db *sql.DB, d *dialect) (err error) {
+	migrations, err := loadMigrations(migrationFiles, d.migrations)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if d.lockSchema != "" {
+		if _, err := conn.ExecContext(ctx, d.lockSchema); err != nil {
+			return fmt.Errorf("failed to lock schema: %w", err)
+		}
+		defer func() {
+			if _, unlockErr := conn.ExecContext(ctx, d.unlockSchema); unlockErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to unlock schema: %w", unlockErr))
+			}
+		}()
+	}
+
+	if _, err := conn.ExecContext(ctx, d.schemaMigrations); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if len(migrations) > 0 && current > migrations[len(migrations)-1].version {
+		return fmt.Errorf("database schema version %d is newer than this build supports", current)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		start := time.Now()
+		if err := applyMigration(ctx, conn, d, m); err != nil {
+			return fmt.Errorf("migration %d_%s failed: %w", m.version, m.name, err)
+		}
+		logger.Info("applied database migration", "version", m.version, "name", m.name, "duration", time.Since(start))
+	}
+	return nil
+}
+
+// applyMigration runs one migration and records it in the same transaction.
+// Connection settings such as foreign_keys only hold for one connection,
+// which is why migrations run on a pinned one.
+func applyMigration(ctx context.Context, conn *sql.Conn, d *dialect, m migration) (err error) {
+	if m.rebuild && d != sqliteDialect {
+		return fmt.Errorf("the %s directive is only supported by sqlite", rebuildDirective)
+	}
+	if m.rebuild {
+		// foreign_keys cannot change inside a transaction
+		if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys=OFF`); err != nil {
+			return err
+		}
+		defer func() {
+			if _, restoreErr := conn.ExecContext(ctx, `PRAGMA foreign_keys=ON`); restoreErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to restore foreign keys: %w", restoreErr))
+			}
+		}()
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec(m.sql); err != nil {
+		return err
+	}
+	if m.rebuild {
+		if err = checkForeignKeys(tx); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.Exec(d.rebind(`
+		INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)
+	`), m.version, m.name, time.Now().UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkForeignKeys fails when a rebuild left rows pointing at missing parents.
+func checkForeignKeys(tx *sql.Tx) error {
+	rows, err := tx.Query(`PRAGMA foreign_key_check`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var violations []string
+	for rows.Next() {
+		var table, parent string
+		var rowID sql.NullInt64
+		var fkID int
+		if err := rows.Scan(&table, &rowID, &parent, &fkID); err != nil {
+			return err
+		}
+		violations = append(violations, fmt.Sprintf("%s row %d references missing %s", table, rowID.Int64, parent))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("foreign key violations: %s", strings.Join(violations, "; "))
+	}
+	return nil
+}
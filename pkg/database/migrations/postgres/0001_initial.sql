@@ -0,0 +1,151 @@
+-- The PostgreSQL version of the SQLite schema at migration 0001, with the
+-- same tables, constraints and indexes.
+
+-- Lotteries table
+CREATE TABLE lotteries (
+	id TEXT PRIMARY KEY,
+	title TEXT NOT NULL,
+	description TEXT,
+	creator_id BIGINT NOT NULL,
+	participants INTEGER NOT NULL DEFAULT 0,
+	draw_mode TEXT NOT NULL CHECK(draw_mode IN ('timed', 'full', 'manual')),
+	draw_time TIMESTAMPTZ,
+	max_entries INTEGER,
+	status TEXT NOT NULL DEFAULT 'draft' CHECK(status IN ('draft', 'active', 'completed')),
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	is_weights_disabled BOOLEAN DEFAULT FALSE
+);
+
+-- Prizes table
+CREATE TABLE prizes (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	lottery_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	quantity INTEGER NOT NULL DEFAULT 1,
+	FOREIGN KEY (lottery_id) REFERENCES lotteries(id) ON DELETE CASCADE
+);
+
+-- Participants table
+CREATE TABLE participants (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	lottery_id TEXT NOT NULL,
+	user_id BIGINT NOT NULL,
+	username TEXT,
+	first_name TEXT,
+	last_name TEXT,
+	weight INTEGER NOT NULL DEFAULT 1,
+	joined_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (lottery_id) REFERENCES lotteries(id) ON DELETE CASCADE,
+	UNIQUE(lottery_id, user_id)
+);
+
+-- Users banned by a bot operator from creating or joining lotteries
+CREATE TABLE banned_users (
+	user_id BIGINT PRIMARY KEY,
+	reason TEXT,
+	banned_by BIGINT NOT NULL,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Edit sessions table
+CREATE TABLE edit_sessions (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	lottery_id TEXT NOT NULL,
+	user_id BIGINT NOT NULL,
+	role TEXT NOT NULL,
+	name TEXT NOT NULL,
+	link_code_hash TEXT UNIQUE,
+	token_hash TEXT UNIQUE,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMPTZ NOT NULL,
+	last_used_at TIMESTAMPTZ,
+	FOREIGN KEY (lottery_id) REFERENCES lotteries(id) ON DELETE CASCADE
+);
+
+-- Organizers table
+CREATE TABLE lottery_organizers (
+	lottery_id TEXT NOT NULL,
+	user_id BIGINT NOT NULL,
+	role TEXT NOT NULL CHECK(role IN ('owner', 'editor', 'moderator')),
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (lottery_id, user_id),
+	FOREIGN KEY (lottery_id) REFERENCES lotteries(id) ON DELETE CASCADE
+);
+
+-- Winners table
+CREATE TABLE winners (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	lottery_id TEXT NOT NULL,
+	participant_id BIGINT NOT NULL,
+	prize_id BIGINT NOT NULL,
+	user_id BIGINT NOT NULL,
+	username TEXT,
+	prize_name TEXT NOT NULL,
+	FOREIGN KEY (lottery_id) REFERENCES lotteries(id) ON DELETE CASCADE,
+	FOREIGN KEY (prize_id) REFERENCES prizes(id) ON DELETE CASCADE
+);
+
+-- Prize weights table
+CREATE TABLE prize_weights (
+	lottery_id TEXT NOT NULL,
+	user_id BIGINT NOT NULL,
+	prize_id BIGINT NOT NULL,
+	weight INTEGER NOT NULL,
+	PRIMARY KEY (lottery_id, user_id, prize_id),
+	FOREIGN KEY (lottery_id) REFERENCES lotteries(id) ON DELETE CASCADE,
+	FOREIGN KEY (prize_id) REFERENCES prizes(id) ON DELETE CASCADE
+);
+
+-- Published announcement messages table
+CREATE TABLE lottery_messages (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	lottery_id TEXT NOT NULL,
+	chat_id BIGINT NOT NULL,
+	message_id BIGINT NOT NULL,
+	participants INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (lottery_id) REFERENCES lotteries(id) ON DELETE CASCADE,
+	UNIQUE(chat_id, message_id)
+);
+
+-- Append-only record of every change made to a lottery. It has no foreign
+-- key so the history outlives the lottery it describes. Values are JSON kept
+-- as text, as in SQLite.
+CREATE TABLE audit_log (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	lottery_id TEXT NOT NULL DEFAULT '',
+	actor_id BIGINT NOT NULL,
+	action TEXT NOT NULL,
+	target TEXT NOT NULL DEFAULT '',
+	old_value TEXT,
+	new_value TEXT,
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+	FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+	FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+-- Indexes for better query performance
+CREATE INDEX idx_prizes_lottery ON prizes(lottery_id);
+CREATE INDEX idx_participants_lottery ON participants(lottery_id);
+CREATE INDEX idx_participants_lottery_joined ON participants(lottery_id, joined_at);
+CREATE INDEX idx_edit_sessions_lottery ON edit_sessions(lottery_id);
+CREATE INDEX idx_edit_sessions_expires ON edit_sessions(expires_at);
+CREATE INDEX idx_winners_lottery ON winners(lottery_id);
+CREATE INDEX idx_participants_user ON participants(user_id);
+CREATE INDEX idx_winners_user ON winners(user_id);
+CREATE INDEX idx_lotteries_timed_due ON lotteries(status, draw_mode, draw_time);
+CREATE INDEX idx_lotteries_draft_created ON lotteries(status, created_at);
+CREATE INDEX idx_lotteries_creator_created ON lotteries(creator_id, created_at);
+CREATE INDEX idx_lottery_messages_lottery ON lottery_messages(lottery_id);
+CREATE INDEX idx_lottery_organizers_user ON lottery_organizers(user_id);
+CREATE INDEX idx_audit_log_lottery ON audit_log(lottery_id, id);
@@ -0,0 +1,5 @@
+-- Published announcements track edits of their lottery, not only its
+-- participant count. revision goes up with every edit and rendered_revision
+-- records the revision the message last showed.
+ALTER TABLE lottery_messages ADD COLUMN revision INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE lottery_messages ADD COLUMN rendered_revision INTEGER NOT NULL DEFAULT 0;
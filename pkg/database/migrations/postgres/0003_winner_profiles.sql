@@ -0,0 +1,5 @@
+-- Winners keep the names and join time of their entry, since participants
+-- are removed once a lottery is drawn. Earlier winners have no join time.
+ALTER TABLE winners ADD COLUMN first_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE winners ADD COLUMN last_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE winners ADD COLUMN joined_at TIMESTAMPTZ;
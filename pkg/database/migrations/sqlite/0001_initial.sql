@@ -0,0 +1,151 @@
+-- The schema as it was before migrations were versioned. Every statement is
+-- idempotent so databases created before then can adopt it.
+
+-- Lotteries table
+CREATE TABLE IF NOT EXISTS lotteries (
+	id TEXT PRIMARY KEY,
+	title TEXT NOT NULL,
+	description TEXT,
+	creator_id INTEGER NOT NULL,
+	participants INTEGER NOT NULL DEFAULT 0,
+	draw_mode TEXT NOT NULL CHECK(draw_mode IN ('timed', 'full', 'manual')),
+	draw_time DATETIME,
+	max_entries INTEGER,
+	status TEXT NOT NULL DEFAULT 'draft' CHECK(status IN ('draft', 'active', 'completed')),
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	is_weights_disabled INTEGER DEFAULT 0
+);
+
+-- Prizes table
+CREATE TABLE IF NOT EXISTS prizes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	lottery_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	quantity INTEGER NOT NULL DEFAULT 1,
+	FOREIGN KEY (lottery_id) REFERENCES lotteries(id) ON DELETE CASCADE
+);
+
+-- Participants table
+CREATE TABLE IF NOT EXISTS participants (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	lottery_id TEXT NOT NULL,
+	user_id INTEGER NOT NULL,
+	username TEXT,
+	first_name TEXT,
+	last_name TEXT,
+	weight INTEGER NOT NULL DEFAULT 1,
+	joined_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (lottery_id) REFERENCES lotteries(id) ON DELETE CASCADE,
+	UNIQUE(lottery_id, user_id)
+);
+
+-- Users banned by a bot operator from creating or joining lotteries
+CREATE TABLE IF NOT EXISTS banned_users (
+	user_id INTEGER PRIMARY KEY,
+	reason TEXT,
+	banned_by INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Edit sessions replace the single edit token per lottery
+DROP TABLE IF EXISTS edit_tokens;
+
+-- Edit sessions table
+CREATE TABLE IF NOT EXISTS edit_sessions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	lottery_id TEXT NOT NULL,
+	user_id INTEGER NOT NULL,
+	role TEXT NOT NULL,
+	name TEXT NOT NULL,
+	link_code_hash TEXT UNIQUE,
+	token_hash TEXT UNIQUE,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	expires_at DATETIME NOT NULL,
+	last_used_at DATETIME,
+	FOREIGN KEY (lottery_id) REFERENCES lotteries(id) ON DELETE CASCADE
+);
+
+-- Organizers table
+CREATE TABLE IF NOT EXISTS lottery_organizers (
+	lottery_id TEXT NOT NULL,
+	user_id INTEGER NOT NULL,
+	role TEXT NOT NULL CHECK(role IN ('owner', 'editor', 'moderator')),
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (lottery_id, user_id),
+	FOREIGN KEY (lottery_id) REFERENCES lotteries(id) ON DELETE CASCADE
+);
+
+-- Winners table
+CREATE TABLE IF NOT EXISTS winners (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	lottery_id TEXT NOT NULL,
+	participant_id INTEGER NOT NULL,
+	prize_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	username TEXT,
+	prize_name TEXT NOT NULL,
+	FOREIGN KEY (lottery_id) REFERENCES lotteries(id) ON DELETE CASCADE,
+	FOREIGN KEY (prize_id) REFERENCES prizes(id) ON DELETE CASCADE
+);
+
+-- Prize weights table
+CREATE TABLE IF NOT EXISTS prize_weights (
+	lottery_id TEXT NOT NULL,
+	user_id INTEGER NOT NULL,
+	prize_id INTEGER NOT NULL,
+	weight INTEGER NOT NULL,
+	PRIMARY KEY (lottery_id, user_id, prize_id),
+	FOREIGN KEY (lottery_id) REFERENCES lotteries(id) ON DELETE CASCADE,
+	FOREIGN KEY (prize_id) REFERENCES prizes(id) ON DELETE CASCADE
+);
+
+-- Published announcement messages table
+CREATE TABLE IF NOT EXISTS lottery_messages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	lottery_id TEXT NOT NULL,
+	chat_id INTEGER NOT NULL,
+	message_id INTEGER NOT NULL,
+	participants INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (lottery_id) REFERENCES lotteries(id) ON DELETE CASCADE,
+	UNIQUE(chat_id, message_id)
+);
+
+-- Append-only record of every change made to a lottery. It has no foreign
+-- key so the history outlives the lottery it describes.
+CREATE TABLE IF NOT EXISTS audit_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	lottery_id TEXT NOT NULL DEFAULT '',
+	actor_id INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	target TEXT NOT NULL DEFAULT '',
+	old_value TEXT,
+	new_value TEXT,
+	created_at DATETIME NOT NULL
+);
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+-- Indexes for better query performance
+CREATE INDEX IF NOT EXISTS idx_prizes_lottery ON prizes(lottery_id);
+CREATE INDEX IF NOT EXISTS idx_participants_lottery ON participants(lottery_id);
+CREATE INDEX IF NOT EXISTS idx_participants_lottery_joined ON participants(lottery_id, joined_at);
+CREATE INDEX IF NOT EXISTS idx_edit_sessions_lottery ON edit_sessions(lottery_id);
+CREATE INDEX IF NOT EXISTS idx_edit_sessions_expires ON edit_sessions(expires_at);
+CREATE INDEX IF NOT EXISTS idx_winners_lottery ON winners(lottery_id);
+CREATE INDEX IF NOT EXISTS idx_participants_user ON participants(user_id);
+CREATE INDEX IF NOT EXISTS idx_winners_user ON winners(user_id);
+CREATE INDEX IF NOT EXISTS idx_lotteries_timed_due ON lotteries(status, draw_mode, draw_time);
+CREATE INDEX IF NOT EXISTS idx_lotteries_draft_created ON lotteries(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_lotteries_creator_created ON lotteries(creator_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_lottery_messages_lottery ON lottery_messages(lottery_id);
+CREATE INDEX IF NOT EXISTS idx_lottery_organizers_user ON lottery_organizers(user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_lottery ON audit_log(lottery_id, id);
@@ -0,0 +1,189 @@
+package database
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/realSunyz/lucky-tgbot/pkg/models"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListParticipants orders participants by ID, which follows join order, or by
+// weight and then ID.
+func (r *SQLRepository) ListParticipants(q models.ParticipantQuery) ([]models.Participant, string, error) {
+	where, args := r.participantFilter(q)
+
+	byWeight := q.Sort == models.ParticipantSortWeight
+	cmp, dir := ">", "ASC"
+	if q.Desc {
+		cmp, dir = "<", "DESC"
+	}
+
+	if q.Cursor != "" {
+		weight, id, err := decodeParticipantCursor(q.Cursor, byWeight)
+		if err != nil {
+			return nil, "", err
+		}
+		if byWeight {
+			where += fmt.Sprintf(" AND (p.weight %[1]s ? OR (p.weight = ? AND p.id %[1]s ?))", cmp)
+			args = append(args, weight, weight, id)
+		} else {
+			where += fmt.Sprintf(" AND p.id %s ?", cmp)
+			args = append(args, id)
+		}
+	}
+
+	order := "p.id " + dir
+	if byWeight {
+		order = fmt.Sprintf("p.weight %[1]s, p.id %[1]s", dir)
+	}
+
+	// One extra row tells whether there is a next page.
+	args = append(args, q.Limit+1)
+	rows, err := r.q.Query(`
+		SELECT `+participantColumns+`
+		FROM participants p WHERE `+where+` ORDER BY `+order+` LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var participants []models.Participant
+	for rows.Next() {
+		var p models.Participant
+		if err := scanParticipant(rows, &p); err != nil {
+			return nil, "", err
+		}
+		p.PrizeWeights = make(map[int64]int)
+		participants = append(participants, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(participants) > q.Limit {
+		participants = participants[:q.Limit]
+		last := participants[len(participants)-1]
+		next = encodeParticipantCursor(last, byWeight)
+	}
+
+	if err := r.loadPrizeWeights(q.LotteryID, participants); err != nil {
+		return nil, "", err
+	}
+	return participants, next, nil
+}
+
+func (r *SQLRepository) CountParticipants(q models.ParticipantQuery) (total int, matched int, err error) {
+	where, args := r.participantFilter(q)
+	err = r.q.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(CASE WHEN `+where+` THEN 1 ELSE 0 END), 0)
+		FROM participants p WHERE p.lottery_id = ?
+	`, append(args, q.LotteryID)...).Scan(&total, &matched)
+	return total, matched, err
+}
+
+// participantFilter builds the WHERE clause shared by listing and counting.
+func (r *SQLRepository) participantFilter(q models.ParticipantQuery) (string, []any) {
+	where := "p.lottery_id = ?"
+	args := []any{q.LotteryID}
+
+	if search := strings.TrimSpace(q.Search); search != "" {
+		pattern := "%" + escapeLike(strings.TrimPrefix(search, "@")) + "%"
+		like := r.dialect.like + ` ? ESCAPE '\'`
+		cond := `p.username ` + like + ` OR p.first_name ` + like + ` OR p.last_name ` + like + `
+			OR (p.first_name || ' ' || p.last_name) ` + like
+		args = append(args, pattern, pattern, pattern, pattern)
+		if userID, err := strconv.ParseInt(search, 10, 64); err == nil {
+			cond += " OR p.user_id = ?"
+			args = append(args, userID)
+		}
+		where += " AND (" + cond + ")"
+	}
+
+	switch q.Filter {
+	case models.ParticipantFilterCustomWeight:
+		where += " AND p.weight != 1"
+	case models.ParticipantFilterCustomPrizeWeight:
+		where += " AND EXISTS (SELECT 1 FROM prize_weights pw WHERE pw.lottery_id = p.lottery_id AND pw.user_id = p.user_id)"
+	}
+	return where, args
+}
+
+// loadPrizeWeights fills in the prize weights of the given participants.
+func (r *SQLRepository) loadPrizeWeights(lotteryID string, participants []models.Participant) error {
+	if len(participants) == 0 {
+		return nil
+	}
+
+	index := make(map[int64]int, len(participants))
+	args := []any{lotteryID}
+	for i, p := range participants {
+		index[p.UserID] = i
+		args = append(args, p.UserID)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(participants)), ",")
+
+	rows, err := r.q.Query(`
+		SELECT user_id, prize_id, weight FROM prize_weights
+		WHERE lottery_id = ? AND user_id IN (`+placeholders+`)
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID, prizeID int64
+		var weight int
+		if err := rows.Scan(&userID, &prizeID, &weight); err != nil {
+			return err
+		}
+		if i, ok := index[userID]; ok {
+			participants[i].PrizeWeights[prizeID] = weight
+		}
+	}
+	return rows.Err()
+}
+
+// A cursor holds the sort key of the last participant on a page: its ID, and
+// its weight first when sorting by weight.
+func encodeParticipantCursor(p models.Participant, byWeight bool) string {
+	key := strconv.FormatInt(p.ID, 10)
+	if byWeight {
+		key = strconv.Itoa(p.Weight) + "." + key
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+func decodeParticipantCursor(cursor string, byWeight bool) (int, int64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+
+	rawWeight, rawID, hasWeight := strings.Cut(string(data), ".")
+	if !hasWeight {
+		rawID = rawWeight
+	}
+	if hasWeight != byWeight {
+		return 0, 0, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+	var weight int
+	if byWeight {
+		if weight, err = strconv.Atoi(rawWeight); err != nil {
+			return 0, 0, ErrInvalidCursor
+		}
+	}
+	return weight, id, nil
+}
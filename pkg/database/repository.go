@@ -1,9 +1,8 @@
 package database
 
 import (
-	"database/sql"
+	"context"
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/realSunyz/lucky-tgbot/pkg/models"
@@ -11,339 +10,154 @@ import (
 
 var ErrParticipantExists = errors.New("participant already exists")
 
-func CreateLottery(lottery *models.Lottery) error {
-	db := GetDB()
-	now := time.Now().UTC()
-	lottery.CreatedAt = now
-	if lottery.Status == "" {
-		lottery.Status = "draft"
-	}
-
-	_, err := db.Exec(`
-		INSERT INTO lotteries (id, title, description, creator_id, participants, draw_mode, draw_time, max_entries, status, created_at, is_weights_disabled)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, lottery.ID, lottery.Title, lottery.Description, lottery.CreatorID, lottery.Participants, lottery.DrawMode, lottery.DrawTime, lottery.MaxEntries, lottery.Status, lottery.CreatedAt, lottery.IsWeightsDisabled)
-	return err
-}
-
-func CountUserLotteriesCreatedSince(creatorID int64, since time.Time) (int, error) {
-	db := GetDB()
-	var count int
-	err := db.QueryRow(`
-		SELECT COUNT(*) FROM lotteries
-		WHERE creator_id = ? AND created_at >= ?
-	`, creatorID, since).Scan(&count)
-	return count, err
-}
-
-func GetLotteryStats() (*models.LotteryStats, error) {
-	db := GetDB()
-	var stats models.LotteryStats
-
-	now := time.Now().UTC()
-	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
-
-	err := db.QueryRow(`
-		SELECT 
-			COUNT(*) as total_count,
-			COALESCE(SUM(CASE WHEN status = 'draft' THEN 1 ELSE 0 END), 0) as draft_count,
-			COALESCE(SUM(CASE WHEN status = 'active' THEN 1 ELSE 0 END), 0) as active_count,
-			COALESCE(SUM(CASE WHEN status = 'completed' THEN 1 ELSE 0 END), 0) as completed_count,
-			COALESCE(SUM(CASE WHEN status = 'active' AND draw_mode = 'timed' AND draw_time > ? THEN 1 ELSE 0 END), 0) as scheduled_count,
-			COALESCE(SUM(CASE WHEN created_at >= ? THEN 1 ELSE 0 END), 0) as today_count
-		FROM lotteries
-	`, now, dayStart).Scan(
-		&stats.TotalCount,
-		&stats.DraftCount,
-		&stats.ActiveCount,
-		&stats.CompletedCount,
-		&stats.ScheduledCount,
-		&stats.TodayCount,
-	)
-
-	return &stats, err
-}
-
-func GetLottery(id string) (*models.Lottery, error) {
-	db := GetDB()
-	lottery := &models.Lottery{}
-	err := db.QueryRow(`
-		SELECT id, title, description, creator_id, participants, draw_mode, draw_time, max_entries, status, created_at, is_weights_disabled
-		FROM lotteries WHERE id = ?
-	`, id).Scan(&lottery.ID, &lottery.Title, &lottery.Description, &lottery.CreatorID, &lottery.Participants, &lottery.DrawMode, &lottery.DrawTime, &lottery.MaxEntries, &lottery.Status, &lottery.CreatedAt, &lottery.IsWeightsDisabled)
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	return lottery, err
-}
-
-func UpdateLottery(lottery *models.Lottery) error {
-	db := GetDB()
-	_, err := db.Exec(`
-		UPDATE lotteries SET title = ?, description = ?, participants = ?, draw_mode = ?, draw_time = ?, max_entries = ?, status = ?, is_weights_disabled = ?
-		WHERE id = ?
-	`, lottery.Title, lottery.Description, lottery.Participants, lottery.DrawMode, lottery.DrawTime, lottery.MaxEntries, lottery.Status, lottery.IsWeightsDisabled, lottery.ID)
-	return err
-}
-
-func GetPrizes(lotteryID string) ([]models.Prize, error) {
-	db := GetDB()
-	rows, err := db.Query(`
-		SELECT id, lottery_id, name, quantity FROM prizes WHERE lottery_id = ?
-	`, lotteryID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var prizes []models.Prize
-	for rows.Next() {
-		var p models.Prize
-		if err := rows.Scan(&p.ID, &p.LotteryID, &p.Name, &p.Quantity); err != nil {
-			return nil, err
-		}
-		prizes = append(prizes, p)
-	}
-	return prizes, nil
-}
-
-func AddParticipant(p *models.Participant) error {
-	db := GetDB()
-	now := time.Now().UTC()
-	p.JoinedAt = now
-	if p.Weight == 0 {
-		p.Weight = 1
-	}
-
-	tx, err := db.Begin()
-	if err != nil {
-		return err
-	}
-
-	committed := false
-	defer func() {
-		if !committed {
-			_ = tx.Rollback()
-		}
-	}()
-
-	result, err := tx.Exec(`
-		INSERT INTO participants (lottery_id, user_id, username, first_name, last_name, weight, joined_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT(lottery_id, user_id) DO NOTHING
-	`, p.LotteryID, p.UserID, p.Username, p.FirstName, p.LastName, p.Weight, p.JoinedAt)
-	if err != nil {
-		return err
-	}
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return ErrParticipantExists
-	}
-
-	if _, err = tx.Exec(`UPDATE lotteries SET participants = participants + 1 WHERE id = ?`, p.LotteryID); err != nil {
-		return err
-	}
-
-	id, _ := result.LastInsertId()
-	p.ID = id
-
-	if err = tx.Commit(); err != nil {
-		return err
-	}
-	committed = true
-
-	return nil
-}
-
-func GetParticipants(lotteryID string) ([]models.Participant, error) {
-	db := GetDB()
-	rows, err := db.Query(`
-		SELECT id, lottery_id, user_id, username, first_name, last_name, weight, joined_at
-		FROM participants WHERE lottery_id = ? ORDER BY joined_at
-	`, lotteryID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var participants []models.Participant
-	participantMap := make(map[int64]*models.Participant)
-
-	for rows.Next() {
-		var p models.Participant
-		if err := rows.Scan(&p.ID, &p.LotteryID, &p.UserID, &p.Username, &p.FirstName, &p.LastName, &p.Weight, &p.JoinedAt); err != nil {
-			return nil, err
-		}
-		p.PrizeWeights = make(map[int64]int)
-		participants = append(participants, p)
-		participantMap[p.UserID] = &participants[len(participants)-1]
-	}
-
-	// Fetch prize weights
-	prizeWeights, err := GetPrizeWeights(lotteryID)
-	if err == nil {
-		for _, pw := range prizeWeights {
-			if p, ok := participantMap[pw.UserID]; ok {
-				p.PrizeWeights[pw.PrizeID] = pw.Weight
-			}
-		}
-	}
-
-	return participants, nil
-}
-
-func UpdateParticipantWeight(lotteryID string, userID int64, weight int) error {
-	db := GetDB()
-	_, err := db.Exec(`
-		UPDATE participants SET weight = ? WHERE lottery_id = ? AND user_id = ?
-	`, weight, lotteryID, userID)
-	return err
-}
-
-func SetPrizeWeight(lotteryID string, userID int64, prizeID int64, weight int) error {
-	db := GetDB()
-	_, err := db.Exec(`
-		INSERT INTO prize_weights (lottery_id, user_id, prize_id, weight)
-		VALUES (?, ?, ?, ?)
-		ON CONFLICT(lottery_id, user_id, prize_id) DO UPDATE SET weight = excluded.weight
-	`, lotteryID, userID, prizeID, weight)
-	return err
-}
-
-func DeletePrizeWeight(lotteryID string, userID int64, prizeID int64) error {
-	db := GetDB()
-	_, err := db.Exec(`DELETE FROM prize_weights WHERE lottery_id = ? AND user_id = ? AND prize_id = ?`, lotteryID, userID, prizeID)
-	return err
-}
-
-func GetPrizeWeights(lotteryID string) ([]models.PrizeWeight, error) {
-	db := GetDB()
-	rows, err := db.Query(`
-		SELECT lottery_id, user_id, prize_id, weight
-		FROM prize_weights WHERE lottery_id = ?
-	`, lotteryID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var weights []models.PrizeWeight
-	for rows.Next() {
-		var pw models.PrizeWeight
-		if err := rows.Scan(&pw.LotteryID, &pw.UserID, &pw.PrizeID, &pw.Weight); err != nil {
-			return nil, err
-		}
-		weights = append(weights, pw)
-	}
-	return weights, nil
-}
-
-func RemoveParticipant(lotteryID string, userID int64) error {
-	db := GetDB()
-	tx, err := db.Begin()
-	if err != nil {
-		return err
-	}
-
-	committed := false
-	defer func() {
-		if !committed {
-			_ = tx.Rollback()
-		}
-	}()
-
-	result, err := tx.Exec(`DELETE FROM participants WHERE lottery_id = ? AND user_id = ?`, lotteryID, userID)
-	if err != nil {
-		return err
-	}
-
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows > 0 {
-		if _, err = tx.Exec(`UPDATE lotteries SET participants = MAX(participants - 1, 0) WHERE id = ?`, lotteryID); err != nil {
-			return err
-		}
-		if _, err = tx.Exec(`DELETE FROM prize_weights WHERE lottery_id = ? AND user_id = ?`, lotteryID, userID); err != nil {
-			return err
-		}
-	}
-
-	if err = tx.Commit(); err != nil {
-		return err
-	}
-	committed = true
-	return nil
-}
-
-func CreateEditToken(token *models.EditToken) error {
-	db := GetDB()
-	db.Exec(`DELETE FROM edit_tokens WHERE lottery_id = ?`, token.LotteryID)
-
-	_, err := db.Exec(`
-		INSERT INTO edit_tokens (token, lottery_id, expires_at)
-		VALUES (?, ?, ?)
-	`, token.Token, token.LotteryID, token.ExpiresAt)
-	return err
-}
-
-func ValidateEditToken(lotteryID, token string) (bool, error) {
-	db := GetDB()
-	var count int
-	err := db.QueryRow(`
-		SELECT COUNT(*) FROM edit_tokens 
-		WHERE lottery_id = ? AND token = ? AND expires_at > ?
-	`, lotteryID, token, time.Now().UTC()).Scan(&count)
-	if err != nil {
-		return false, err
-	}
-	return count > 0, nil
-}
-
-func GetWinners(lotteryID string) ([]models.Winner, error) {
-	db := GetDB()
-	rows, err := db.Query(`
-		SELECT id, lottery_id, participant_id, prize_id, user_id, username, prize_name
-		FROM winners WHERE lottery_id = ?
-	`, lotteryID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var winners []models.Winner
-	for rows.Next() {
-		var w models.Winner
-		if err := rows.Scan(&w.ID, &w.LotteryID, &w.ParticipantID, &w.PrizeID, &w.UserID, &w.Username, &w.PrizeName); err != nil {
-			return nil, err
-		}
-		winners = append(winners, w)
-	}
-	return winners, nil
-}
-
-func GenerateLotteryID() (string, error) {
-	db := GetDB()
-	for i := 0; i < 10; i++ {
-		id := fmt.Sprintf("%06d", time.Now().UnixNano()%1000000)
-		var exists int
-		err := db.QueryRow(`SELECT COUNT(*) FROM lotteries WHERE id = ?`, id).Scan(&exists)
-		if err != nil {
-			return "", err
-		}
-		if exists == 0 {
-			return id, nil
-		}
-	}
-	return "", fmt.Errorf("failed to generate unique lottery ID")
-}
-
-func DeleteLottery(id string) error {
-	db := GetDB()
-	_, err := db.Exec(`DELETE FROM lotteries WHERE id = ?`, id)
-	return err
-}
+// LotteryRepository stores lotteries and everything attached to them. Lookups
+// of a single record return nil rather than an error when it does not exist.
+type LotteryRepository interface {
+	// InTx runs fn in a transaction that is committed when fn returns nil.
+	// Calls on the repository passed to fn are part of the transaction, and
+	// InTx on it joins the same transaction.
+	InTx(fn func(tx LotteryRepository) error) error
+	// Ping checks that the backend is reachable.
+	Ping(ctx context.Context) error
+	// Maintain runs the backend's periodic housekeeping.
+	Maintain() error
+
+	GenerateLotteryID() (string, error)
+	CreateLottery(lottery *models.Lottery) error
+	GetLottery(id string) (*models.Lottery, error)
+	// LockLottery is GetLottery that also keeps other transactions from
+	// changing the lottery, or locking it themselves, until the calling
+	// transaction ends. Changes that depend on the lottery's state, such as
+	// adding participants and drawing, lock it first.
+	LockLottery(id string) (*models.Lottery, error)
+	UpdateLottery(lottery *models.Lottery) error
+	// DeleteLottery removes a lottery and everything attached to it. The
+	// audit log is kept.
+	DeleteLottery(id string) error
+	CountUserLotteriesCreatedSince(creatorID int64, since time.Time) (int, error)
+	// SearchCreatorLotteries returns the creator's active lotteries, newest
+	// first, optionally filtered by a title substring.
+	SearchCreatorLotteries(creatorID int64, title string, limit int) ([]models.Lottery, error)
+	// ListCreatorLotteries returns one page of the creator's lotteries, newest
+	// first, together with the total count.
+	ListCreatorLotteries(creatorID int64, limit, offset int) ([]models.Lottery, int, error)
+	// DueLotteryIDs returns the active lotteries whose draw time has passed or
+	// that are full.
+	DueLotteryIDs(now time.Time) ([]string, error)
+	DeleteDraftsCreatedBefore(cutoff time.Time) error
+	GetLotteryStats() (*models.LotteryStats, error)
+	GetAdminStats() (*models.AdminStats, error)
+
+	GetPrizes(lotteryID string) ([]models.Prize, error)
+	// ReplacePrizes swaps the prizes of a lottery for new ones.
+	ReplacePrizes(lotteryID string, prizes []models.Prize) error
+
+	// AddParticipant stores a new participant with the given weight and
+	// counts it on the lottery. It returns ErrParticipantExists when the user
+	// has already joined.
+	AddParticipant(p *models.Participant) error
+	// GetParticipant returns the participant with their prize weights.
+	GetParticipant(lotteryID string, userID int64) (*models.Participant, error)
+	// GetAllParticipants returns every participant with their prize weights
+	// in join order.
+	GetAllParticipants(lotteryID string) ([]models.Participant, error)
+	// ListParticipants returns one page of participants selected by q, with
+	// their prize weights, and the cursor of the next page, which is empty on
+	// the last page. Participants are ordered by join order, or by weight and
+	// then join order. It returns ErrInvalidCursor for a malformed cursor.
+	ListParticipants(q models.ParticipantQuery) ([]models.Participant, string, error)
+	// CountParticipants returns how many participants the lottery has and how
+	// many of them q selects.
+	CountParticipants(q models.ParticipantQuery) (total int, matched int, err error)
+	SetParticipantWeight(lotteryID string, userID int64, weight int) error
+	// RemoveParticipant removes a participant and their prize weights.
+	RemoveParticipant(lotteryID string, userID int64) error
+	// FillParticipantProfile sets the names of a user's entries that were
+	// added without any.
+	FillParticipantProfile(userID int64, username, firstName, lastName string) error
+	GetPrizeWeight(lotteryID string, userID int64, prizeID int64) (*int, error)
+	SetPrizeWeight(lotteryID string, userID int64, prizeID int64, weight int) error
+	DeletePrizeWeight(lotteryID string, userID int64, prizeID int64) error
+	// GetWeightDistribution counts the participants of a lottery per weight,
+	// lightest first.
+	GetWeightDistribution(lotteryID string) ([]models.WeightBucket, error)
+	// GetPrizeWeightDistributions counts, for each prize with a per-prize
+	// override, the participants per weight they are drawn with for it.
+	GetPrizeWeightDistributions(lotteryID string) ([]models.PrizeWeightDistribution, error)
+	// GetUserActiveEntries returns the active lotteries the user has joined,
+	// most recently joined first.
+	GetUserActiveEntries(userID int64, limit int) ([]models.UserEntry, error)
+
+	// CreateWinners stores the winners of a draw and sets their IDs.
+	CreateWinners(winners []models.Winner) error
+	GetWinners(lotteryID string) ([]models.Winner, error)
+	// GetWinnersAfter returns up to limit winners of a lottery with IDs above
+	// afterID in ID order.
+	GetWinnersAfter(lotteryID string, afterID int64, limit int) ([]models.Winner, error)
+	// GetUserWins returns the prizes the user has won, newest first.
+	GetUserWins(userID int64, limit int) ([]models.UserWin, error)
+	// ClearAfterDraw removes the participants, prize weights and edit
+	// sessions a drawn lottery no longer needs.
+	ClearAfterDraw(lotteryID string) error
+
+	GetOrganizerRole(lotteryID string, userID int64) (string, error)
+	GetOrganizers(lotteryID string) ([]models.Organizer, error)
+	SetOrganizer(lotteryID string, userID int64, role string) error
+	RemoveOrganizer(lotteryID string, userID int64) error
+
+	// CreateEditSession stores a new session that can only be used after its
+	// one-time link code has been exchanged for a session token.
+	CreateEditSession(session *models.EditSession, linkCodeHash string) error
+	// ExchangeEditLinkCode consumes a one-time link code and binds the
+	// session to the given token hash. It returns nil when the code is
+	// unknown, already used or expired.
+	ExchangeEditLinkCode(lotteryID, linkCodeHash, tokenHash string) (*models.EditSession, error)
+	// ValidateEditSession returns the live session bound to the token hash
+	// and records its use, or nil when the token is unknown or expired.
+	ValidateEditSession(lotteryID, tokenHash string) (*models.EditSession, error)
+	// GetEditSessions returns the live sessions of a lottery, oldest first.
+	GetEditSessions(lotteryID string) ([]models.EditSession, error)
+	DeleteEditSession(lotteryID string, sessionID int64) (bool, error)
+	DeleteUserEditSessions(lotteryID string, userID int64) error
+	DeleteExpiredEditSessions(now time.Time) error
+
+	CreateLotteryMessage(m *models.LotteryMessage) error
+	GetLotteryMessages(lotteryID string) ([]models.LotteryMessage, error)
+	// GetOutdatedLotteryMessages returns announcements of active lotteries
+	// whose rendered participant count no longer matches the lottery or that
+	// were rendered before the lottery's last edit.
+	GetOutdatedLotteryMessages() ([]models.LotteryMessage, error)
+	// MarkLotteryMessagesOutdated records an edit of the lottery on all of
+	// its announcements.
+	MarkLotteryMessagesOutdated(lotteryID string) error
+	// MarkLotteryMessageRendered records the participant count and revision
+	// an announcement now shows.
+	MarkLotteryMessageRendered(id int64, participants, revision int) error
+
+	IsUserBanned(userID int64) (bool, error)
+	GetBannedUser(userID int64) (*models.BannedUser, error)
+	// BanUser bans a user or replaces the reason of an existing ban.
+	BanUser(ban *models.BannedUser) error
+	UnbanUser(userID int64) error
+
+	// AppendAudit records a change and sets the entry's ID.
+	AppendAudit(entry *models.AuditEntry) error
+	// GetAuditLog returns up to limit entries of a lottery, newest first.
+	// When beforeID is positive only older entries are returned.
+	GetAuditLog(lotteryID string, beforeID int64, limit int) ([]models.AuditEntry, error)
+	// GetFullAuditLog pages through the entries of every lottery together
+	// with those of no lottery, such as bans, like GetAuditLog.
+	GetFullAuditLog(beforeID int64, limit int) ([]models.AuditEntry, error)
+	// LastAuditEntry returns the newest entry of a lottery with the action.
+	LastAuditEntry(lotteryID, action string) (*models.AuditEntry, error)
+	// LastWeightChangeAfterJoin returns when the weights of a lottery were
+	// last changed after its first participant joined, or nil if they never
+	// were. Adding a participant with a non-default weight and turning
+	// weights back on count as changes.
+	LastWeightChangeAfterJoin(lotteryID string) (*time.Time, error)
+}
+
+var (
+	_ LotteryRepository = (*SQLRepository)(nil)
+	_ LotteryRepository = (*MemoryRepository)(nil)
+)
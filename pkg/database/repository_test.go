@@ -0,0 +1,448 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/realSunyz/lucky-tgbot/pkg/models"
+)
+
+// postgresURLEnv names a PostgreSQL database, given as a URL, that the tests
+// may create and drop schemas in. The PostgreSQL tests are skipped without it.
+const postgresURLEnv = "LUCKY_TEST_POSTGRES_URL"
+
+// testPostgresURL creates a schema of its own for the test and returns a URL
+// whose connections use it.
+func testPostgresURL(t *testing.T) string {
+	t.Helper()
+	base := os.Getenv(postgresURLEnv)
+	if base == "" {
+		t.Skipf("set %s to run the PostgreSQL tests", postgresURLEnv)
+	}
+
+	admin, err := sql.Open("pgx", base)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", postgresURLEnv, err)
+	}
+	schema := fmt.Sprintf("lucky_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		admin.Close()
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Errorf("failed to drop schema %s: %v", schema, err)
+		}
+		admin.Close()
+	})
+
+	u, err := url.Parse(base)
+	if err != nil {
+		t.Fatalf("%s is not a URL: %v", postgresURLEnv, err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func openTestPostgres(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := OpenPostgres(testPostgresURL(t), 4)
+	if err != nil {
+		t.Fatalf("OpenPostgres: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func openTestSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "lottery.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// forEachRepository runs test against a fresh repository of every backend.
+func forEachRepository(t *testing.T, test func(t *testing.T, repo LotteryRepository)) {
+	backends := []struct {
+		name string
+		open func(t *testing.T) LotteryRepository
+	}{
+		{"sqlite", func(t *testing.T) LotteryRepository { return NewSQLiteRepository(openTestSQLite(t)) }},
+		{"postgres", func(t *testing.T) LotteryRepository { return NewPostgresRepository(openTestPostgres(t)) }},
+		{"memory", func(*testing.T) LotteryRepository { return NewMemoryRepository() }},
+	}
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			test(t, backend.open(t))
+		})
+	}
+}
+
+func createTestLottery(t *testing.T, repo LotteryRepository, id string) {
+	t.Helper()
+	lottery := &models.Lottery{
+		ID:        id,
+		Title:     "Lottery " + id,
+		CreatorID: 1,
+		DrawMode:  "manual",
+		Status:    "active",
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := repo.CreateLottery(lottery); err != nil {
+		t.Fatalf("CreateLottery: %v", err)
+	}
+}
+
+func addTestParticipant(t *testing.T, repo LotteryRepository, lotteryID string, userID int64, username string) {
+	t.Helper()
+	p := &models.Participant{LotteryID: lotteryID, UserID: userID, Username: username, Weight: 1}
+	if err := repo.AddParticipant(p); err != nil {
+		t.Fatalf("AddParticipant(%d): %v", userID, err)
+	}
+}
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`SELECT 1`, `SELECT 1`},
+		{`SELECT * FROM t WHERE a = ? AND b = ?`, `SELECT * FROM t WHERE a = $1 AND b = $2`},
+		{`SELECT '?' FROM t WHERE a = ?`, `SELECT '?' FROM t WHERE a = $1`},
+		{`UPDATE t SET a = 'it''s ?' WHERE b = ?`, `UPDATE t SET a = 'it''s ?' WHERE b = $1`},
+	}
+	for _, tt := range tests {
+		if got := postgresDialect.rebind(tt.query); got != tt.want {
+			t.Errorf("rebind(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+		if got := sqliteDialect.rebind(tt.query); got != tt.query {
+			t.Errorf("sqlite rebind(%q) = %q, want it unchanged", tt.query, got)
+		}
+	}
+}
+
+func TestRepositoryParticipants(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo LotteryRepository) {
+		createTestLottery(t, repo, "200001")
+		addTestParticipant(t, repo, "200001", 1, "alice")
+		addTestParticipant(t, repo, "200001", 2, "bob")
+
+		err := repo.AddParticipant(&models.Participant{LotteryID: "200001", UserID: 1, Weight: 1})
+		if !errors.Is(err, ErrParticipantExists) {
+			t.Errorf("duplicate AddParticipant = %v, want ErrParticipantExists", err)
+		}
+
+		lottery, err := repo.GetLottery("200001")
+		if err != nil {
+			t.Fatalf("GetLottery: %v", err)
+		}
+		if lottery.Participants != 2 {
+			t.Errorf("participants = %d, want 2", lottery.Participants)
+		}
+
+		// Search ignores case on every backend
+		found, _, err := repo.ListParticipants(models.ParticipantQuery{LotteryID: "200001", Search: "ALICE", Limit: 10})
+		if err != nil {
+			t.Fatalf("ListParticipants: %v", err)
+		}
+		if len(found) != 1 || found[0].UserID != 1 {
+			t.Errorf("search for ALICE found %+v, want user 1", found)
+		}
+
+		if err := repo.RemoveParticipant("200001", 2); err != nil {
+			t.Fatalf("RemoveParticipant: %v", err)
+		}
+		if lottery, err = repo.GetLottery("200001"); err != nil {
+			t.Fatalf("GetLottery: %v", err)
+		}
+		if lottery.Participants != 1 {
+			t.Errorf("participants after removal = %d, want 1", lottery.Participants)
+		}
+	})
+}
+
+func TestRepositoryInTxRollsBack(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo LotteryRepository) {
+		createTestLottery(t, repo, "200002")
+
+		errRollback := errors.New("rollback")
+		err := repo.InTx(func(tx LotteryRepository) error {
+			if _, err := tx.LockLottery("200002"); err != nil {
+				return err
+			}
+			p := &models.Participant{LotteryID: "200002", UserID: 1, Weight: 1}
+			if err := tx.AddParticipant(p); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Fatalf("InTx = %v, want the error of its function", err)
+		}
+
+		participant, err := repo.GetParticipant("200002", 1)
+		if err != nil {
+			t.Fatalf("GetParticipant: %v", err)
+		}
+		lottery, err := repo.GetLottery("200002")
+		if err != nil {
+			t.Fatalf("GetLottery: %v", err)
+		}
+		if participant != nil || lottery.Participants != 0 {
+			t.Errorf("rolled back join left participant %+v and a count of %d", participant, lottery.Participants)
+		}
+	})
+}
+
+func TestRepositoryLastWeightChangeAfterJoin(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo LotteryRepository) {
+		start := time.Now().UTC().Truncate(time.Second)
+		appendEntry := func(offset time.Duration, action, oldValue, newValue string) time.Time {
+			t.Helper()
+			entry := &models.AuditEntry{
+				LotteryID: "200003",
+				ActorID:   1,
+				Action:    action,
+				CreatedAt: start.Add(offset),
+			}
+			if oldValue != "" {
+				entry.OldValue = []byte(oldValue)
+			}
+			if newValue != "" {
+				entry.NewValue = []byte(newValue)
+			}
+			if err := repo.AppendAudit(entry); err != nil {
+				t.Fatalf("AppendAudit(%s): %v", action, err)
+			}
+			return entry.CreatedAt
+		}
+		check := func(want *time.Time) {
+			t.Helper()
+			got, err := repo.LastWeightChangeAfterJoin("200003")
+			if err != nil {
+				t.Fatalf("LastWeightChangeAfterJoin: %v", err)
+			}
+			switch {
+			case want == nil && got != nil:
+				t.Errorf("LastWeightChangeAfterJoin = %v, want none", got)
+			case want != nil && (got == nil || !got.Equal(*want)):
+				t.Errorf("LastWeightChangeAfterJoin = %v, want %v", got, want)
+			}
+		}
+
+		// Weights set before anyone joined are not flagged
+		appendEntry(0, models.AuditParticipantWeight, `{"weight":1}`, `{"weight":2}`)
+		check(nil)
+
+		appendEntry(time.Second, models.AuditParticipantJoin, "", `{"username":"alice"}`)
+		appendEntry(2*time.Second, models.AuditParticipantAdd, "", `{"username":"","weight":1}`)
+		appendEntry(3*time.Second, models.AuditLotteryUpdate, `{"title":"a","is_weights_disabled":false}`, `{"title":"b","is_weights_disabled":false}`)
+		check(nil)
+
+		added := appendEntry(4*time.Second, models.AuditParticipantAdd, "", `{"username":"","weight":3}`)
+		check(&added)
+
+		toggled := appendEntry(5*time.Second, models.AuditLotteryUpdate, `{"is_weights_disabled":false}`, `{"is_weights_disabled":true}`)
+		check(&toggled)
+
+		changed := appendEntry(6*time.Second, models.AuditPrizeWeightSet, "", `{"weight":4}`)
+		check(&changed)
+	})
+}
+
+func TestRepositoryWeightDistribution(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo LotteryRepository) {
+		createTestLottery(t, repo, "200004")
+		prizes := []models.Prize{{LotteryID: "200004", Name: "a", Quantity: 1}, {LotteryID: "200004", Name: "b", Quantity: 1}}
+		if err := repo.ReplacePrizes("200004", prizes); err != nil {
+			t.Fatalf("ReplacePrizes: %v", err)
+		}
+		if prizes, _ = repo.GetPrizes("200004"); len(prizes) != 2 {
+			t.Fatalf("got %d prizes, want 2", len(prizes))
+		}
+		for userID := int64(1); userID <= 4; userID++ {
+			addTestParticipant(t, repo, "200004", userID, "")
+		}
+		if err := repo.SetParticipantWeight("200004", 2, 3); err != nil {
+			t.Fatalf("SetParticipantWeight: %v", err)
+		}
+		if err := repo.SetPrizeWeight("200004", 3, prizes[1].ID, 7); err != nil {
+			t.Fatalf("SetPrizeWeight: %v", err)
+		}
+
+		distribution, err := repo.GetWeightDistribution("200004")
+		if err != nil {
+			t.Fatalf("GetWeightDistribution: %v", err)
+		}
+		want := fmt.Sprint([]models.WeightBucket{{Weight: 1, Participants: 3}, {Weight: 3, Participants: 1}})
+		if got := fmt.Sprint(distribution); got != want {
+			t.Errorf("distribution = %s, want %s", got, want)
+		}
+
+		perPrize, err := repo.GetPrizeWeightDistributions("200004")
+		if err != nil {
+			t.Fatalf("GetPrizeWeightDistributions: %v", err)
+		}
+		want = fmt.Sprint([]models.PrizeWeightDistribution{{
+			PrizeID:      prizes[1].ID,
+			Distribution: []models.WeightBucket{{Weight: 1, Participants: 2}, {Weight: 3, Participants: 1}, {Weight: 7, Participants: 1}},
+		}})
+		if got := fmt.Sprint(perPrize); got != want {
+			t.Errorf("prize distributions = %s, want %s", got, want)
+		}
+	})
+}
+
+func TestRepositoryOutdatedLotteryMessages(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo LotteryRepository) {
+		createTestLottery(t, repo, "200005")
+		if err := repo.CreateLotteryMessage(&models.LotteryMessage{LotteryID: "200005", ChatID: 5, MessageID: 7}); err != nil {
+			t.Fatalf("CreateLotteryMessage: %v", err)
+		}
+		outdated := func() []models.LotteryMessage {
+			t.Helper()
+			messages, err := repo.GetOutdatedLotteryMessages()
+			if err != nil {
+				t.Fatalf("GetOutdatedLotteryMessages: %v", err)
+			}
+			return messages
+		}
+
+		if messages := outdated(); len(messages) != 0 {
+			t.Fatalf("a fresh announcement is outdated: %+v", messages)
+		}
+
+		addTestParticipant(t, repo, "200005", 1, "")
+		messages := outdated()
+		if len(messages) != 1 {
+			t.Fatalf("got %d outdated announcements after a join, want 1", len(messages))
+		}
+		if err := repo.MarkLotteryMessageRendered(messages[0].ID, 1, messages[0].Revision); err != nil {
+			t.Fatalf("MarkLotteryMessageRendered: %v", err)
+		}
+		if messages := outdated(); len(messages) != 0 {
+			t.Fatalf("a rendered announcement is outdated: %+v", messages)
+		}
+
+		// An edit made while a message is being rendered keeps it outdated
+		if err := repo.MarkLotteryMessagesOutdated("200005"); err != nil {
+			t.Fatalf("MarkLotteryMessagesOutdated: %v", err)
+		}
+		messages = outdated()
+		if len(messages) != 1 {
+			t.Fatalf("got %d outdated announcements after an edit, want 1", len(messages))
+		}
+		if err := repo.MarkLotteryMessagesOutdated("200005"); err != nil {
+			t.Fatalf("MarkLotteryMessagesOutdated: %v", err)
+		}
+		if err := repo.MarkLotteryMessageRendered(messages[0].ID, 1, messages[0].Revision); err != nil {
+			t.Fatalf("MarkLotteryMessageRendered: %v", err)
+		}
+		if messages := outdated(); len(messages) != 1 {
+			t.Errorf("got %d outdated announcements after a concurrent edit, want 1", len(messages))
+		}
+	})
+}
+
+// TestPostgresLockLotteryBlocks checks that LockLottery holds the row until
+// the transaction ends, so a second locker sees the first one's changes.
+func TestPostgresLockLotteryBlocks(t *testing.T) {
+	repo := NewPostgresRepository(openTestPostgres(t))
+	createTestLottery(t, repo, "200006")
+
+	locked := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- repo.InTx(func(tx LotteryRepository) error {
+			lottery, err := tx.LockLottery("200006")
+			if err != nil {
+				close(locked)
+				return err
+			}
+			close(locked)
+			time.Sleep(200 * time.Millisecond)
+			lottery.Title = "updated"
+			return tx.UpdateLottery(lottery)
+		})
+	}()
+
+	<-locked
+	var title string
+	err := repo.InTx(func(tx LotteryRepository) error {
+		lottery, err := tx.LockLottery("200006")
+		if err != nil {
+			return err
+		}
+		title = lottery.Title
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("second LockLottery: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("first transaction: %v", err)
+	}
+	if title != "updated" {
+		t.Errorf("second locker read title %q, want the committed %q", title, "updated")
+	}
+}
+
+// TestPostgresConcurrentMigrations starts several processes' worth of
+// migrations on an empty schema at once; the advisory lock lets each
+// migration apply exactly once.
+func TestPostgresConcurrentMigrations(t *testing.T) {
+	dsn := testPostgresURL(t)
+
+	const processes = 4
+	var wg sync.WaitGroup
+	errs := make([]error, processes)
+	for i := range processes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db, err := sql.Open("pgx", dsn)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer db.Close()
+			errs[i] = migrate(db, postgresDialect)
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("migration %d: %v", i, err)
+		}
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	defer db.Close()
+
+	migrations, err := loadMigrations(migrationFiles, postgresDialect.migrations)
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	var applied, distinct int
+	if err := db.QueryRow(`SELECT COUNT(*), COUNT(DISTINCT version) FROM schema_migrations`).Scan(&applied, &distinct); err != nil {
+		t.Fatalf("failed to count applied migrations: %v", err)
+	}
+	if applied != len(migrations) || distinct != len(migrations) {
+		t.Errorf("schema_migrations has %d rows for %d versions, want %d of each", applied, distinct, len(migrations))
+	}
+}
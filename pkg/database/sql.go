@@ -0,0 +1,1022 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/realSunyz/lucky-tgbot/pkg/models"
+)
+
+// querier is what *sql.DB and *sql.Tx have in common.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// SQLRepository stores lotteries in a SQLite database opened by Open or a
+// PostgreSQL database opened by OpenPostgres.
+type SQLRepository struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	q       querier
+	dialect *dialect
+}
+
+func NewSQLiteRepository(db *sql.DB) *SQLRepository {
+	return newSQLRepository(db, sqliteDialect)
+}
+
+func NewPostgresRepository(db *sql.DB) *SQLRepository {
+	return newSQLRepository(db, postgresDialect)
+}
+
+func newSQLRepository(db *sql.DB, d *dialect) *SQLRepository {
+	return &SQLRepository{db: db, q: bind(db, d), dialect: d}
+}
+
+func (r *SQLRepository) InTx(fn func(tx LotteryRepository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	tx, err := r.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err := fn(&SQLRepository{db: r.db, tx: tx, q: bind(tx, r.dialect), dialect: r.dialect}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+func (r *SQLRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// Maintain truncates the SQLite WAL and lets SQLite refresh its query
+// planner statistics. PostgreSQL leaves this to autovacuum.
+func (r *SQLRepository) Maintain() error {
+	return r.dialect.maintain(r.q)
+}
+
+const lotteryColumns = `id, title, description, creator_id, participants, draw_mode, draw_time, max_entries, status, created_at, is_weights_disabled`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLottery(row scanner, l *models.Lottery, extra ...any) error {
+	return row.Scan(append([]any{&l.ID, &l.Title, &l.Description, &l.CreatorID, &l.Participants, &l.DrawMode, &l.DrawTime, &l.MaxEntries, &l.Status, &l.CreatedAt, &l.IsWeightsDisabled}, extra...)...)
+}
+
+func scanLotteries(rows *sql.Rows) ([]models.Lottery, error) {
+	defer rows.Close()
+
+	var lotteries []models.Lottery
+	for rows.Next() {
+		var l models.Lottery
+		if err := scanLottery(rows, &l); err != nil {
+			return nil, err
+		}
+		lotteries = append(lotteries, l)
+	}
+	return lotteries, rows.Err()
+}
+
+func (r *SQLRepository) GenerateLotteryID() (string, error) {
+	for i := 0; i < 10; i++ {
+		id := fmt.Sprintf("%06d", time.Now().UnixNano()%1000000)
+		var exists int
+		err := r.q.QueryRow(`SELECT COUNT(*) FROM lotteries WHERE id = ?`, id).Scan(&exists)
+		if err != nil {
+			return "", err
+		}
+		if exists == 0 {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate unique lottery ID")
+}
+
+func (r *SQLRepository) CreateLottery(lottery *models.Lottery) error {
+	if lottery.CreatedAt.IsZero() {
+		lottery.CreatedAt = time.Now().UTC()
+	}
+	if lottery.Status == "" {
+		lottery.Status = "draft"
+	}
+
+	_, err := r.q.Exec(`
+		INSERT INTO lotteries (`+lotteryColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, lottery.ID, lottery.Title, lottery.Description, lottery.CreatorID, lottery.Participants, lottery.DrawMode, lottery.DrawTime, lottery.MaxEntries, lottery.Status, lottery.CreatedAt, lottery.IsWeightsDisabled)
+	return err
+}
+
+func (r *SQLRepository) GetLottery(id string) (*models.Lottery, error) {
+	return r.getLottery(id, "")
+}
+
+func (r *SQLRepository) LockLottery(id string) (*models.Lottery, error) {
+	return r.getLottery(id, r.dialect.forUpdate)
+}
+
+func (r *SQLRepository) getLottery(id, lock string) (*models.Lottery, error) {
+	lottery := &models.Lottery{}
+	err := scanLottery(r.q.QueryRow(`SELECT `+lotteryColumns+` FROM lotteries WHERE id = ?`+lock, id), lottery)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return lottery, nil
+}
+
+func (r *SQLRepository) UpdateLottery(lottery *models.Lottery) error {
+	_, err := r.q.Exec(`
+		UPDATE lotteries
+		SET title = ?, description = ?, participants = ?, draw_mode = ?, draw_time = ?, max_entries = ?, status = ?, is_weights_disabled = ?
+		WHERE id = ?
+	`, lottery.Title, lottery.Description, lottery.Participants, lottery.DrawMode, lottery.DrawTime, lottery.MaxEntries, lottery.Status, lottery.IsWeightsDisabled, lottery.ID)
+	return err
+}
+
+// DeleteLottery relies on the schema's cascading foreign keys.
+func (r *SQLRepository) DeleteLottery(id string) error {
+	_, err := r.q.Exec(`DELETE FROM lotteries WHERE id = ?`, id)
+	return err
+}
+
+func (r *SQLRepository) CountUserLotteriesCreatedSince(creatorID int64, since time.Time) (int, error) {
+	var count int
+	err := r.q.QueryRow(`
+		SELECT COUNT(*) FROM lotteries
+		WHERE creator_id = ? AND created_at >= ?
+	`, creatorID, since).Scan(&count)
+	return count, err
+}
+
+func (r *SQLRepository) SearchCreatorLotteries(creatorID int64, title string, limit int) ([]models.Lottery, error) {
+	rows, err := r.q.Query(`
+		SELECT `+lotteryColumns+`
+		FROM lotteries
+		WHERE creator_id = ? AND status = 'active' AND title `+r.dialect.like+` ? ESCAPE '\'
+		ORDER BY created_at DESC
+		LIMIT ?
+	`, creatorID, "%"+escapeLike(title)+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanLotteries(rows)
+}
+
+func (r *SQLRepository) ListCreatorLotteries(creatorID int64, limit, offset int) ([]models.Lottery, int, error) {
+	var total int
+	if err := r.q.QueryRow(`SELECT COUNT(*) FROM lotteries WHERE creator_id = ?`, creatorID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.q.Query(`
+		SELECT `+lotteryColumns+`
+		FROM lotteries
+		WHERE creator_id = ?
+		ORDER BY created_at DESC
+		LIMIT ? OFFSET ?
+	`, creatorID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	lotteries, err := scanLotteries(rows)
+	return lotteries, total, err
+}
+
+func (r *SQLRepository) DueLotteryIDs(now time.Time) ([]string, error) {
+	rows, err := r.q.Query(`
+		SELECT id FROM lotteries
+		WHERE status = 'active' AND (
+			(draw_mode = 'timed' AND draw_time IS NOT NULL AND draw_time <= ?)
+			OR (draw_mode = 'full' AND max_entries IS NOT NULL AND participants >= max_entries)
+		)
+	`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *SQLRepository) DeleteDraftsCreatedBefore(cutoff time.Time) error {
+	_, err := r.q.Exec(`DELETE FROM lotteries WHERE status = 'draft' AND created_at < ?`, cutoff)
+	return err
+}
+
+func (r *SQLRepository) GetLotteryStats() (*models.LotteryStats, error) {
+	var stats models.LotteryStats
+
+	now := time.Now().UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	err := r.q.QueryRow(`
+		SELECT
+			COUNT(*) as total_count,
+			COALESCE(SUM(CASE WHEN status = 'draft' THEN 1 ELSE 0 END), 0) as draft_count,
+			COALESCE(SUM(CASE WHEN status = 'active' THEN 1 ELSE 0 END), 0) as active_count,
+			COALESCE(SUM(CASE WHEN status = 'completed' THEN 1 ELSE 0 END), 0) as completed_count,
+			COALESCE(SUM(CASE WHEN status = 'active' AND draw_mode = 'timed' AND draw_time > ? THEN 1 ELSE 0 END), 0) as scheduled_count,
+			COALESCE(SUM(CASE WHEN created_at >= ? THEN 1 ELSE 0 END), 0) as today_count
+		FROM lotteries
+	`, now, dayStart).Scan(
+		&stats.TotalCount,
+		&stats.DraftCount,
+		&stats.ActiveCount,
+		&stats.CompletedCount,
+		&stats.ScheduledCount,
+		&stats.TodayCount,
+	)
+
+	return &stats, err
+}
+
+func (r *SQLRepository) GetAdminStats() (*models.AdminStats, error) {
+	lotteryStats, err := r.GetLotteryStats()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.AdminStats{LotteryStats: *lotteryStats}
+	err = r.q.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM participants),
+			(SELECT COUNT(*) FROM winners),
+			(SELECT COUNT(DISTINCT creator_id) FROM lotteries),
+			(SELECT COUNT(*) FROM edit_sessions WHERE expires_at > ?),
+			(SELECT COUNT(*) FROM banned_users)
+	`, time.Now().UTC()).Scan(
+		&stats.ParticipantCount,
+		&stats.WinnerCount,
+		&stats.CreatorCount,
+		&stats.EditSessionCount,
+		&stats.BannedUserCount,
+	)
+	return stats, err
+}
+
+func (r *SQLRepository) GetPrizes(lotteryID string) ([]models.Prize, error) {
+	rows, err := r.q.Query(`
+		SELECT id, lottery_id, name, quantity FROM prizes WHERE lottery_id = ? ORDER BY id
+	`, lotteryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prizes []models.Prize
+	for rows.Next() {
+		var p models.Prize
+		if err := rows.Scan(&p.ID, &p.LotteryID, &p.Name, &p.Quantity); err != nil {
+			return nil, err
+		}
+		prizes = append(prizes, p)
+	}
+	return prizes, rows.Err()
+}
+
+// ReplacePrizes must run in a transaction so that the lottery never appears
+// without prizes.
+func (r *SQLRepository) ReplacePrizes(lotteryID string, prizes []models.Prize) error {
+	if _, err := r.q.Exec(`DELETE FROM prizes WHERE lottery_id = ?`, lotteryID); err != nil {
+		return err
+	}
+	for _, prize := range prizes {
+		if _, err := r.q.Exec(`
+			INSERT INTO prizes (lottery_id, name, quantity) VALUES (?, ?, ?)
+		`, lotteryID, prize.Name, prize.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const participantColumns = `p.id, p.lottery_id, p.user_id, p.username, p.first_name, p.last_name, p.weight, p.joined_at`
+
+func scanParticipant(row scanner, p *models.Participant) error {
+	return row.Scan(&p.ID, &p.LotteryID, &p.UserID, &p.Username, &p.FirstName, &p.LastName, &p.Weight, &p.JoinedAt)
+}
+
+func (r *SQLRepository) AddParticipant(p *models.Participant) error {
+	p.JoinedAt = time.Now().UTC()
+
+	err := r.q.QueryRow(`
+		INSERT INTO participants (lottery_id, user_id, username, first_name, last_name, weight, joined_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(lottery_id, user_id) DO NOTHING
+		RETURNING id
+	`, p.LotteryID, p.UserID, p.Username, p.FirstName, p.LastName, p.Weight, p.JoinedAt).Scan(&p.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrParticipantExists
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = r.q.Exec(`UPDATE lotteries SET participants = participants + 1 WHERE id = ?`, p.LotteryID)
+	return err
+}
+
+func (r *SQLRepository) GetParticipant(lotteryID string, userID int64) (*models.Participant, error) {
+	p := &models.Participant{PrizeWeights: make(map[int64]int)}
+	err := scanParticipant(r.q.QueryRow(`
+		SELECT `+participantColumns+` FROM participants p WHERE p.lottery_id = ? AND p.user_id = ?
+	`, lotteryID, userID), p)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	participants := []models.Participant{*p}
+	if err := r.loadPrizeWeights(lotteryID, participants); err != nil {
+		return nil, err
+	}
+	return &participants[0], nil
+}
+
+func (r *SQLRepository) GetAllParticipants(lotteryID string) ([]models.Participant, error) {
+	rows, err := r.q.Query(`
+		SELECT `+participantColumns+` FROM participants p WHERE p.lottery_id = ? ORDER BY p.id
+	`, lotteryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var participants []models.Participant
+	index := make(map[int64]int)
+	for rows.Next() {
+		var p models.Participant
+		if err := scanParticipant(rows, &p); err != nil {
+			return nil, err
+		}
+		p.PrizeWeights = make(map[int64]int)
+		index[p.UserID] = len(participants)
+		participants = append(participants, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// A lottery can have more participants than fit in one IN list, so read
+	// every prize weight of the lottery at once.
+	weightRows, err := r.q.Query(`
+		SELECT user_id, prize_id, weight FROM prize_weights WHERE lottery_id = ?
+	`, lotteryID)
+	if err != nil {
+		return nil, err
+	}
+	defer weightRows.Close()
+
+	for weightRows.Next() {
+		var userID, prizeID int64
+		var weight int
+		if err := weightRows.Scan(&userID, &prizeID, &weight); err != nil {
+			return nil, err
+		}
+		if i, ok := index[userID]; ok {
+			participants[i].PrizeWeights[prizeID] = weight
+		}
+	}
+	return participants, weightRows.Err()
+}
+
+func (r *SQLRepository) SetParticipantWeight(lotteryID string, userID int64, weight int) error {
+	_, err := r.q.Exec(`
+		UPDATE participants SET weight = ? WHERE lottery_id = ? AND user_id = ?
+	`, weight, lotteryID, userID)
+	return err
+}
+
+func (r *SQLRepository) RemoveParticipant(lotteryID string, userID int64) error {
+	result, err := r.q.Exec(`DELETE FROM participants WHERE lottery_id = ? AND user_id = ?`, lotteryID, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	if _, err := r.q.Exec(`UPDATE lotteries SET participants = CASE WHEN participants > 0 THEN participants - 1 ELSE 0 END WHERE id = ?`, lotteryID); err != nil {
+		return err
+	}
+	_, err = r.q.Exec(`DELETE FROM prize_weights WHERE lottery_id = ? AND user_id = ?`, lotteryID, userID)
+	return err
+}
+
+// FillParticipantProfile checks for blank entries first, since the update
+// would otherwise take the write lock on every message.
+func (r *SQLRepository) FillParticipantProfile(userID int64, username, firstName, lastName string) error {
+	var pending bool
+	err := r.q.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM participants
+			WHERE user_id = ? AND username = '' AND first_name = '' AND last_name = ''
+		)
+	`, userID).Scan(&pending)
+	if err != nil || !pending {
+		return err
+	}
+
+	_, err = r.q.Exec(`
+		UPDATE participants SET username = ?, first_name = ?, last_name = ?
+		WHERE user_id = ? AND username = '' AND first_name = '' AND last_name = ''
+	`, username, firstName, lastName, userID)
+	return err
+}
+
+func (r *SQLRepository) GetPrizeWeight(lotteryID string, userID int64, prizeID int64) (*int, error) {
+	var weight int
+	err := r.q.QueryRow(`
+		SELECT weight FROM prize_weights WHERE lottery_id = ? AND user_id = ? AND prize_id = ?
+	`, lotteryID, userID, prizeID).Scan(&weight)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &weight, nil
+}
+
+func (r *SQLRepository) SetPrizeWeight(lotteryID string, userID int64, prizeID int64, weight int) error {
+	_, err := r.q.Exec(`
+		INSERT INTO prize_weights (lottery_id, user_id, prize_id, weight)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(lottery_id, user_id, prize_id) DO UPDATE SET weight = excluded.weight
+	`, lotteryID, userID, prizeID, weight)
+	return err
+}
+
+func (r *SQLRepository) DeletePrizeWeight(lotteryID string, userID int64, prizeID int64) error {
+	_, err := r.q.Exec(`
+		DELETE FROM prize_weights WHERE lottery_id = ? AND user_id = ? AND prize_id = ?
+	`, lotteryID, userID, prizeID)
+	return err
+}
+
+func (r *SQLRepository) GetWeightDistribution(lotteryID string) ([]models.WeightBucket, error) {
+	rows, err := r.q.Query(`
+		SELECT weight, COUNT(*) FROM participants
+		WHERE lottery_id = ?
+		GROUP BY weight ORDER BY weight
+	`, lotteryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []models.WeightBucket{}
+	for rows.Next() {
+		var b models.WeightBucket
+		if err := rows.Scan(&b.Weight, &b.Participants); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// GetPrizeWeightDistributions only counts the prizes that some participant
+// has an override for; every other prize is drawn with the base weights.
+func (r *SQLRepository) GetPrizeWeightDistributions(lotteryID string) ([]models.PrizeWeightDistribution, error) {
+	rows, err := r.q.Query(`
+		SELECT pr.id, COALESCE(pw.weight, p.weight) AS effective_weight, COUNT(*)
+		FROM prizes pr
+		JOIN participants p ON p.lottery_id = pr.lottery_id
+		LEFT JOIN prize_weights pw ON pw.lottery_id = p.lottery_id AND pw.user_id = p.user_id AND pw.prize_id = pr.id
+		WHERE pr.lottery_id = ? AND EXISTS (
+			SELECT 1 FROM prize_weights o
+			JOIN participants op ON op.lottery_id = o.lottery_id AND op.user_id = o.user_id
+			WHERE o.prize_id = pr.id
+		)
+		GROUP BY pr.id, COALESCE(pw.weight, p.weight)
+		ORDER BY pr.id, effective_weight
+	`, lotteryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var distributions []models.PrizeWeightDistribution
+	for rows.Next() {
+		var prizeID int64
+		var b models.WeightBucket
+		if err := rows.Scan(&prizeID, &b.Weight, &b.Participants); err != nil {
+			return nil, err
+		}
+		if n := len(distributions); n == 0 || distributions[n-1].PrizeID != prizeID {
+			distributions = append(distributions, models.PrizeWeightDistribution{PrizeID: prizeID})
+		}
+		last := &distributions[len(distributions)-1]
+		last.Distribution = append(last.Distribution, b)
+	}
+	return distributions, rows.Err()
+}
+
+func (r *SQLRepository) GetUserActiveEntries(userID int64, limit int) ([]models.UserEntry, error) {
+	rows, err := r.q.Query(`
+		SELECT l.id, l.title, l.description, l.creator_id, l.participants, l.draw_mode, l.draw_time, l.max_entries, l.status, l.created_at, l.is_weights_disabled, p.joined_at
+		FROM participants p
+		JOIN lotteries l ON l.id = p.lottery_id
+		WHERE p.user_id = ? AND l.status = 'active'
+		ORDER BY p.joined_at DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.UserEntry
+	for rows.Next() {
+		var e models.UserEntry
+		if err := scanLottery(rows, &e.Lottery, &e.JoinedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (r *SQLRepository) CreateWinners(winners []models.Winner) error {
+	for i := range winners {
+		w := &winners[i]
+		if err := r.q.QueryRow(`
+			INSERT INTO winners (lottery_id, participant_id, prize_id, user_id, username, first_name, last_name, joined_at, prize_name)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			RETURNING id
+		`, w.LotteryID, w.ParticipantID, w.PrizeID, w.UserID, w.Username, w.FirstName, w.LastName, w.JoinedAt, w.PrizeName).Scan(&w.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const winnerColumns = `id, lottery_id, participant_id, prize_id, user_id, username, first_name, last_name, joined_at, prize_name`
+
+func scanWinners(rows *sql.Rows) ([]models.Winner, error) {
+	defer rows.Close()
+
+	var winners []models.Winner
+	for rows.Next() {
+		var w models.Winner
+		if err := rows.Scan(&w.ID, &w.LotteryID, &w.ParticipantID, &w.PrizeID, &w.UserID, &w.Username, &w.FirstName, &w.LastName, &w.JoinedAt, &w.PrizeName); err != nil {
+			return nil, err
+		}
+		winners = append(winners, w)
+	}
+	return winners, rows.Err()
+}
+
+func (r *SQLRepository) GetWinners(lotteryID string) ([]models.Winner, error) {
+	rows, err := r.q.Query(`SELECT `+winnerColumns+` FROM winners WHERE lottery_id = ? ORDER BY id`, lotteryID)
+	if err != nil {
+		return nil, err
+	}
+	return scanWinners(rows)
+}
+
+func (r *SQLRepository) GetWinnersAfter(lotteryID string, afterID int64, limit int) ([]models.Winner, error) {
+	rows, err := r.q.Query(`
+		SELECT `+winnerColumns+`
+		FROM winners WHERE lottery_id = ? AND id > ? ORDER BY id LIMIT ?
+	`, lotteryID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanWinners(rows)
+}
+
+func (r *SQLRepository) GetUserWins(userID int64, limit int) ([]models.UserWin, error) {
+	rows, err := r.q.Query(`
+		SELECT w.lottery_id, l.title, w.prize_name
+		FROM winners w
+		JOIN lotteries l ON l.id = w.lottery_id
+		WHERE w.user_id = ?
+		ORDER BY w.id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var wins []models.UserWin
+	for rows.Next() {
+		var w models.UserWin
+		if err := rows.Scan(&w.LotteryID, &w.Title, &w.PrizeName); err != nil {
+			return nil, err
+		}
+		wins = append(wins, w)
+	}
+	return wins, rows.Err()
+}
+
+func (r *SQLRepository) ClearAfterDraw(lotteryID string) error {
+	if _, err := r.q.Exec(`DELETE FROM prize_weights WHERE lottery_id = ?`, lotteryID); err != nil {
+		return err
+	}
+	if _, err := r.q.Exec(`DELETE FROM participants WHERE lottery_id = ?`, lotteryID); err != nil {
+		return err
+	}
+	_, err := r.q.Exec(`DELETE FROM edit_sessions WHERE lottery_id = ?`, lotteryID)
+	return err
+}
+
+func (r *SQLRepository) GetOrganizerRole(lotteryID string, userID int64) (string, error) {
+	var role string
+	err := r.q.QueryRow(`
+		SELECT role FROM lottery_organizers WHERE lottery_id = ? AND user_id = ?
+	`, lotteryID, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+func (r *SQLRepository) GetOrganizers(lotteryID string) ([]models.Organizer, error) {
+	rows, err := r.q.Query(`
+		SELECT lottery_id, user_id, role, created_at
+		FROM lottery_organizers WHERE lottery_id = ? ORDER BY created_at
+	`, lotteryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var organizers []models.Organizer
+	for rows.Next() {
+		var o models.Organizer
+		if err := rows.Scan(&o.LotteryID, &o.UserID, &o.Role, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		organizers = append(organizers, o)
+	}
+	return organizers, rows.Err()
+}
+
+// SetOrganizer keeps the original creation time when changing a role.
+func (r *SQLRepository) SetOrganizer(lotteryID string, userID int64, role string) error {
+	_, err := r.q.Exec(`
+		INSERT INTO lottery_organizers (lottery_id, user_id, role, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(lottery_id, user_id) DO UPDATE SET role = excluded.role
+	`, lotteryID, userID, role, time.Now().UTC())
+	return err
+}
+
+func (r *SQLRepository) RemoveOrganizer(lotteryID string, userID int64) error {
+	_, err := r.q.Exec(`DELETE FROM lottery_organizers WHERE lottery_id = ? AND user_id = ?`, lotteryID, userID)
+	return err
+}
+
+func (r *SQLRepository) CreateEditSession(session *models.EditSession, linkCodeHash string) error {
+	session.CreatedAt = time.Now().UTC()
+
+	return r.q.QueryRow(`
+		INSERT INTO edit_sessions (lottery_id, user_id, role, name, link_code_hash, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, session.LotteryID, session.UserID, session.Role, session.Name, linkCodeHash, session.CreatedAt, session.ExpiresAt).Scan(&session.ID)
+}
+
+func (r *SQLRepository) ExchangeEditLinkCode(lotteryID, linkCodeHash, tokenHash string) (*models.EditSession, error) {
+	now := time.Now().UTC()
+	return r.touchEditSession(`
+		UPDATE edit_sessions SET link_code_hash = NULL, token_hash = ?, last_used_at = ?
+		WHERE lottery_id = ? AND link_code_hash = ? AND expires_at > ?
+	`, lotteryID, tokenHash, tokenHash, now, lotteryID, linkCodeHash, now)
+}
+
+func (r *SQLRepository) ValidateEditSession(lotteryID, tokenHash string) (*models.EditSession, error) {
+	now := time.Now().UTC()
+	return r.touchEditSession(`
+		UPDATE edit_sessions SET last_used_at = ?
+		WHERE lottery_id = ? AND token_hash = ? AND expires_at > ?
+	`, lotteryID, tokenHash, now, lotteryID, tokenHash, now)
+}
+
+// touchEditSession runs an update of a live session and then reads the
+// session back by its token hash, or returns nil if nothing was updated.
+func (r *SQLRepository) touchEditSession(update, lotteryID, tokenHash string, args ...any) (*models.EditSession, error) {
+	result, err := r.q.Exec(update, args...)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, nil
+	}
+
+	session := &models.EditSession{}
+	err = scanEditSession(r.q.QueryRow(`
+		SELECT `+editSessionColumns+` FROM edit_sessions WHERE lottery_id = ? AND token_hash = ?
+	`, lotteryID, tokenHash), session)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+const editSessionColumns = `id, lottery_id, user_id, role, name, created_at, expires_at, last_used_at`
+
+func scanEditSession(row scanner, s *models.EditSession) error {
+	return row.Scan(&s.ID, &s.LotteryID, &s.UserID, &s.Role, &s.Name, &s.CreatedAt, &s.ExpiresAt, &s.LastUsedAt)
+}
+
+func (r *SQLRepository) GetEditSessions(lotteryID string) ([]models.EditSession, error) {
+	rows, err := r.q.Query(`
+		SELECT `+editSessionColumns+`
+		FROM edit_sessions WHERE lottery_id = ? AND expires_at > ?
+		ORDER BY created_at
+	`, lotteryID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []models.EditSession
+	for rows.Next() {
+		var session models.EditSession
+		if err := scanEditSession(rows, &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (r *SQLRepository) DeleteEditSession(lotteryID string, sessionID int64) (bool, error) {
+	result, err := r.q.Exec(`DELETE FROM edit_sessions WHERE lottery_id = ? AND id = ?`, lotteryID, sessionID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+func (r *SQLRepository) DeleteUserEditSessions(lotteryID string, userID int64) error {
+	_, err := r.q.Exec(`DELETE FROM edit_sessions WHERE lottery_id = ? AND user_id = ?`, lotteryID, userID)
+	return err
+}
+
+func (r *SQLRepository) DeleteExpiredEditSessions(now time.Time) error {
+	_, err := r.q.Exec(`DELETE FROM edit_sessions WHERE expires_at < ?`, now)
+	return err
+}
+
+func (r *SQLRepository) CreateLotteryMessage(m *models.LotteryMessage) error {
+	m.CreatedAt = time.Now().UTC()
+
+	return r.q.QueryRow(`
+		INSERT INTO lottery_messages (lottery_id, chat_id, message_id, participants, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id
+	`, m.LotteryID, m.ChatID, m.MessageID, m.Participants, m.CreatedAt).Scan(&m.ID)
+}
+
+func (r *SQLRepository) GetLotteryMessages(lotteryID string) ([]models.LotteryMessage, error) {
+	rows, err := r.q.Query(`
+		SELECT id, lottery_id, chat_id, message_id, participants, revision, rendered_revision, created_at
+		FROM lottery_messages WHERE lottery_id = ?
+	`, lotteryID)
+	if err != nil {
+		return nil, err
+	}
+	return scanLotteryMessages(rows)
+}
+
+func (r *SQLRepository) GetOutdatedLotteryMessages() ([]models.LotteryMessage, error) {
+	rows, err := r.q.Query(`
+		SELECT m.id, m.lottery_id, m.chat_id, m.message_id, m.participants, m.revision, m.rendered_revision, m.created_at
+		FROM lottery_messages m
+		JOIN lotteries l ON l.id = m.lottery_id
+		WHERE l.status = 'active' AND (m.participants != l.participants OR m.rendered_revision != m.revision)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return scanLotteryMessages(rows)
+}
+
+func (r *SQLRepository) MarkLotteryMessagesOutdated(lotteryID string) error {
+	_, err := r.q.Exec(`UPDATE lottery_messages SET revision = revision + 1 WHERE lottery_id = ?`, lotteryID)
+	return err
+}
+
+func (r *SQLRepository) MarkLotteryMessageRendered(id int64, participants, revision int) error {
+	_, err := r.q.Exec(`
+		UPDATE lottery_messages SET participants = ?, rendered_revision = ? WHERE id = ?
+	`, participants, revision, id)
+	return err
+}
+
+func scanLotteryMessages(rows *sql.Rows) ([]models.LotteryMessage, error) {
+	defer rows.Close()
+
+	var messages []models.LotteryMessage
+	for rows.Next() {
+		var m models.LotteryMessage
+		if err := rows.Scan(&m.ID, &m.LotteryID, &m.ChatID, &m.MessageID, &m.Participants, &m.Revision, &m.RenderedRevision, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+func (r *SQLRepository) IsUserBanned(userID int64) (bool, error) {
+	var banned bool
+	err := r.q.QueryRow(`SELECT EXISTS(SELECT 1 FROM banned_users WHERE user_id = ?)`, userID).Scan(&banned)
+	return banned, err
+}
+
+func (r *SQLRepository) GetBannedUser(userID int64) (*models.BannedUser, error) {
+	ban := &models.BannedUser{}
+	err := r.q.QueryRow(`
+		SELECT user_id, reason, banned_by, created_at FROM banned_users WHERE user_id = ?
+	`, userID).Scan(&ban.UserID, &ban.Reason, &ban.BannedBy, &ban.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ban, nil
+}
+
+func (r *SQLRepository) BanUser(ban *models.BannedUser) error {
+	if ban.CreatedAt.IsZero() {
+		ban.CreatedAt = time.Now().UTC()
+	}
+	_, err := r.q.Exec(`
+		INSERT INTO banned_users (user_id, reason, banned_by, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET reason = excluded.reason, banned_by = excluded.banned_by, created_at = excluded.created_at
+	`, ban.UserID, ban.Reason, ban.BannedBy, ban.CreatedAt)
+	return err
+}
+
+func (r *SQLRepository) UnbanUser(userID int64) error {
+	_, err := r.q.Exec(`DELETE FROM banned_users WHERE user_id = ?`, userID)
+	return err
+}
+
+func (r *SQLRepository) AppendAudit(entry *models.AuditEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+	return r.q.QueryRow(`
+		INSERT INTO audit_log (lottery_id, actor_id, action, target, old_value, new_value, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, entry.LotteryID, entry.ActorID, entry.Action, entry.Target, nullJSON(entry.OldValue), nullJSON(entry.NewValue), entry.CreatedAt).Scan(&entry.ID)
+}
+
+func nullJSON(value json.RawMessage) sql.NullString {
+	return sql.NullString{String: string(value), Valid: value != nil}
+}
+
+const auditColumns = `id, lottery_id, actor_id, action, target, old_value, new_value, created_at`
+
+func scanAuditEntry(row scanner, e *models.AuditEntry) error {
+	var oldValue, newValue sql.NullString
+	if err := row.Scan(&e.ID, &e.LotteryID, &e.ActorID, &e.Action, &e.Target, &oldValue, &newValue, &e.CreatedAt); err != nil {
+		return err
+	}
+	if oldValue.Valid {
+		e.OldValue = json.RawMessage(oldValue.String)
+	}
+	if newValue.Valid {
+		e.NewValue = json.RawMessage(newValue.String)
+	}
+	return nil
+}
+
+func (r *SQLRepository) GetAuditLog(lotteryID string, beforeID int64, limit int) ([]models.AuditEntry, error) {
+	return r.queryAuditLog(`lottery_id = ?`, []any{lotteryID}, beforeID, limit)
+}
+
+func (r *SQLRepository) GetFullAuditLog(beforeID int64, limit int) ([]models.AuditEntry, error) {
+	return r.queryAuditLog(`1 = 1`, nil, beforeID, limit)
+}
+
+func (r *SQLRepository) queryAuditLog(where string, args []any, beforeID int64, limit int) ([]models.AuditEntry, error) {
+	query := `SELECT ` + auditColumns + ` FROM audit_log WHERE ` + where
+	if beforeID > 0 {
+		query += ` AND id < ?`
+		args = append(args, beforeID)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := scanAuditEntry(rows, &e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (r *SQLRepository) LastAuditEntry(lotteryID, action string) (*models.AuditEntry, error) {
+	entry := &models.AuditEntry{}
+	err := scanAuditEntry(r.q.QueryRow(`
+		SELECT `+auditColumns+` FROM audit_log
+		WHERE lottery_id = ? AND action = ?
+		ORDER BY id DESC LIMIT 1
+	`, lotteryID, action), entry)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (r *SQLRepository) LastWeightChangeAfterJoin(lotteryID string) (*time.Time, error) {
+	jsonText := r.dialect.jsonText
+	var changedAt time.Time
+	err := r.q.QueryRow(`
+		SELECT created_at FROM audit_log
+		WHERE lottery_id = ?
+		  AND id > (
+			SELECT MIN(id) FROM audit_log
+			WHERE lottery_id = ? AND action IN (?, ?)
+		  )
+		  AND (
+			action IN (?, ?, ?)
+			OR (action = ? AND CAST(`+jsonText("new_value", "weight")+` AS INTEGER) != 1)
+			OR (action = ? AND `+jsonText("old_value", "is_weights_disabled")+`
+				`+r.dialect.isDistinct+` `+jsonText("new_value", "is_weights_disabled")+`)
+		  )
+		ORDER BY id DESC LIMIT 1
+	`, lotteryID,
+		lotteryID, models.AuditParticipantJoin, models.AuditParticipantAdd,
+		models.AuditParticipantWeight, models.AuditPrizeWeightSet, models.AuditPrizeWeightDelete,
+		models.AuditParticipantAdd,
+		models.AuditLotteryUpdate,
+	).Scan(&changedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &changedAt, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,154 @@
+package events
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/realSunyz/lucky-tgbot/pkg/metrics"
+)
+
+// Event types
+const (
+	TypeParticipants = "participants"
+	TypeLottery      = "lottery"
+	TypeWeights      = "weights"
+	TypeDrawStarted  = "draw_started"
+	TypeDrawFailed   = "draw_failed"
+	TypeWinners      = "winners"
+	TypeDeleted      = "deleted"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind by before
+// it is dropped.
+const subscriberBuffer = 16
+
+var (
+	ErrTooManySubscribers = errors.New("too many subscribers for this lottery")
+	ErrBrokerFull         = errors.New("too many subscribers")
+	ErrClientLimit        = errors.New("too many subscriptions from this client")
+)
+
+// Event is a change to a lottery. Data is encoded as JSON for clients.
+type Event struct {
+	Type      string
+	LotteryID string
+	Data      any
+}
+
+// Subscription receives the events of one lottery on C. C is closed when the
+// subscription is closed or falls too far behind, in which case the client
+// should reconnect and reload the lottery.
+type Subscription struct {
+	C <-chan Event
+
+	ch        chan Event
+	lotteryID string
+	client    string
+	broker    *Broker
+	once      sync.Once
+}
+
+// Close stops the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.broker.remove(s)
+}
+
+// Broker fans out lottery events to subscribers in this process. Events are
+// not shared between processes, so with several replicas on one PostgreSQL
+// database a stream only hears of changes made by the replica serving it.
+type Broker struct {
+	mu         sync.Mutex
+	subs       map[string]map[*Subscription]struct{}
+	clients    map[string]int
+	total      int
+	perClient  int
+	perLottery int
+	maxTotal   int
+}
+
+// NewBroker allows at most perClient subscriptions from one client,
+// perLottery subscribers to each lottery and maxTotal subscribers overall.
+func NewBroker(perClient, perLottery, maxTotal int) *Broker {
+	return &Broker{
+		subs:       make(map[string]map[*Subscription]struct{}),
+		clients:    make(map[string]int),
+		perClient:  perClient,
+		perLottery: perLottery,
+		maxTotal:   maxTotal,
+	}
+}
+
+// Subscribe starts receiving the events of a lottery on behalf of client,
+// which identifies the caller, such as by IP address.
+func (b *Broker) Subscribe(lotteryID, client string) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.total >= b.maxTotal {
+		return nil, ErrBrokerFull
+	}
+	if b.clients[client] >= b.perClient {
+		return nil, ErrClientLimit
+	}
+	subs := b.subs[lotteryID]
+	if len(subs) >= b.perLottery {
+		return nil, ErrTooManySubscribers
+	}
+	if subs == nil {
+		subs = make(map[*Subscription]struct{})
+		b.subs[lotteryID] = subs
+	}
+
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, lotteryID: lotteryID, client: client, broker: b}
+	subs[sub] = struct{}{}
+	b.clients[client]++
+	b.total++
+	metrics.EventStreams.Inc()
+	return sub, nil
+}
+
+// HasSubscribers reports whether anyone is listening to a lottery, so callers
+// can skip building events nobody receives.
+func (b *Broker) HasSubscribers(lotteryID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs[lotteryID]) > 0
+}
+
+// Publish sends an event to every subscriber of its lottery without blocking.
+// Subscribers whose buffer is full are dropped.
+func (b *Broker) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs[event.LotteryID] {
+		select {
+		case sub.ch <- event:
+		default:
+			b.removeLocked(sub)
+		}
+	}
+}
+
+func (b *Broker) remove(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.removeLocked(sub)
+}
+
+func (b *Broker) removeLocked(sub *Subscription) {
+	sub.once.Do(func() {
+		subs := b.subs[sub.lotteryID]
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(b.subs, sub.lotteryID)
+		}
+		if b.clients[sub.client]--; b.clients[sub.client] <= 0 {
+			delete(b.clients, sub.client)
+		}
+		b.total--
+		metrics.EventStreams.Dec()
+		close(sub.ch)
+	})
+}
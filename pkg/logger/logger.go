@@ -1,36 +1,130 @@
 package logger
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
-	"time"
+	"strings"
 )
 
-var (
-	stdout = log.New(os.Stdout, "", 0)
-	stderr = log.New(os.Stderr, "", 0)
-)
+type attrsKey struct{}
+
+// Setup installs the default logger. format is "text" or "json" and level is
+// one of debug, info, warn or error.
+func Setup(w io.Writer, format, level string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", "text":
+		handler = slog.NewTextHandler(w, opts)
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return fmt.Errorf("invalid log format %q, expected text or json", format)
+	}
+
+	slog.SetDefault(slog.New(contextHandler{handler}))
+	return nil
+}
+
+// With returns a context whose log records carry the given key-value pairs,
+// such as the request ID or the lottery being handled.
+func With(ctx context.Context, args ...any) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	merged := make([]slog.Attr, 0, len(attrs)+len(args)/2)
+	merged = append(merged, attrs...)
+	merged = append(merged, argsToAttrs(args)...)
+	return context.WithValue(ctx, attrsKey{}, merged)
+}
 
-func Infof(format string, args ...any) {
-	logf(stdout, "INFO", format, args...)
+func Debug(msg string, args ...any) {
+	log(context.Background(), slog.LevelDebug, msg, args...)
 }
 
-func Warnf(format string, args ...any) {
-	logf(stdout, "WARN", format, args...)
+func Info(msg string, args ...any) {
+	log(context.Background(), slog.LevelInfo, msg, args...)
 }
 
-func Errorf(format string, args ...any) {
-	logf(stderr, "ERROR", format, args...)
+func Warn(msg string, args ...any) {
+	log(context.Background(), slog.LevelWarn, msg, args...)
 }
 
-func Fatalf(format string, args ...any) {
-	logf(stderr, "FATAL", format, args...)
+func Error(msg string, args ...any) {
+	log(context.Background(), slog.LevelError, msg, args...)
+}
+
+// Fatal logs at error level and exits the process.
+func Fatal(msg string, args ...any) {
+	log(context.Background(), slog.LevelError, msg, args...)
 	os.Exit(1)
 }
 
-func logf(l *log.Logger, level, format string, args ...any) {
-	ts := time.Now().Format(time.RFC3339)
-	msg := fmt.Sprintf(format, args...)
-	l.Printf("%s [%s] %s", ts, level, msg)
+// The *Context variants also attach the attributes stored in ctx by With.
+
+func DebugContext(ctx context.Context, msg string, args ...any) {
+	log(ctx, slog.LevelDebug, msg, args...)
+}
+
+func InfoContext(ctx context.Context, msg string, args ...any) {
+	log(ctx, slog.LevelInfo, msg, args...)
+}
+
+func WarnContext(ctx context.Context, msg string, args ...any) {
+	log(ctx, slog.LevelWarn, msg, args...)
+}
+
+func ErrorContext(ctx context.Context, msg string, args ...any) {
+	log(ctx, slog.LevelError, msg, args...)
+}
+
+func log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	logger := slog.Default()
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	logger.Log(ctx, level, msg, args...)
+}
+
+func argsToAttrs(args []any) []slog.Attr {
+	record := slog.Record{}
+	record.Add(args...)
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	return attrs
+}
+
+// contextHandler adds the attributes stored by With to every record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if attrs, ok := ctx.Value(attrsKey{}).([]slog.Attr); ok {
+		record.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
 }
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "lucky"
+
+// Draw sources
+const (
+	DrawSourceScheduler = "scheduler"
+	DrawSourceFull      = "full"
+	DrawSourceManual    = "manual"
+)
+
+// Join sources
+const (
+	JoinSourceSelf      = "self"
+	JoinSourceOrganizer = "organizer"
+)
+
+var (
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	LotteriesCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lotteries_created_total",
+		Help:      "Lotteries set up and activated.",
+	})
+
+	Joins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lottery_joins_total",
+		Help:      "Participants added to lotteries by source.",
+	}, []string{"source"})
+
+	Draws = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lottery_draws_total",
+		Help:      "Completed lottery draws by source.",
+	}, []string{"source"})
+
+	DrawFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lottery_draw_failures_total",
+		Help:      "Automatic draws that failed after every retry, by source.",
+	}, []string{"source"})
+
+	NotifierSendErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifier_send_errors_total",
+		Help:      "Telegram messages the notifier failed to send, by notification.",
+	}, []string{"notification"})
+
+	AutoDrawCheckDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "auto_draw_check_duration_seconds",
+		Help:      "Duration of the scheduled check for lotteries due to be drawn.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	EventStreams = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "event_streams",
+		Help:      "Open lottery event streams.",
+	})
+
+	CleanupDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cleanup_duration_seconds",
+		Help:      "Duration of the periodic cleanup worker run.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	BackupDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "backup_duration_seconds",
+		Help:      "Duration of database backups, scheduled or on demand.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300},
+	})
+
+	LastBackup = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_backup_timestamp_seconds",
+		Help:      "Unix time of the last successful database backup.",
+	})
+)
+
+// RegisterDB exports the connection pool stats of db, labelled with the
+// database driver.
+func RegisterDB(db *sql.DB, driver string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, driver))
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.Handler())
+}
@@ -1,6 +1,15 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	RoleOwner     = "owner"
+	RoleEditor    = "editor"
+	RoleModerator = "moderator"
+)
 
 type Lottery struct {
 	ID                string     `json:"id"`
@@ -35,6 +44,37 @@ type Participant struct {
 	JoinedAt     time.Time     `json:"joined_at"`
 }
 
+// Participant list sort orders and filters
+const (
+	ParticipantSortJoined = "joined"
+	ParticipantSortWeight = "weight"
+
+	ParticipantFilterCustomWeight      = "custom_weight"
+	ParticipantFilterCustomPrizeWeight = "custom_prize_weight"
+)
+
+// ParticipantQuery selects one page of a lottery's participants. Search
+// matches the username or names, or the exact user ID. Cursor is the
+// NextCursor of the previous page and must be used with the same query.
+type ParticipantQuery struct {
+	LotteryID string
+	Search    string
+	Sort      string
+	Desc      bool
+	Filter    string
+	Cursor    string
+	Limit     int
+}
+
+// ParticipantPage is one page of participants. Total counts every
+// participant of the lottery and Matched those selected by the query.
+type ParticipantPage struct {
+	Participants []Participant `json:"participants"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
+	Total        int           `json:"total"`
+	Matched      int           `json:"matched"`
+}
+
 type PrizeWeight struct {
 	LotteryID string `json:"lottery_id"`
 	UserID    int64  `json:"user_id"`
@@ -42,20 +82,63 @@ type PrizeWeight struct {
 	Weight    int    `json:"weight"`
 }
 
-type EditToken struct {
-	Token     string    `json:"token"`
+type EditSession struct {
+	ID         int64      `json:"id"`
+	LotteryID  string     `json:"lottery_id"`
+	UserID     int64      `json:"user_id"`
+	Role       string     `json:"role"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type Organizer struct {
 	LotteryID string    `json:"lottery_id"`
-	ExpiresAt time.Time `json:"expires_at"`
+	UserID    int64     `json:"user_id"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
+// Winner copies the names and join time of the winning entry, which is
+// removed after the draw. Winners are public, so those fields are only read
+// by the export and never encoded. JoinedAt is nil for winners drawn before
+// it was recorded.
 type Winner struct {
-	ID            int64  `json:"id"`
-	LotteryID     string `json:"lottery_id"`
-	ParticipantID int64  `json:"participant_id"`
-	PrizeID       int64  `json:"prize_id"`
-	UserID        int64  `json:"user_id"`
-	Username      string `json:"username"`
-	PrizeName     string `json:"prize_name"`
+	ID            int64      `json:"id"`
+	LotteryID     string     `json:"lottery_id"`
+	ParticipantID int64      `json:"participant_id"`
+	PrizeID       int64      `json:"prize_id"`
+	UserID        int64      `json:"user_id"`
+	Username      string     `json:"username"`
+	FirstName     string     `json:"-"`
+	LastName      string     `json:"-"`
+	JoinedAt      *time.Time `json:"-"`
+	PrizeName     string     `json:"prize_name"`
+}
+
+// UserEntry is an active lottery the user has joined.
+type UserEntry struct {
+	Lottery
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// UserWin is a prize the user has won.
+type UserWin struct {
+	LotteryID string `json:"lottery_id"`
+	Title     string `json:"title"`
+	PrizeName string `json:"prize_name"`
+}
+
+type LotteryMessage struct {
+	ID               int64     `json:"id"`
+	LotteryID        string    `json:"lottery_id"`
+	ChatID           int64     `json:"chat_id"`
+	MessageID        int       `json:"message_id"`
+	Participants     int       `json:"participants"`
+	Revision         int       `json:"revision"`
+	RenderedRevision int       `json:"rendered_revision"`
+	CreatedAt        time.Time `json:"created_at"`
 }
 
 type LotteryStats struct {
@@ -66,3 +149,83 @@ type LotteryStats struct {
 	ScheduledCount int `json:"scheduled_count"`
 	TodayCount     int `json:"today_count"`
 }
+
+// AdminStats extends LotteryStats with figures only bot operators see.
+type AdminStats struct {
+	LotteryStats
+	ParticipantCount int `json:"participant_count"`
+	WinnerCount      int `json:"winner_count"`
+	CreatorCount     int `json:"creator_count"`
+	EditSessionCount int `json:"edit_session_count"`
+	BannedUserCount  int `json:"banned_user_count"`
+}
+
+// Backup is a snapshot of the SQLite database in the backup directory.
+type Backup struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type BannedUser struct {
+	UserID    int64     `json:"user_id"`
+	Reason    string    `json:"reason"`
+	BannedBy  int64     `json:"banned_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Audit actions
+const (
+	AuditLotteryCreate     = "lottery.create"
+	AuditLotteryUpdate     = "lottery.update"
+	AuditLotteryDraw       = "lottery.draw"
+	AuditLotteryDelete     = "lottery.delete"
+	AuditParticipantJoin   = "participant.join"
+	AuditParticipantAdd    = "participant.add"
+	AuditParticipantRemove = "participant.remove"
+	AuditParticipantWeight = "participant.weight"
+	AuditPrizeWeightSet    = "prize_weight.set"
+	AuditPrizeWeightDelete = "prize_weight.delete"
+	AuditOrganizerSet      = "organizer.set"
+	AuditOrganizerRemove   = "organizer.remove"
+	AuditUserBan           = "user.ban"
+	AuditUserUnban         = "user.unban"
+)
+
+// AuditEntry records one change. ActorID is 0 for changes made by the bot
+// itself, such as scheduled draws. OldValue and NewValue hold JSON objects
+// and are null when there is nothing before or after the change.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	LotteryID string          `json:"lottery_id"`
+	ActorID   int64           `json:"actor_id"`
+	Action    string          `json:"action"`
+	Target    string          `json:"target"`
+	OldValue  json.RawMessage `json:"old_value"`
+	NewValue  json.RawMessage `json:"new_value"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// WeightBucket is the number of participants drawn with the same weight.
+type WeightBucket struct {
+	Weight       int `json:"weight"`
+	Participants int `json:"participants"`
+}
+
+// PrizeWeightDistribution is the weight distribution for one prize that has
+// per-prize overrides.
+type PrizeWeightDistribution struct {
+	PrizeID      int64          `json:"prize_id"`
+	Distribution []WeightBucket `json:"distribution"`
+}
+
+// WeightSummary is the public, anonymised view of the weights a lottery is
+// drawn with. LastChangedAt is the latest weight change made after the first
+// participant joined; ChangedAfterJoin is true when there is one.
+type WeightSummary struct {
+	Custom           bool                      `json:"custom"`
+	Distribution     []WeightBucket            `json:"distribution"`
+	Prizes           []PrizeWeightDistribution `json:"prizes,omitempty"`
+	ChangedAfterJoin bool                      `json:"changed_after_join"`
+	LastChangedAt    *time.Time                `json:"last_changed_at,omitempty"`
+}
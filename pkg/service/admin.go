@@ -0,0 +1,167 @@
+package service
+
+import (
+	"github.com/realSunyz/lucky-tgbot/pkg/database"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	"github.com/realSunyz/lucky-tgbot/pkg/models"
+)
+
+// AdminLotteryInfo is everything a bot operator sees when inspecting a lottery.
+type AdminLotteryInfo struct {
+	*LotterySnapshot
+	Organizers []models.Organizer
+	Sessions   []models.EditSession
+}
+
+// IsAdmin reports whether the user is one of the configured bot operators.
+func (s *LotteryService) IsAdmin(userID int64) bool {
+	_, ok := s.admins[userID]
+	return ok
+}
+
+func (s *LotteryService) AdminStats(adminID int64) (*models.AdminStats, error) {
+	if !s.IsAdmin(adminID) {
+		return nil, ErrPermissionDenied
+	}
+	s.auditAdmin(adminID, "stats", "")
+	return s.repo.GetAdminStats()
+}
+
+func (s *LotteryService) AdminInspectLottery(adminID int64, lotteryID string) (*AdminLotteryInfo, error) {
+	if !s.IsAdmin(adminID) {
+		return nil, ErrPermissionDenied
+	}
+
+	snapshot, err := s.GetLotterySnapshot(lotteryID)
+	if err != nil {
+		return nil, err
+	}
+	organizers, err := s.repo.GetOrganizers(lotteryID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.repo.GetEditSessions(lotteryID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditAdmin(adminID, "inspect", lotteryID)
+	return &AdminLotteryInfo{LotterySnapshot: snapshot, Organizers: organizers, Sessions: sessions}, nil
+}
+
+// AdminDrawLottery draws any active lottery regardless of who owns it.
+func (s *LotteryService) AdminDrawLottery(adminID int64, lotteryID string) ([]models.Winner, error) {
+	if !s.IsAdmin(adminID) {
+		return nil, ErrPermissionDenied
+	}
+
+	return s.DrawLottery(lotteryID, adminID)
+}
+
+// AdminDeleteLottery deletes a lottery in any state regardless of who owns it.
+func (s *LotteryService) AdminDeleteLottery(adminID int64, lotteryID string) error {
+	if !s.IsAdmin(adminID) {
+		return ErrPermissionDenied
+	}
+
+	lottery, err := s.repo.GetLottery(lotteryID)
+	if err != nil {
+		return err
+	}
+	if lottery == nil {
+		return ErrLotteryNotFound
+	}
+	return s.deleteLottery(lottery, adminID)
+}
+
+// AdminBanUser stops a user from creating or joining any lottery.
+func (s *LotteryService) AdminBanUser(adminID int64, userID int64, reason string) error {
+	if !s.IsAdmin(adminID) {
+		return ErrPermissionDenied
+	}
+	if s.IsAdmin(userID) {
+		return ErrCannotBanAdmin
+	}
+
+	return s.repo.InTx(func(tx database.LotteryRepository) error {
+		var oldValue any
+		old, err := tx.GetBannedUser(userID)
+		if err != nil {
+			return err
+		}
+		if old != nil {
+			oldValue = map[string]string{"reason": old.Reason}
+		}
+
+		if err := tx.BanUser(&models.BannedUser{UserID: userID, Reason: reason, BannedBy: adminID}); err != nil {
+			return err
+		}
+		return appendAudit(tx, "", adminID, models.AuditUserBan, participantTarget(userID), oldValue, map[string]string{"reason": reason})
+	})
+}
+
+func (s *LotteryService) AdminUnbanUser(adminID int64, userID int64) error {
+	if !s.IsAdmin(adminID) {
+		return ErrPermissionDenied
+	}
+
+	return s.repo.InTx(func(tx database.LotteryRepository) error {
+		ban, err := tx.GetBannedUser(userID)
+		if err != nil {
+			return err
+		}
+		if ban == nil {
+			return ErrUserNotBanned
+		}
+
+		if err := tx.UnbanUser(userID); err != nil {
+			return err
+		}
+		return appendAudit(tx, "", adminID, models.AuditUserUnban, participantTarget(userID), map[string]string{"reason": ban.Reason}, nil)
+	})
+}
+
+// Backupper takes a database backup on demand.
+type Backupper interface {
+	Backup() (*models.Backup, error)
+}
+
+// SetBackupper enables AdminBackup. Without one it returns
+// ErrBackupsDisabled.
+func (s *LotteryService) SetBackupper(b Backupper) {
+	s.backups = b
+}
+
+// AdminBackup takes a database backup right away.
+func (s *LotteryService) AdminBackup(adminID int64) (*models.Backup, error) {
+	if !s.IsAdmin(adminID) {
+		return nil, ErrPermissionDenied
+	}
+	if s.backups == nil {
+		return nil, ErrBackupsDisabled
+	}
+
+	backup, err := s.backups.Backup()
+	if err != nil {
+		return nil, err
+	}
+	s.auditAdmin(adminID, "backup", backup.Name)
+	return backup, nil
+}
+
+// checkNotBanned returns ErrUserBanned for users an operator has banned.
+func (s *LotteryService) checkNotBanned(userID int64) error {
+	banned, err := s.repo.IsUserBanned(userID)
+	if err != nil {
+		return err
+	}
+	if banned {
+		return ErrUserBanned
+	}
+	return nil
+}
+
+// auditAdmin logs admin reads. Changes are recorded in the audit log instead.
+func (s *LotteryService) auditAdmin(adminID int64, action string, target string) {
+	logger.Info("admin audit", "admin_id", adminID, "action", action, "target", target)
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/realSunyz/lucky-tgbot/pkg/database"
+	"github.com/realSunyz/lucky-tgbot/pkg/models"
+)
+
+// SystemActorID is recorded as the actor of changes the bot makes on its own.
+const SystemActorID int64 = 0
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+// lotteryAuditValue is how a lottery's editable fields are recorded.
+type lotteryAuditValue struct {
+	Title             string            `json:"title"`
+	Description       string            `json:"description"`
+	DrawMode          string            `json:"draw_mode"`
+	DrawTime          *time.Time        `json:"draw_time"`
+	MaxEntries        *int              `json:"max_entries"`
+	Status            string            `json:"status"`
+	IsWeightsDisabled bool              `json:"is_weights_disabled"`
+	Prizes            []prizeAuditValue `json:"prizes,omitempty"`
+}
+
+type prizeAuditValue struct {
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+}
+
+func newLotteryAuditValue(lottery *models.Lottery, prizes []models.Prize) *lotteryAuditValue {
+	value := &lotteryAuditValue{
+		Title:             lottery.Title,
+		Description:       lottery.Description,
+		DrawMode:          lottery.DrawMode,
+		DrawTime:          lottery.DrawTime,
+		MaxEntries:        lottery.MaxEntries,
+		Status:            lottery.Status,
+		IsWeightsDisabled: lottery.IsWeightsDisabled,
+	}
+	for _, p := range prizes {
+		value.Prizes = append(value.Prizes, prizeAuditValue{Name: p.Name, Quantity: p.Quantity})
+	}
+	return value
+}
+
+// drawAuditValue is how a draw is recorded. Weights keeps the distribution the
+// draw used, since participants are removed once a lottery is drawn.
+type drawAuditValue struct {
+	Source       string                `json:"source"`
+	Participants int                   `json:"participants"`
+	Winners      []map[string]any      `json:"winners"`
+	Weights      *models.WeightSummary `json:"weights,omitempty"`
+}
+
+func participantTarget(userID int64) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+func prizeWeightTarget(userID, prizeID int64) string {
+	return fmt.Sprintf("user:%d/prize:%d", userID, prizeID)
+}
+
+// GetAuditLog returns the newest audit entries of a lottery first, starting
+// below beforeID when it is positive. Owners of the lottery and bot admins may
+// read it; admins can still read it after the lottery is deleted.
+func (s *LotteryService) GetAuditLog(lotteryID string, requesterID int64, beforeID int64, limit int) ([]models.AuditEntry, error) {
+	if !s.IsAdmin(requesterID) {
+		if _, err := s.requireOwner(lotteryID, requesterID); err != nil {
+			return nil, err
+		}
+	}
+
+	return auditPage(s.repo.GetAuditLog(lotteryID, beforeID, auditPageSize(limit)))
+}
+
+// GetFullAuditLog pages through the audit entries of all lotteries and those
+// of no lottery, such as bans, like GetAuditLog. Only bot admins may read it.
+func (s *LotteryService) GetFullAuditLog(requesterID int64, beforeID int64, limit int) ([]models.AuditEntry, error) {
+	if !s.IsAdmin(requesterID) {
+		return nil, ErrPermissionDenied
+	}
+	return auditPage(s.repo.GetFullAuditLog(beforeID, auditPageSize(limit)))
+}
+
+func auditPageSize(limit int) int {
+	if limit <= 0 {
+		return defaultAuditPageSize
+	}
+	return min(limit, maxAuditPageSize)
+}
+
+// auditPage returns an empty page rather than nil, so it is encoded as [].
+func auditPage(entries []models.AuditEntry, err error) ([]models.AuditEntry, error) {
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+	return entries, nil
+}
+
+// appendAudit records a change in the same transaction that makes it. A nil
+// oldValue or newValue is stored as NULL.
+func appendAudit(tx database.LotteryRepository, lotteryID string, actorID int64, action, target string, oldValue, newValue any) error {
+	oldJSON, err := marshalAuditValue(oldValue)
+	if err != nil {
+		return err
+	}
+	newJSON, err := marshalAuditValue(newValue)
+	if err != nil {
+		return err
+	}
+
+	return tx.AppendAudit(&models.AuditEntry{
+		LotteryID: lotteryID,
+		ActorID:   actorID,
+		Action:    action,
+		Target:    target,
+		OldValue:  oldJSON,
+		NewValue:  newJSON,
+	})
+}
+
+func marshalAuditValue(value any) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit value: %w", err)
+	}
+	return data, nil
+}
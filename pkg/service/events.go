@@ -0,0 +1,85 @@
+package service
+
+import (
+	"github.com/realSunyz/lucky-tgbot/pkg/events"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	"github.com/realSunyz/lucky-tgbot/pkg/models"
+)
+
+// lotteryEvent is sent when a lottery is set up or edited. It has the shape
+// of the public lottery response without the winners and weights.
+type lotteryEvent struct {
+	*models.Lottery
+	Prizes []models.Prize `json:"prizes"`
+}
+
+type weightsEvent struct {
+	Weights *models.WeightSummary `json:"weights"`
+}
+
+type winnersEvent struct {
+	Lottery *models.Lottery `json:"lottery"`
+	Winners []models.Winner `json:"winners"`
+}
+
+// SubscribeEvents starts receiving the public events of a lottery for client,
+// whose open streams are capped. Completed lotteries have nothing more to
+// report.
+func (s *LotteryService) SubscribeEvents(lotteryID, client string) (*events.Subscription, error) {
+	lottery, err := s.repo.GetLottery(lotteryID)
+	if err != nil {
+		return nil, err
+	}
+	if lottery == nil {
+		return nil, ErrLotteryNotFound
+	}
+	if lottery.Status == "completed" {
+		return nil, ErrLotteryEnded
+	}
+	return s.events.Subscribe(lotteryID, client)
+}
+
+func (s *LotteryService) publish(lotteryID, eventType string, data any) {
+	s.events.Publish(events.Event{Type: eventType, LotteryID: lotteryID, Data: data})
+}
+
+// publishParticipants sends the participant count of a lottery. The count is
+// read back so concurrent joins are reported in order of their commits.
+func (s *LotteryService) publishParticipants(lotteryID string) {
+	if !s.events.HasSubscribers(lotteryID) {
+		return
+	}
+
+	lottery, err := s.repo.GetLottery(lotteryID)
+	if err != nil {
+		logger.Warn("failed to read participant count for event", "lottery_id", lotteryID, "error", err)
+		return
+	}
+	if lottery == nil {
+		return
+	}
+	s.publish(lotteryID, events.TypeParticipants, map[string]int{"participants": lottery.Participants})
+}
+
+// publishWeights sends the public weight summary of a lottery after its
+// weights were changed.
+func (s *LotteryService) publishWeights(lotteryID string) {
+	if !s.events.HasSubscribers(lotteryID) {
+		return
+	}
+
+	lottery, err := s.repo.GetLottery(lotteryID)
+	if err != nil {
+		logger.Warn("failed to read lottery for weights event", "lottery_id", lotteryID, "error", err)
+		return
+	}
+	if lottery == nil {
+		return
+	}
+	summary, err := s.GetWeightSummary(lottery)
+	if err != nil {
+		logger.Warn("failed to read weight summary for event", "lottery_id", lotteryID, "error", err)
+		return
+	}
+	s.publish(lotteryID, events.TypeWeights, weightsEvent{Weights: summary})
+}
@@ -0,0 +1,318 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/realSunyz/lucky-tgbot/pkg/models"
+)
+
+// Export formats
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatJSON = "json"
+)
+
+// Export contents
+const (
+	ExportParticipants = "participants"
+	ExportWinners      = "winners"
+)
+
+var ErrInvalidExport = errors.New("invalid export format or content")
+
+type participantExport struct {
+	UserID       int64               `json:"user_id"`
+	Username     string              `json:"username"`
+	FirstName    string              `json:"first_name"`
+	LastName     string              `json:"last_name"`
+	Weight       int                 `json:"weight"`
+	PrizeWeights []prizeWeightExport `json:"prize_weights"`
+	JoinedAt     time.Time           `json:"joined_at"`
+}
+
+type prizeWeightExport struct {
+	PrizeID   int64  `json:"prize_id"`
+	PrizeName string `json:"prize_name"`
+	Weight    int    `json:"weight"`
+}
+
+type winnerExport struct {
+	UserID    int64      `json:"user_id"`
+	Username  string     `json:"username"`
+	FirstName string     `json:"first_name"`
+	LastName  string     `json:"last_name"`
+	JoinedAt  *time.Time `json:"joined_at"`
+	PrizeID   int64      `json:"prize_id"`
+	PrizeName string     `json:"prize_name"`
+}
+
+// ValidateExport checks the format and content of an export request.
+func ValidateExport(format, what string) error {
+	if format != ExportFormatCSV && format != ExportFormatJSON {
+		return ErrInvalidExport
+	}
+	if what != ExportParticipants && what != ExportWinners {
+		return ErrInvalidExport
+	}
+	return nil
+}
+
+// ExportFilename is the suggested file name of an export.
+func ExportFilename(lotteryID, format, what string) string {
+	return fmt.Sprintf("lottery-%s-%s.%s", lotteryID, what, format)
+}
+
+// AuthorizeExport returns the lottery when the user is one of its organizers.
+func (s *LotteryService) AuthorizeExport(lotteryID string, userID int64) (*models.Lottery, error) {
+	lottery, err := s.repo.GetLottery(lotteryID)
+	if err != nil {
+		return nil, err
+	}
+	if lottery == nil {
+		return nil, ErrLotteryNotFound
+	}
+	role, err := s.organizerRole(lottery, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, ErrPermissionDenied
+	}
+	return lottery, nil
+}
+
+// Export writes the participants or winners of a lottery to w. Rows are read
+// in batches, and w is flushed after each batch when it supports flushing, so
+// large lotteries are never held in memory. Participants are removed when a
+// lottery is drawn, so only winners remain to export afterwards.
+func (s *LotteryService) Export(w io.Writer, lotteryID, format, what string) error {
+	if err := ValidateExport(format, what); err != nil {
+		return err
+	}
+
+	prizes, err := s.repo.GetPrizes(lotteryID)
+	if err != nil {
+		return err
+	}
+
+	var out exportEncoder
+	switch format {
+	case ExportFormatCSV:
+		out = newCSVExport(w)
+	default:
+		out = &jsonExport{w: w}
+	}
+
+	if what == ExportWinners {
+		err = s.exportWinners(out, lotteryID)
+	} else {
+		err = s.exportParticipants(out, lotteryID, prizes)
+	}
+	if err != nil {
+		return err
+	}
+	return out.end()
+}
+
+func (s *LotteryService) exportParticipants(out exportEncoder, lotteryID string, prizes []models.Prize) error {
+	header := []string{"user_id", "username", "first_name", "last_name", "weight", "joined_at"}
+	for _, prize := range prizes {
+		header = append(header, fmt.Sprintf("weight: %s #%d", prize.Name, prize.ID))
+	}
+	if err := out.begin(header); err != nil {
+		return err
+	}
+
+	return s.eachParticipantPage(lotteryID, participantBatchSize, func(participants []models.Participant) error {
+		for _, p := range participants {
+			row := participantExport{
+				UserID:       p.UserID,
+				Username:     p.Username,
+				FirstName:    p.FirstName,
+				LastName:     p.LastName,
+				Weight:       p.Weight,
+				PrizeWeights: []prizeWeightExport{},
+				JoinedAt:     p.JoinedAt.UTC(),
+			}
+			record := []string{
+				strconv.FormatInt(p.UserID, 10),
+				p.Username,
+				p.FirstName,
+				p.LastName,
+				strconv.Itoa(p.Weight),
+				row.JoinedAt.Format(time.RFC3339),
+			}
+			for _, prize := range prizes {
+				weight, ok := p.PrizeWeights[prize.ID]
+				if !ok {
+					record = append(record, "")
+					continue
+				}
+				record = append(record, strconv.Itoa(weight))
+				row.PrizeWeights = append(row.PrizeWeights, prizeWeightExport{PrizeID: prize.ID, PrizeName: prize.Name, Weight: weight})
+			}
+			if err := out.row(record, row); err != nil {
+				return err
+			}
+		}
+		return out.flush()
+	})
+}
+
+func (s *LotteryService) exportWinners(out exportEncoder, lotteryID string) error {
+	header := []string{"user_id", "username", "first_name", "last_name", "joined_at", "prize_id", "prize_name"}
+	if err := out.begin(header); err != nil {
+		return err
+	}
+
+	var afterID int64
+	for {
+		winners, err := s.repo.GetWinnersAfter(lotteryID, afterID, participantBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, w := range winners {
+			row := winnerExport{
+				UserID:    w.UserID,
+				Username:  w.Username,
+				FirstName: w.FirstName,
+				LastName:  w.LastName,
+				PrizeID:   w.PrizeID,
+				PrizeName: w.PrizeName,
+			}
+			// Winners drawn before join times were recorded have none
+			var joinedAt string
+			if w.JoinedAt != nil {
+				t := w.JoinedAt.UTC()
+				row.JoinedAt = &t
+				joinedAt = t.Format(time.RFC3339)
+			}
+			record := []string{
+				strconv.FormatInt(w.UserID, 10),
+				w.Username,
+				w.FirstName,
+				w.LastName,
+				joinedAt,
+				strconv.FormatInt(w.PrizeID, 10),
+				w.PrizeName,
+			}
+			if err := out.row(record, row); err != nil {
+				return err
+			}
+		}
+		if err := out.flush(); err != nil {
+			return err
+		}
+		if len(winners) < participantBatchSize {
+			return nil
+		}
+		afterID = winners[len(winners)-1].ID
+	}
+}
+
+// exportEncoder writes export rows in one format. Each row is given both as
+// a CSV record and as the value to encode as JSON.
+type exportEncoder interface {
+	begin(header []string) error
+	row(record []string, value any) error
+	flush() error
+	end() error
+}
+
+type csvExport struct {
+	w   io.Writer
+	csv *csv.Writer
+}
+
+func newCSVExport(w io.Writer) *csvExport {
+	return &csvExport{w: w, csv: csv.NewWriter(w)}
+}
+
+func (e *csvExport) begin(header []string) error {
+	// The header holds prize names, which are user-controlled too
+	for i, field := range header {
+		header[i] = escapeFormula(field)
+	}
+	return e.csv.Write(header)
+}
+
+func (e *csvExport) row(record []string, _ any) error {
+	for i, field := range record {
+		record[i] = escapeFormula(field)
+	}
+	return e.csv.Write(record)
+}
+
+func (e *csvExport) flush() error {
+	e.csv.Flush()
+	if err := e.csv.Error(); err != nil {
+		return err
+	}
+	return flushWriter(e.w)
+}
+
+func (e *csvExport) end() error {
+	return e.flush()
+}
+
+// escapeFormula keeps spreadsheets from evaluating user-controlled names as
+// formulas.
+func escapeFormula(field string) string {
+	if field != "" && strings.ContainsRune("=+-@\t\r", rune(field[0])) {
+		return "'" + field
+	}
+	return field
+}
+
+type jsonExport struct {
+	w    io.Writer
+	rows int
+}
+
+func (e *jsonExport) begin(_ []string) error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonExport) row(_ []string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if e.rows == 0 {
+		sep = "\n"
+	}
+	e.rows++
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonExport) flush() error {
+	return flushWriter(e.w)
+}
+
+func (e *jsonExport) end() error {
+	if _, err := io.WriteString(e.w, "\n]\n"); err != nil {
+		return err
+	}
+	return e.flush()
+}
+
+// flushWriter pushes buffered output to the client when w buffers it.
+func flushWriter(w io.Writer) error {
+	if f, ok := w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
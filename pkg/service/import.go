@@ -0,0 +1,382 @@
+package service
+
+import (
+	"container/list"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/realSunyz/lucky-tgbot/pkg/database"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	"github.com/realSunyz/lucky-tgbot/pkg/metrics"
+	"github.com/realSunyz/lucky-tgbot/pkg/models"
+)
+
+// Import row statuses
+const (
+	ImportAdded     = "added"
+	ImportDuplicate = "duplicate"
+	ImportInvalid   = "invalid"
+)
+
+// MaxImportRows is the most participants a single import may contain.
+const MaxImportRows = 5000
+
+// profileCacheSize is how many users FillParticipantProfile remembers having
+// checked.
+const profileCacheSize = 10000
+
+var (
+	ErrImportMalformed = errors.New("import file is malformed")
+	ErrImportTooLarge  = errors.New("import file has too many rows")
+)
+
+// prizeWeightColumn matches the per-prize weight columns of a CSV export, so
+// an exported file can be imported again.
+var prizeWeightColumn = regexp.MustCompile(`#(\d+)$`)
+
+// ImportRow is one participant to import. Weight defaults to 1.
+type ImportRow struct {
+	UserID       int64               `json:"user_id"`
+	Weight       *int                `json:"weight"`
+	PrizeWeights []prizeWeightExport `json:"prize_weights"`
+
+	// line is the CSV line or JSON array position reported back, and err a
+	// problem found while parsing the row.
+	line int
+	err  string
+}
+
+// ImportResult reports what happened to one row of an import.
+type ImportResult struct {
+	Row    int    `json:"row"`
+	UserID int64  `json:"user_id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport is the outcome of an import, with one result per row.
+type ImportReport struct {
+	Added      int            `json:"added"`
+	Duplicates int            `json:"duplicates"`
+	Invalid    int            `json:"invalid"`
+	Rows       []ImportResult `json:"rows"`
+}
+
+// ParseImportCSV reads participants from CSV. The header row must name a
+// user_id column and may name a weight column and per-prize weight columns
+// ending in "#<prize id>", as written by the CSV export. Other columns, such
+// as names, are ignored.
+func ParseImportCSV(r io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImportMalformed, err)
+	}
+
+	userCol, weightCol := -1, -1
+	type prizeColumn struct {
+		index   int
+		prizeID int64
+	}
+	var prizeCols []prizeColumn
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		switch {
+		case name == "user_id":
+			userCol = i
+		case name == "weight":
+			weightCol = i
+		case strings.HasPrefix(name, "weight:"):
+			if m := prizeWeightColumn.FindStringSubmatch(name); m != nil {
+				prizeID, _ := strconv.ParseInt(m[1], 10, 64)
+				prizeCols = append(prizeCols, prizeColumn{index: i, prizeID: prizeID})
+			}
+		}
+	}
+	if userCol < 0 {
+		return nil, fmt.Errorf("%w: missing user_id column", ErrImportMalformed)
+	}
+
+	var rows []ImportRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrImportMalformed, err)
+		}
+		if len(rows) == MaxImportRows {
+			return nil, ErrImportTooLarge
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := ImportRow{line: line}
+		field := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		if row.UserID, err = strconv.ParseInt(field(userCol), 10, 64); err != nil {
+			row.err = "invalid user_id"
+		}
+		if v := field(weightCol); v != "" && row.err == "" {
+			weight, err := strconv.Atoi(v)
+			if err != nil {
+				row.err = "invalid weight"
+			}
+			row.Weight = &weight
+		}
+		for _, col := range prizeCols {
+			v := field(col.index)
+			if v == "" || row.err != "" {
+				continue
+			}
+			weight, err := strconv.Atoi(v)
+			if err != nil {
+				row.err = fmt.Sprintf("invalid weight for prize %d", col.prizeID)
+				break
+			}
+			row.PrizeWeights = append(row.PrizeWeights, prizeWeightExport{PrizeID: col.prizeID, Weight: weight})
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// ParseImportJSON reads participants from a JSON array of objects with a
+// user_id and optional weight and prize_weights, as written by the JSON export.
+func ParseImportJSON(r io.Reader) ([]ImportRow, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImportMalformed, err)
+	}
+	if len(raw) > MaxImportRows {
+		return nil, ErrImportTooLarge
+	}
+
+	rows := make([]ImportRow, len(raw))
+	for i, data := range raw {
+		rows[i].line = i + 1
+		if err := json.Unmarshal(data, &rows[i]); err != nil {
+			rows[i].err = "invalid entry"
+		}
+	}
+	return rows, nil
+}
+
+// ImportParticipants adds the rows to a lottery in one transaction. Rows that
+// are invalid or already joined are reported and skipped without failing the
+// import. Names stay blank until the users next interact with the bot.
+func (s *LotteryService) ImportParticipants(lotteryID string, actorID int64, rows []ImportRow) (*ImportReport, error) {
+	lottery, err := s.repo.GetLottery(lotteryID)
+	if err != nil {
+		return nil, err
+	}
+	if lottery == nil {
+		return nil, ErrLotteryNotFound
+	}
+	if err := notEnded(lottery); err != nil {
+		return nil, err
+	}
+
+	prizes, err := s.repo.GetPrizes(lotteryID)
+	if err != nil {
+		return nil, err
+	}
+	prizeNames := make(map[int64]string, len(prizes))
+	for _, prize := range prizes {
+		prizeNames[prize.ID] = prize.Name
+	}
+
+	report := &ImportReport{Rows: make([]ImportResult, 0, len(rows))}
+	err = s.repo.InTx(func(tx database.LotteryRepository) error {
+		if _, err := lockLottery(tx, lotteryID, notEnded); err != nil {
+			return err
+		}
+		seen := make(map[int64]bool, len(rows))
+		for _, row := range rows {
+			result := ImportResult{Row: row.line, UserID: row.UserID, Status: ImportInvalid}
+			if row.err == "" {
+				row.err = validateImportRow(row, prizeNames)
+			}
+			if row.err == "" && seen[row.UserID] {
+				result.Status = ImportDuplicate
+				row.err = "user listed more than once"
+			}
+			if row.err == "" {
+				banned, err := tx.IsUserBanned(row.UserID)
+				if err != nil {
+					return err
+				}
+				if banned {
+					row.err = "user is banned"
+				}
+			}
+			if row.err != "" {
+				result.Error = row.err
+				report.Rows = append(report.Rows, result)
+				continue
+			}
+			seen[row.UserID] = true
+
+			added, err := importParticipant(tx, lotteryID, actorID, row, prizeNames)
+			if err != nil {
+				return err
+			}
+			if added {
+				result.Status = ImportAdded
+			} else {
+				result.Status = ImportDuplicate
+				result.Error = "user already joined"
+			}
+			report.Rows = append(report.Rows, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range report.Rows {
+		switch result.Status {
+		case ImportAdded:
+			report.Added++
+		case ImportDuplicate:
+			report.Duplicates++
+		default:
+			report.Invalid++
+		}
+	}
+	for _, result := range report.Rows {
+		if result.Status == ImportAdded {
+			// The new entry has no name until its user is checked again
+			s.profiled.remove(result.UserID)
+		}
+	}
+	metrics.Joins.WithLabelValues(metrics.JoinSourceOrganizer).Add(float64(report.Added))
+	if report.Added > 0 {
+		s.publishParticipants(lotteryID)
+	}
+	return report, nil
+}
+
+func validateImportRow(row ImportRow, prizeNames map[int64]string) string {
+	if row.UserID <= 0 {
+		return "invalid user_id"
+	}
+	if row.Weight != nil && *row.Weight < 0 {
+		return "weight must be non-negative"
+	}
+	for _, pw := range row.PrizeWeights {
+		if _, ok := prizeNames[pw.PrizeID]; !ok {
+			return fmt.Sprintf("unknown prize %d", pw.PrizeID)
+		}
+		if pw.Weight < 0 {
+			return "weight must be non-negative"
+		}
+	}
+	return ""
+}
+
+// importParticipant adds one imported participant and its prize weights.
+// It reports false when the user has already joined.
+func importParticipant(tx database.LotteryRepository, lotteryID string, actorID int64, row ImportRow, prizeNames map[int64]string) (bool, error) {
+	participant := &models.Participant{LotteryID: lotteryID, UserID: row.UserID, Weight: 1}
+	if row.Weight != nil {
+		participant.Weight = *row.Weight
+	}
+
+	if err := tx.AddParticipant(participant); err != nil {
+		if errors.Is(err, database.ErrParticipantExists) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	newValue := map[string]any{"username": "", "weight": participant.Weight, "source": "import"}
+	if len(row.PrizeWeights) > 0 {
+		prizeWeights := make(map[string]int, len(row.PrizeWeights))
+		for _, pw := range row.PrizeWeights {
+			if err := tx.SetPrizeWeight(lotteryID, row.UserID, pw.PrizeID, pw.Weight); err != nil {
+				return false, err
+			}
+			prizeWeights[prizeNames[pw.PrizeID]] = pw.Weight
+		}
+		newValue["prize_weights"] = prizeWeights
+	}
+
+	if err := appendAudit(tx, lotteryID, actorID, models.AuditParticipantAdd, participantTarget(row.UserID), nil, newValue); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// FillParticipantProfile sets the username and names of a user on the entries
+// that were imported without them. It only writes when such entries exist,
+// and users checked recently are skipped until they are imported again.
+func (s *LotteryService) FillParticipantProfile(input JoinInput) {
+	if s.profiled.add(input.UserID) {
+		return
+	}
+	if err := s.repo.FillParticipantProfile(input.UserID, input.Username, input.FirstName, input.LastName); err != nil {
+		s.profiled.remove(input.UserID)
+		logger.Error("failed to fill imported entries", "user_id", input.UserID, "error", err)
+	}
+}
+
+// userLRU remembers the most recently added user IDs, up to a fixed number.
+type userLRU struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[int64]*list.Element
+}
+
+func newUserLRU(size int) *userLRU {
+	return &userLRU{size: size, order: list.New(), items: make(map[int64]*list.Element, size)}
+}
+
+// add marks userID as the most recent and reports whether it was already
+// remembered.
+func (l *userLRU) add(userID int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.items[userID]; ok {
+		l.order.MoveToFront(e)
+		return true
+	}
+	l.items[userID] = l.order.PushFront(userID)
+	if l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(int64))
+	}
+	return false
+}
+
+func (l *userLRU) remove(userID int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.items[userID]; ok {
+		l.order.Remove(e)
+		delete(l.items, userID)
+	}
+}
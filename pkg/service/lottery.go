@@ -2,15 +2,17 @@ package service
 
 import (
 	"context"
-	"database/sql"
 	"errors"
-	"fmt"
 	"math/rand"
+	"slices"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/realSunyz/lucky-tgbot/pkg/config"
 	"github.com/realSunyz/lucky-tgbot/pkg/database"
+	"github.com/realSunyz/lucky-tgbot/pkg/events"
 	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	"github.com/realSunyz/lucky-tgbot/pkg/metrics"
 	"github.com/realSunyz/lucky-tgbot/pkg/models"
 )
 
@@ -24,19 +26,36 @@ var (
 	ErrTokenInvalid        = errors.New("invalid or expired token")
 	ErrPermissionDenied    = errors.New("permission denied")
 	ErrParticipantExists   = errors.New("participant already exists")
+	ErrParticipantNotFound = errors.New("participant not found")
+	ErrPrizeNotFound       = errors.New("prize not found")
 	ErrLotteryCannotDelete = errors.New("cannot delete lottery in current state")
 	ErrCreateTooFrequent   = errors.New("lottery creation too frequent")
 	ErrCreateDailyLimit    = errors.New("lottery creation daily limit reached")
+	ErrInvalidRole         = errors.New("invalid organizer role")
+	ErrOrganizerIsCreator  = errors.New("creator is always the owner")
+	ErrSessionNotFound     = errors.New("edit session not found")
+	ErrUserBanned          = errors.New("user is banned")
+	ErrUserNotBanned       = errors.New("user is not banned")
+	ErrCannotBanAdmin      = errors.New("admins cannot be banned")
+	ErrInvalidQuery        = errors.New("invalid participant query")
+	ErrBackupsDisabled     = errors.New("backups are not configured")
 )
 
 const (
-	createLotteryCooldown = time.Minute
-	maxDailyCreateCount   = 10
+	maxSearchResults     = 20
+	maxSessionNameLength = 32
+	maxUserEntries       = 50
+
+	defaultParticipantPageSize = 50
+	maxParticipantPageSize     = 200
+	// participantBatchSize is how many rows are read per query when walking
+	// every participant or winner of a lottery.
+	participantBatchSize = 500
 )
 
 type Notifier interface {
 	LotteryCreated(lottery *models.Lottery, prizes []models.Prize)
-	WinnersDrawn(lottery *models.Lottery, winners []models.Winner)
+	WinnersDrawn(lottery *models.Lottery, prizes []models.Prize, winners []models.Winner)
 }
 
 type LotterySnapshot struct {
@@ -45,6 +64,16 @@ type LotterySnapshot struct {
 	Winners []models.Winner
 }
 
+// UserEntries is what a participant sees about their own entries.
+// UserEntries holds up to maxUserEntries of each list. MoreActive and
+// MoreWins report that a list was cut short.
+type UserEntries struct {
+	Active     []models.UserEntry `json:"active"`
+	Wins       []models.UserWin   `json:"wins"`
+	MoreActive bool               `json:"more_active"`
+	MoreWins   bool               `json:"more_wins"`
+}
+
 type CreateLotteryInput struct {
 	Title             string
 	Description       string
@@ -75,17 +104,43 @@ type JoinInput struct {
 }
 
 type LotteryService struct {
-	db       *sql.DB
-	notifier Notifier
+	repo        database.LotteryRepository
+	notifier    Notifier
+	cfg         config.LotteryConfig
+	tokenSecret []byte
+	admins      map[int64]struct{}
+	events      *events.Broker
+	backups     Backupper
+	// profiled holds the users whose imported entries were already filled.
+	profiled *userLRU
 }
 
-func NewLotteryService(db *sql.DB, notifier Notifier) *LotteryService {
-	return &LotteryService{db: db, notifier: notifier}
+// NewLotteryService signs creation tokens with the bot token and treats the
+// configured admin user IDs as bot operators. Lottery events are published to
+// subscribers in this process.
+func NewLotteryService(repo database.LotteryRepository, notifier Notifier, cfg *config.Config) *LotteryService {
+	admins := make(map[int64]struct{}, len(cfg.Telegram.AdminUserIDs))
+	for _, id := range cfg.Telegram.AdminUserIDs {
+		admins[id] = struct{}{}
+	}
+	return &LotteryService{
+		repo:        repo,
+		notifier:    notifier,
+		cfg:         cfg.Lottery,
+		tokenSecret: []byte(cfg.Telegram.Token),
+		admins:      admins,
+		events:      events.NewBroker(cfg.Server.EventStreams.PerClient, cfg.Server.EventStreams.PerLottery, cfg.Server.EventStreams.Total),
+		profiled:    newUserLRU(profileCacheSize),
+	}
 }
 
 func (s *LotteryService) CreateDraftLottery(creatorID int64) (*models.Lottery, error) {
+	if err := s.checkNotBanned(creatorID); err != nil {
+		return nil, err
+	}
+
 	now := time.Now().UTC()
-	recentCount, err := database.CountUserLotteriesCreatedSince(creatorID, now.Add(-createLotteryCooldown))
+	recentCount, err := s.repo.CountUserLotteriesCreatedSince(creatorID, now.Add(-s.cfg.CreateCooldown))
 	if err != nil {
 		return nil, err
 	}
@@ -94,15 +149,15 @@ func (s *LotteryService) CreateDraftLottery(creatorID int64) (*models.Lottery, e
 	}
 
 	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
-	dailyCount, err := database.CountUserLotteriesCreatedSince(creatorID, dayStart)
+	dailyCount, err := s.repo.CountUserLotteriesCreatedSince(creatorID, dayStart)
 	if err != nil {
 		return nil, err
 	}
-	if dailyCount >= maxDailyCreateCount {
+	if dailyCount >= s.cfg.MaxDailyCreates {
 		return nil, ErrCreateDailyLimit
 	}
 
-	id, err := database.GenerateLotteryID()
+	id, err := s.repo.GenerateLotteryID()
 	if err != nil {
 		return nil, err
 	}
@@ -114,14 +169,14 @@ func (s *LotteryService) CreateDraftLottery(creatorID int64) (*models.Lottery, e
 		Status:       "draft",
 		DrawMode:     "manual",
 	}
-	if err := database.CreateLottery(lottery); err != nil {
+	if err := s.repo.CreateLottery(lottery); err != nil {
 		return nil, err
 	}
 	return lottery, nil
 }
 
 func (s *LotteryService) GetLotterySnapshot(id string) (*LotterySnapshot, error) {
-	lottery, err := database.GetLottery(id)
+	lottery, err := s.repo.GetLottery(id)
 	if err != nil {
 		return nil, err
 	}
@@ -129,7 +184,7 @@ func (s *LotteryService) GetLotterySnapshot(id string) (*LotterySnapshot, error)
 		return nil, ErrLotteryNotFound
 	}
 
-	prizes, err := database.GetPrizes(id)
+	prizes, err := s.repo.GetPrizes(id)
 	if err != nil {
 		return nil, err
 	}
@@ -140,7 +195,7 @@ func (s *LotteryService) GetLotterySnapshot(id string) (*LotterySnapshot, error)
 	}
 
 	if lottery.Status == "completed" {
-		winners, err := database.GetWinners(id)
+		winners, err := s.repo.GetWinners(id)
 		if err != nil {
 			return nil, err
 		}
@@ -150,30 +205,138 @@ func (s *LotteryService) GetLotterySnapshot(id string) (*LotterySnapshot, error)
 	return snapshot, nil
 }
 
+// SearchLotteries resolves an inline query. An exact lottery ID matches any
+// active lottery; otherwise only the requester's own lotteries are searched
+// by title.
+func (s *LotteryService) SearchLotteries(requesterID int64, query string) ([]LotterySnapshot, error) {
+	query = strings.TrimSpace(query)
+
+	var lotteries []models.Lottery
+	if query != "" {
+		lottery, err := s.repo.GetLottery(query)
+		if err != nil {
+			return nil, err
+		}
+		if lottery != nil && lottery.Status == "active" {
+			lotteries = append(lotteries, *lottery)
+		}
+	}
+
+	if len(lotteries) == 0 {
+		owned, err := s.repo.SearchCreatorLotteries(requesterID, query, maxSearchResults)
+		if err != nil {
+			return nil, err
+		}
+		lotteries = owned
+	}
+
+	snapshots := make([]LotterySnapshot, 0, len(lotteries))
+	for i := range lotteries {
+		prizes, err := s.repo.GetPrizes(lotteries[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, LotterySnapshot{Lottery: &lotteries[i], Prizes: prizes})
+	}
+	return snapshots, nil
+}
+
+// ListCreatorLotteries returns the given zero-based page of lotteries created
+// by the user and the total number of pages.
+func (s *LotteryService) ListCreatorLotteries(creatorID int64, page, pageSize int) ([]models.Lottery, int, error) {
+	if page < 0 {
+		page = 0
+	}
+	lotteries, total, err := s.repo.ListCreatorLotteries(creatorID, pageSize, page*pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	pages := (total + pageSize - 1) / pageSize
+	return lotteries, pages, nil
+}
+
+// GetUserEntries lists the active lotteries the user has joined and the
+// prizes they have won.
+func (s *LotteryService) GetUserEntries(userID int64) (*UserEntries, error) {
+	// One more than is returned is read to tell whether there are more
+	active, err := s.repo.GetUserActiveEntries(userID, maxUserEntries+1)
+	if err != nil {
+		return nil, err
+	}
+	wins, err := s.repo.GetUserWins(userID, maxUserEntries+1)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := &UserEntries{Active: active, Wins: wins}
+	if len(entries.Active) > maxUserEntries {
+		entries.Active = entries.Active[:maxUserEntries]
+		entries.MoreActive = true
+	}
+	if len(entries.Wins) > maxUserEntries {
+		entries.Wins = entries.Wins[:maxUserEntries]
+		entries.MoreWins = true
+	}
+	if entries.Active == nil {
+		entries.Active = []models.UserEntry{}
+	}
+	if entries.Wins == nil {
+		entries.Wins = []models.UserWin{}
+	}
+	return entries, nil
+}
+
+// Ping reports whether the storage backend is reachable.
+func (s *LotteryService) Ping(ctx context.Context) error {
+	return s.repo.Ping(ctx)
+}
+
 func (s *LotteryService) GetLotteryStats() (*models.LotteryStats, error) {
-	return database.GetLotteryStats()
+	return s.repo.GetLotteryStats()
 }
 
 func (s *LotteryService) DeleteLottery(lotteryID string, userID int64) error {
-	lottery, err := database.GetLottery(lotteryID)
+	lottery, err := s.repo.GetLottery(lotteryID)
 	if err != nil {
 		return err
 	}
 	if lottery == nil {
 		return ErrLotteryNotFound
 	}
-	if lottery.CreatorID != userID {
+	role, err := s.organizerRole(lottery, userID)
+	if err != nil {
+		return err
+	}
+	if role != models.RoleOwner {
 		return ErrPermissionDenied
 	}
 	if lottery.Status != "draft" && lottery.Status != "active" {
 		return ErrLotteryCannotDelete
 	}
 
-	return database.DeleteLottery(lotteryID)
+	return s.deleteLottery(lottery, userID)
+}
+
+func (s *LotteryService) deleteLottery(lottery *models.Lottery, actorID int64) error {
+	err := s.repo.InTx(func(tx database.LotteryRepository) error {
+		prizes, err := tx.GetPrizes(lottery.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.DeleteLottery(lottery.ID); err != nil {
+			return err
+		}
+		return appendAudit(tx, lottery.ID, actorID, models.AuditLotteryDelete, "", newLotteryAuditValue(lottery, prizes), nil)
+	})
+	if err != nil {
+		return err
+	}
+	s.publish(lottery.ID, events.TypeDeleted, nil)
+	return nil
 }
 
 func (s *LotteryService) GetResults(id string) (*models.Lottery, []models.Prize, []models.Winner, error) {
-	lottery, err := database.GetLottery(id)
+	lottery, err := s.repo.GetLottery(id)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -184,11 +347,11 @@ func (s *LotteryService) GetResults(id string) (*models.Lottery, []models.Prize,
 		return nil, nil, nil, ErrLotteryNotDrawn
 	}
 
-	winners, err := database.GetWinners(id)
+	winners, err := s.repo.GetWinners(id)
 	if err != nil {
 		return nil, nil, nil, err
 	}
-	prizes, err := database.GetPrizes(id)
+	prizes, err := s.repo.GetPrizes(id)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -196,13 +359,20 @@ func (s *LotteryService) GetResults(id string) (*models.Lottery, []models.Prize,
 }
 
 func (s *LotteryService) CreateLottery(id string, input CreateLotteryInput) (*models.Lottery, []models.Prize, error) {
-	existing, err := database.GetLottery(id)
+	if err := s.checkNotBanned(input.CreatorID); err != nil {
+		return nil, nil, err
+	}
+
+	existing, err := s.repo.GetLottery(id)
 	if err != nil {
 		return nil, nil, err
 	}
 	if existing != nil && existing.Status != "draft" {
 		return nil, nil, ErrLotteryConflict
 	}
+	if existing != nil && existing.CreatorID != input.CreatorID {
+		return nil, nil, ErrPermissionDenied
+	}
 
 	lottery := &models.Lottery{
 		ID:                id,
@@ -217,48 +387,35 @@ func (s *LotteryService) CreateLottery(id string, input CreateLotteryInput) (*mo
 		IsWeightsDisabled: input.IsWeightsDisabled,
 	}
 
-	tx, err := s.db.BeginTx(context.Background(), nil)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	committed := false
-	defer func() {
-		if !committed {
-			_ = tx.Rollback()
+	var prizes []models.Prize
+	err = s.repo.InTx(func(tx database.LotteryRepository) error {
+		if existing != nil {
+			lottery.CreatedAt = existing.CreatedAt
+			if err := tx.UpdateLottery(lottery); err != nil {
+				return err
+			}
+		} else {
+			lottery.CreatedAt = time.Now().UTC()
+			if err := tx.CreateLottery(lottery); err != nil {
+				return err
+			}
 		}
-	}()
 
-	if existing != nil {
-		lottery.CreatorID = existing.CreatorID
-		lottery.CreatedAt = existing.CreatedAt
-		if err := updateLotteryTx(tx, lottery); err != nil {
-			return nil, nil, err
+		if err := tx.ReplacePrizes(id, input.Prizes); err != nil {
+			return err
 		}
-	} else {
-		now := time.Now().UTC()
-		lottery.CreatedAt = now
-		if err := createLotteryTx(tx, lottery); err != nil {
-			return nil, nil, err
+		var err error
+		if prizes, err = tx.GetPrizes(id); err != nil {
+			return err
 		}
-	}
-
-	if err := deletePrizesTx(tx, id); err != nil {
-		return nil, nil, err
-	}
-	if err := createPrizesTx(tx, id, input.Prizes); err != nil {
-		return nil, nil, err
-	}
 
-	prizes, err := getPrizesTx(tx, id)
+		return appendAudit(tx, id, input.CreatorID, models.AuditLotteryCreate, "", nil, newLotteryAuditValue(lottery, prizes))
+	})
 	if err != nil {
 		return nil, nil, err
 	}
-
-	if err := tx.Commit(); err != nil {
-		return nil, nil, err
-	}
-	committed = true
+	metrics.LotteriesCreated.Inc()
+	s.publish(id, events.TypeLottery, lotteryEvent{Lottery: lottery, Prizes: prizes})
 
 	if s.notifier != nil {
 		go s.notifier.LotteryCreated(lottery, prizes)
@@ -267,8 +424,8 @@ func (s *LotteryService) CreateLottery(id string, input CreateLotteryInput) (*mo
 	return lottery, prizes, nil
 }
 
-func (s *LotteryService) UpdateLottery(id string, input UpdateLotteryInput) (*models.Lottery, []models.Prize, error) {
-	lottery, err := database.GetLottery(id)
+func (s *LotteryService) UpdateLottery(id string, actorID int64, input UpdateLotteryInput) (*models.Lottery, []models.Prize, error) {
+	lottery, err := s.repo.GetLottery(id)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -278,6 +435,7 @@ func (s *LotteryService) UpdateLottery(id string, input UpdateLotteryInput) (*mo
 	if lottery.Status == "completed" {
 		return nil, nil, ErrLotteryEnded
 	}
+	previous := *lottery
 
 	if input.Title != "" {
 		lottery.Title = input.Title
@@ -296,58 +454,60 @@ func (s *LotteryService) UpdateLottery(id string, input UpdateLotteryInput) (*mo
 	}
 	lottery.IsWeightsDisabled = input.IsWeightsDisabled
 
-	tx, err := s.db.BeginTx(context.Background(), nil)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	committed := false
-	defer func() {
-		if !committed {
-			_ = tx.Rollback()
+	var prizes []models.Prize
+	err = s.repo.InTx(func(tx database.LotteryRepository) error {
+		if err := tx.UpdateLottery(lottery); err != nil {
+			return err
+		}
+		if err := tx.MarkLotteryMessagesOutdated(id); err != nil {
+			return err
 		}
-	}()
-
-	if err := updateLotteryTx(tx, lottery); err != nil {
-		return nil, nil, err
-	}
 
-	if input.ReplacePrizes {
-		if err := deletePrizesTx(tx, id); err != nil {
-			return nil, nil, err
+		var oldPrizes []models.Prize
+		var err error
+		if input.ReplacePrizes {
+			if oldPrizes, err = tx.GetPrizes(id); err != nil {
+				return err
+			}
+			if err := tx.ReplacePrizes(id, input.Prizes); err != nil {
+				return err
+			}
 		}
-		if err := createPrizesTx(tx, id, input.Prizes); err != nil {
-			return nil, nil, err
+
+		if prizes, err = tx.GetPrizes(id); err != nil {
+			return err
 		}
-	}
 
-	prizes, err := getPrizesTx(tx, id)
+		// Prizes are only recorded when they were replaced
+		oldValue := newLotteryAuditValue(&previous, oldPrizes)
+		newValue := newLotteryAuditValue(lottery, nil)
+		if input.ReplacePrizes {
+			newValue = newLotteryAuditValue(lottery, prizes)
+		}
+		return appendAudit(tx, id, actorID, models.AuditLotteryUpdate, "", oldValue, newValue)
+	})
 	if err != nil {
 		return nil, nil, err
 	}
-
-	if err := tx.Commit(); err != nil {
-		return nil, nil, err
-	}
-	committed = true
+	s.publish(id, events.TypeLottery, lotteryEvent{Lottery: lottery, Prizes: prizes})
 
 	return lottery, prizes, nil
 }
 
 func (s *LotteryService) JoinLottery(lotteryID string, input JoinInput) (*models.Lottery, *models.Participant, error) {
-	lottery, err := database.GetLottery(lotteryID)
+	if err := s.checkNotBanned(input.UserID); err != nil {
+		return nil, nil, err
+	}
+
+	lottery, err := s.repo.GetLottery(lotteryID)
 	if err != nil {
 		return nil, nil, err
 	}
 	if lottery == nil {
 		return nil, nil, ErrLotteryNotFound
 	}
-	if lottery.Status != "active" {
-		return lottery, nil, ErrLotteryNotActive
-	}
-
-	if lottery.MaxEntries != nil && lottery.Participants >= *lottery.MaxEntries {
-		return lottery, nil, ErrLotteryFull
+	if err := joinable(lottery); err != nil {
+		return lottery, nil, err
 	}
 
 	participant := &models.Participant{
@@ -359,33 +519,46 @@ func (s *LotteryService) JoinLottery(lotteryID string, input JoinInput) (*models
 		Weight:    1,
 	}
 
-	if err := database.AddParticipant(participant); err != nil {
-		if errors.Is(err, database.ErrParticipantExists) {
+	locked, err := s.addParticipant(participant, input.UserID, models.AuditParticipantJoin, joinable)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrParticipantExists):
+			// The entry may have been imported without a name.
+			s.FillParticipantProfile(input)
 			return lottery, nil, ErrParticipantExists
+		case errors.Is(err, ErrLotteryNotActive), errors.Is(err, ErrLotteryFull):
+			return lottery, nil, err
 		}
 		return nil, nil, err
 	}
+	lottery = locked
 	lottery.Participants++
+	metrics.Joins.WithLabelValues(metrics.JoinSourceSelf).Inc()
+	s.publishParticipants(lotteryID)
 
 	if lottery.DrawMode == "full" && lottery.MaxEntries != nil {
 		if lottery.Participants >= *lottery.MaxEntries {
-			go s.drawWithRetry(lotteryID, "full")
+			go s.drawWithRetry(lotteryID, metrics.DrawSourceFull)
 		}
 	}
 
 	return lottery, participant, nil
 }
 
-func (s *LotteryService) AddParticipant(lotteryID string, input JoinInput) (*models.Participant, error) {
-	lottery, err := database.GetLottery(lotteryID)
+func (s *LotteryService) AddParticipant(lotteryID string, actorID int64, input JoinInput) (*models.Participant, error) {
+	if err := s.checkNotBanned(input.UserID); err != nil {
+		return nil, err
+	}
+
+	lottery, err := s.repo.GetLottery(lotteryID)
 	if err != nil {
 		return nil, err
 	}
 	if lottery == nil {
 		return nil, ErrLotteryNotFound
 	}
-	if lottery.Status == "completed" {
-		return nil, ErrLotteryEnded
+	if err := notEnded(lottery); err != nil {
+		return nil, err
 	}
 
 	participant := &models.Participant{
@@ -397,177 +570,640 @@ func (s *LotteryService) AddParticipant(lotteryID string, input JoinInput) (*mod
 		Weight:    1,
 	}
 
-	if err := database.AddParticipant(participant); err != nil {
-		if errors.Is(err, database.ErrParticipantExists) {
-			return nil, ErrParticipantExists
-		}
+	if _, err := s.addParticipant(participant, actorID, models.AuditParticipantAdd, notEnded); err != nil {
 		return nil, err
 	}
+	metrics.Joins.WithLabelValues(metrics.JoinSourceOrganizer).Inc()
+	s.publishParticipants(lotteryID)
 
 	return participant, nil
 }
 
-func (s *LotteryService) GetParticipants(lotteryID string) ([]models.Participant, error) {
-	return database.GetParticipants(lotteryID)
+// ListParticipants returns one page of participants with the lottery's total
+// and matching counts.
+func (s *LotteryService) ListParticipants(q models.ParticipantQuery) (*models.ParticipantPage, error) {
+	switch q.Sort {
+	case "":
+		q.Sort = models.ParticipantSortJoined
+	case models.ParticipantSortJoined, models.ParticipantSortWeight:
+	default:
+		return nil, ErrInvalidQuery
+	}
+	switch q.Filter {
+	case "", models.ParticipantFilterCustomWeight, models.ParticipantFilterCustomPrizeWeight:
+	default:
+		return nil, ErrInvalidQuery
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultParticipantPageSize
+	}
+	q.Limit = min(q.Limit, maxParticipantPageSize)
+
+	participants, next, err := s.repo.ListParticipants(q)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidCursor) {
+			return nil, ErrInvalidQuery
+		}
+		return nil, err
+	}
+	total, matched, err := s.repo.CountParticipants(q)
+	if err != nil {
+		return nil, err
+	}
+
+	if participants == nil {
+		participants = []models.Participant{}
+	}
+	return &models.ParticipantPage{
+		Participants: participants,
+		NextCursor:   next,
+		Total:        total,
+		Matched:      matched,
+	}, nil
+}
+
+// eachParticipantPage calls fn with every participant of a lottery, in join
+// order and pageSize at a time, so large lotteries are never loaded at once.
+func (s *LotteryService) eachParticipantPage(lotteryID string, pageSize int, fn func([]models.Participant) error) error {
+	q := models.ParticipantQuery{LotteryID: lotteryID, Limit: pageSize}
+	for {
+		participants, next, err := s.repo.ListParticipants(q)
+		if err != nil {
+			return err
+		}
+		if err := fn(participants); err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		q.Cursor = next
+	}
+}
+
+// addParticipant adds the participant once check accepts the locked lottery,
+// so that a concurrent draw or join cannot change it in between. It returns
+// the lottery as it was before the participant was added.
+func (s *LotteryService) addParticipant(participant *models.Participant, actorID int64, action string, check func(*models.Lottery) error) (*models.Lottery, error) {
+	var lottery *models.Lottery
+	err := s.repo.InTx(func(tx database.LotteryRepository) error {
+		var err error
+		if lottery, err = lockLottery(tx, participant.LotteryID, check); err != nil {
+			return err
+		}
+		if err := tx.AddParticipant(participant); err != nil {
+			if errors.Is(err, database.ErrParticipantExists) {
+				return ErrParticipantExists
+			}
+			return err
+		}
+		newValue := map[string]any{"username": participant.Username, "weight": participant.Weight}
+		return appendAudit(tx, participant.LotteryID, actorID, action, participantTarget(participant.UserID), nil, newValue)
+	})
+	return lottery, err
+}
+
+// lockLottery locks the lottery for the rest of the transaction and returns
+// it when check accepts it.
+func lockLottery(tx database.LotteryRepository, lotteryID string, check func(*models.Lottery) error) (*models.Lottery, error) {
+	lottery, err := tx.LockLottery(lotteryID)
+	if err != nil {
+		return nil, err
+	}
+	if lottery == nil {
+		return nil, ErrLotteryNotFound
+	}
+	if err := check(lottery); err != nil {
+		return nil, err
+	}
+	return lottery, nil
+}
+
+// joinable accepts lotteries that users can join themselves.
+func joinable(lottery *models.Lottery) error {
+	if lottery.Status != "active" {
+		return ErrLotteryNotActive
+	}
+	if lottery.MaxEntries != nil && lottery.Participants >= *lottery.MaxEntries {
+		return ErrLotteryFull
+	}
+	return nil
 }
 
-func (s *LotteryService) UpdateParticipantWeight(lotteryID string, userID int64, weight int) error {
-	return database.UpdateParticipantWeight(lotteryID, userID, weight)
+// notEnded accepts lotteries that organizers can still add participants to.
+func notEnded(lottery *models.Lottery) error {
+	if lottery.Status == "completed" {
+		return ErrLotteryEnded
+	}
+	return nil
 }
 
-func (s *LotteryService) SetPrizeWeight(lotteryID string, userID int64, prizeID int64, weight int) error {
-	return database.SetPrizeWeight(lotteryID, userID, prizeID, weight)
+// drawable accepts lotteries that can be drawn.
+func drawable(lottery *models.Lottery) error {
+	if lottery.Status == "completed" {
+		return ErrLotteryEnded
+	}
+	if lottery.Status != "active" {
+		return ErrLotteryNotActive
+	}
+	return nil
 }
 
-func (s *LotteryService) DeletePrizeWeight(lotteryID string, userID int64, prizeID int64) error {
-	return database.DeletePrizeWeight(lotteryID, userID, prizeID)
+func (s *LotteryService) UpdateParticipantWeight(lotteryID string, actorID int64, userID int64, weight int) error {
+	err := s.repo.InTx(func(tx database.LotteryRepository) error {
+		participant, err := tx.GetParticipant(lotteryID, userID)
+		if err != nil {
+			return err
+		}
+		if participant == nil {
+			return ErrParticipantNotFound
+		}
+
+		if err := tx.SetParticipantWeight(lotteryID, userID, weight); err != nil {
+			return err
+		}
+		return appendAudit(tx, lotteryID, actorID, models.AuditParticipantWeight, participantTarget(userID),
+			map[string]int{"weight": participant.Weight}, map[string]int{"weight": weight})
+	})
+	if err != nil {
+		return err
+	}
+	s.publishWeights(lotteryID)
+	return nil
 }
 
-func (s *LotteryService) RemoveParticipant(lotteryID string, userID int64) error {
-	return database.RemoveParticipant(lotteryID, userID)
+// SetPrizeWeight overrides the weight of a participant for one prize of the
+// same lottery until it is drawn.
+func (s *LotteryService) SetPrizeWeight(lotteryID string, actorID int64, userID int64, prizeID int64, weight int) error {
+	err := s.repo.InTx(func(tx database.LotteryRepository) error {
+		if _, err := lockLottery(tx, lotteryID, notEnded); err != nil {
+			return err
+		}
+		participant, err := tx.GetParticipant(lotteryID, userID)
+		if err != nil {
+			return err
+		}
+		if participant == nil {
+			return ErrParticipantNotFound
+		}
+		prizes, err := tx.GetPrizes(lotteryID)
+		if err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(prizes, func(p models.Prize) bool { return p.ID == prizeID }) {
+			return ErrPrizeNotFound
+		}
+
+		var oldValue any
+		old, err := tx.GetPrizeWeight(lotteryID, userID, prizeID)
+		if err != nil {
+			return err
+		}
+		if old != nil {
+			oldValue = map[string]int{"weight": *old}
+		}
+
+		if err := tx.SetPrizeWeight(lotteryID, userID, prizeID, weight); err != nil {
+			return err
+		}
+		return appendAudit(tx, lotteryID, actorID, models.AuditPrizeWeightSet, prizeWeightTarget(userID, prizeID),
+			oldValue, map[string]int{"weight": weight})
+	})
+	if err != nil {
+		return err
+	}
+	s.publishWeights(lotteryID)
+	return nil
 }
 
-func (s *LotteryService) ValidateEditToken(lotteryID, token string) error {
-	valid, err := database.ValidateEditToken(lotteryID, token)
+func (s *LotteryService) DeletePrizeWeight(lotteryID string, actorID int64, userID int64, prizeID int64) error {
+	deleted := false
+	err := s.repo.InTx(func(tx database.LotteryRepository) error {
+		old, err := tx.GetPrizeWeight(lotteryID, userID, prizeID)
+		if err != nil || old == nil {
+			return err
+		}
+
+		if err := tx.DeletePrizeWeight(lotteryID, userID, prizeID); err != nil {
+			return err
+		}
+		deleted = true
+		return appendAudit(tx, lotteryID, actorID, models.AuditPrizeWeightDelete, prizeWeightTarget(userID, prizeID),
+			map[string]int{"weight": *old}, nil)
+	})
 	if err != nil {
 		return err
 	}
-	if !valid {
-		return ErrTokenInvalid
+	if deleted {
+		s.publishWeights(lotteryID)
+	}
+	return nil
+}
+
+func (s *LotteryService) RemoveParticipant(lotteryID string, actorID int64, userID int64) error {
+	err := s.repo.InTx(func(tx database.LotteryRepository) error {
+		participant, err := tx.GetParticipant(lotteryID, userID)
+		if err != nil {
+			return err
+		}
+		if participant == nil {
+			return ErrParticipantNotFound
+		}
+
+		if err := tx.RemoveParticipant(lotteryID, userID); err != nil {
+			return err
+		}
+
+		oldValue := map[string]any{
+			"username":      participant.Username,
+			"weight":        participant.Weight,
+			"prize_weights": participant.PrizeWeights,
+		}
+		return appendAudit(tx, lotteryID, actorID, models.AuditParticipantRemove, participantTarget(userID), oldValue, nil)
+	})
+	if err != nil {
+		return err
 	}
+	s.publishParticipants(lotteryID)
 	return nil
 }
 
-func (s *LotteryService) CreateEditToken(lotteryID string, requesterID int64, ttl time.Duration) (string, *models.Lottery, error) {
-	lottery, err := database.GetLottery(lotteryID)
+// ValidateEditToken returns the edit session bound to the token, which names
+// the organizer and their role.
+func (s *LotteryService) ValidateEditToken(lotteryID, token string) (*models.EditSession, error) {
+	if token == "" {
+		return nil, ErrTokenInvalid
+	}
+	session, err := s.repo.ValidateEditSession(lotteryID, hashSecret(token))
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrTokenInvalid
+	}
+	return session, nil
+}
+
+// CreateEditSession opens a new named edit session for an organizer and
+// returns the one-time link code that must be exchanged for the session
+// token. Existing sessions are left untouched.
+func (s *LotteryService) CreateEditSession(lotteryID string, requesterID int64, name string) (string, *models.Lottery, error) {
+	lottery, err := s.repo.GetLottery(lotteryID)
 	if err != nil {
 		return "", nil, err
 	}
 	if lottery == nil {
 		return "", nil, ErrLotteryNotFound
 	}
-	if lottery.CreatorID != requesterID {
+	role, err := s.organizerRole(lottery, requesterID)
+	if err != nil {
+		return "", nil, err
+	}
+	if role == "" {
 		return "", nil, ErrPermissionDenied
 	}
 
-	token := uuid.NewString()
-	editToken := &models.EditToken{
-		Token:     token,
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "会话 " + time.Now().Format("01-02 15:04")
+	}
+	if len([]rune(name)) > maxSessionNameLength {
+		name = string([]rune(name)[:maxSessionNameLength])
+	}
+
+	code, err := newSecret()
+	if err != nil {
+		return "", nil, err
+	}
+	session := &models.EditSession{
 		LotteryID: lotteryID,
-		ExpiresAt: time.Now().Add(ttl),
+		UserID:    requesterID,
+		Role:      role,
+		Name:      name,
+		ExpiresAt: time.Now().Add(s.cfg.EditSessionTTL),
 	}
-	if err := database.CreateEditToken(editToken); err != nil {
+	if err := s.repo.CreateEditSession(session, hashSecret(code)); err != nil {
 		return "", nil, err
 	}
 
-	return token, lottery, nil
+	return code, lottery, nil
 }
 
-func (s *LotteryService) DrawLottery(lotteryID string) ([]models.Winner, error) {
-	tx, err := s.db.BeginTx(context.Background(), nil)
+// ExchangeEditLinkCode turns a one-time link code into a session token. The
+// code stops working after the first exchange.
+func (s *LotteryService) ExchangeEditLinkCode(lotteryID, code string) (string, *models.EditSession, error) {
+	if code == "" {
+		return "", nil, ErrTokenInvalid
+	}
+
+	token, err := newSecret()
 	if err != nil {
-		return nil, err
+		return "", nil, err
+	}
+	session, err := s.repo.ExchangeEditLinkCode(lotteryID, hashSecret(code), hashSecret(token))
+	if err != nil {
+		return "", nil, err
+	}
+	if session == nil {
+		return "", nil, ErrTokenInvalid
 	}
 
-	committed := false
-	defer func() {
-		if !committed {
-			_ = tx.Rollback()
-		}
-	}()
+	return token, session, nil
+}
 
-	lottery, err := getLotteryTx(tx, lotteryID)
+// ListEditSessions returns the live edit sessions of a lottery. Owners see
+// every session, other organizers only their own.
+func (s *LotteryService) ListEditSessions(lotteryID string, requesterID int64) ([]models.EditSession, *models.Lottery, error) {
+	lottery, err := s.repo.GetLottery(lotteryID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if lottery == nil {
-		return nil, ErrLotteryNotFound
+		return nil, nil, ErrLotteryNotFound
 	}
-	if lottery.Status == "completed" {
-		return nil, ErrLotteryEnded
+	role, err := s.organizerRole(lottery, requesterID)
+	if err != nil {
+		return nil, nil, err
 	}
-	if lottery.Status != "active" {
-		return nil, ErrLotteryNotActive
+	if role == "" {
+		return nil, nil, ErrPermissionDenied
 	}
 
-	prizes, err := getPrizesTx(tx, lotteryID)
+	sessions, err := s.repo.GetEditSessions(lotteryID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if role == models.RoleOwner {
+		return sessions, lottery, nil
+	}
+
+	own := sessions[:0]
+	for _, session := range sessions {
+		if session.UserID == requesterID {
+			own = append(own, session)
+		}
+	}
+	return own, lottery, nil
+}
+
+// RevokeEditSession ends an edit session. Owners may revoke any session,
+// other organizers only their own.
+func (s *LotteryService) RevokeEditSession(lotteryID string, requesterID int64, sessionID int64) error {
+	sessions, _, err := s.ListEditSessions(lotteryID, requesterID)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, session := range sessions {
+		if session.ID == sessionID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ErrSessionNotFound
+	}
+
+	deleted, err := s.repo.DeleteEditSession(lotteryID, sessionID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// SetOrganizer grants or changes a co-organizer role. Only owners may manage
+// organizers.
+func (s *LotteryService) SetOrganizer(lotteryID string, requesterID int64, userID int64, role string) error {
+	if role != models.RoleOwner && role != models.RoleEditor && role != models.RoleModerator {
+		return ErrInvalidRole
+	}
+
+	lottery, err := s.requireOwner(lotteryID, requesterID)
+	if err != nil {
+		return err
 	}
+	if lottery.CreatorID == userID {
+		return ErrOrganizerIsCreator
+	}
+
+	return s.repo.InTx(func(tx database.LotteryRepository) error {
+		var oldValue any
+		oldRole, err := tx.GetOrganizerRole(lotteryID, userID)
+		if err != nil {
+			return err
+		}
+		if oldRole != "" {
+			oldValue = map[string]string{"role": oldRole}
+		}
+
+		if err := tx.SetOrganizer(lotteryID, userID, role); err != nil {
+			return err
+		}
+		// Sessions carry the role they were opened with
+		if oldRole != "" && oldRole != role {
+			if err := tx.DeleteUserEditSessions(lotteryID, userID); err != nil {
+				return err
+			}
+		}
+		return appendAudit(tx, lotteryID, requesterID, models.AuditOrganizerSet, participantTarget(userID),
+			oldValue, map[string]string{"role": role})
+	})
+}
 
-	participants, err := getParticipantsTx(tx, lotteryID)
+func (s *LotteryService) RemoveOrganizer(lotteryID string, requesterID int64, userID int64) error {
+	lottery, err := s.requireOwner(lotteryID, requesterID)
 	if err != nil {
+		return err
+	}
+	if lottery.CreatorID == userID {
+		return ErrOrganizerIsCreator
+	}
+
+	return s.repo.InTx(func(tx database.LotteryRepository) error {
+		oldRole, err := tx.GetOrganizerRole(lotteryID, userID)
+		if err != nil || oldRole == "" {
+			return err
+		}
+
+		if err := tx.RemoveOrganizer(lotteryID, userID); err != nil {
+			return err
+		}
+		if err := tx.DeleteUserEditSessions(lotteryID, userID); err != nil {
+			return err
+		}
+		return appendAudit(tx, lotteryID, requesterID, models.AuditOrganizerRemove, participantTarget(userID),
+			map[string]string{"role": oldRole}, nil)
+	})
+}
+
+func (s *LotteryService) GetOrganizers(lotteryID string, requesterID int64) ([]models.Organizer, error) {
+	if _, err := s.requireOwner(lotteryID, requesterID); err != nil {
 		return nil, err
 	}
+	return s.repo.GetOrganizers(lotteryID)
+}
 
-	winners := drawWinners(lotteryID, prizes, participants)
-	winnerStmt, err := tx.Prepare(`
-		INSERT INTO winners (lottery_id, participant_id, prize_id, user_id, username, prize_name)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`)
+func (s *LotteryService) requireOwner(lotteryID string, userID int64) (*models.Lottery, error) {
+	lottery, err := s.repo.GetLottery(lotteryID)
+	if err != nil {
+		return nil, err
+	}
+	if lottery == nil {
+		return nil, ErrLotteryNotFound
+	}
+	role, err := s.organizerRole(lottery, userID)
 	if err != nil {
 		return nil, err
 	}
-	defer winnerStmt.Close()
+	if role != models.RoleOwner {
+		return nil, ErrPermissionDenied
+	}
+	return lottery, nil
+}
 
-	for i := range winners {
-		if err := createWinnerStmt(winnerStmt, &winners[i]); err != nil {
-			return nil, err
-		}
+// organizerRole returns the user's role on the lottery, or an empty string
+// when the user is not an organizer. The creator is always an owner.
+func (s *LotteryService) organizerRole(lottery *models.Lottery, userID int64) (string, error) {
+	if lottery.CreatorID == userID {
+		return models.RoleOwner, nil
 	}
+	return s.repo.GetOrganizerRole(lottery.ID, userID)
+}
 
-	lottery.Status = "completed"
-	lottery.Participants = len(participants)
-	if err := updateLotteryTx(tx, lottery); err != nil {
+func (s *LotteryService) GetPublishableLottery(lotteryID string, requesterID int64) (*LotterySnapshot, error) {
+	snapshot, err := s.GetLotterySnapshot(lotteryID)
+	if err != nil {
 		return nil, err
 	}
-	if err := cleanupAfterDrawTx(tx, lotteryID); err != nil {
+	role, err := s.organizerRole(snapshot.Lottery, requesterID)
+	if err != nil {
 		return nil, err
 	}
+	if role != models.RoleOwner {
+		return nil, ErrPermissionDenied
+	}
+	if snapshot.Lottery.Status == "completed" {
+		return nil, ErrLotteryEnded
+	}
+	if snapshot.Lottery.Status != "active" {
+		return nil, ErrLotteryNotActive
+	}
+	return snapshot, nil
+}
+
+func (s *LotteryService) SaveLotteryMessage(lotteryID string, chatID int64, messageID int, participants int) error {
+	return s.repo.CreateLotteryMessage(&models.LotteryMessage{
+		LotteryID:    lotteryID,
+		ChatID:       chatID,
+		MessageID:    messageID,
+		Participants: participants,
+	})
+}
 
-	if err := tx.Commit(); err != nil {
+func (s *LotteryService) GetLotteryMessages(lotteryID string) ([]models.LotteryMessage, error) {
+	return s.repo.GetLotteryMessages(lotteryID)
+}
+
+func (s *LotteryService) GetOutdatedLotteryMessages() ([]models.LotteryMessage, error) {
+	return s.repo.GetOutdatedLotteryMessages()
+}
+
+// MarkLotteryMessageRendered records that the announcement m now shows
+// participants entries and the lottery as of m's revision. An edit made since
+// m was loaded leaves it outdated.
+func (s *LotteryService) MarkLotteryMessageRendered(m models.LotteryMessage, participants int) error {
+	return s.repo.MarkLotteryMessageRendered(m.ID, participants, m.Revision)
+}
+
+// DrawLottery draws the lottery right away on behalf of actorID.
+func (s *LotteryService) DrawLottery(lotteryID string, actorID int64) ([]models.Winner, error) {
+	return s.drawLottery(lotteryID, actorID, metrics.DrawSourceManual)
+}
+
+func (s *LotteryService) drawLottery(lotteryID string, actorID int64, source string) ([]models.Winner, error) {
+	var lottery *models.Lottery
+	var prizes []models.Prize
+	var winners []models.Winner
+	started := false
+	err := s.repo.InTx(func(tx database.LotteryRepository) error {
+		// The lock keeps participants from joining during the draw and
+		// another process from drawing the same lottery.
+		var err error
+		if lottery, err = lockLottery(tx, lotteryID, drawable); err != nil {
+			return err
+		}
+		s.publish(lotteryID, events.TypeDrawStarted, nil)
+		started = true
+
+		if prizes, err = tx.GetPrizes(lotteryID); err != nil {
+			return err
+		}
+		participants, err := tx.GetAllParticipants(lotteryID)
+		if err != nil {
+			return err
+		}
+
+		winners = drawWinners(lotteryID, prizes, participants, lottery.IsWeightsDisabled)
+		if err := tx.CreateWinners(winners); err != nil {
+			return err
+		}
+
+		lottery.Status = "completed"
+		lottery.Participants = len(participants)
+		if err := tx.UpdateLottery(lottery); err != nil {
+			return err
+		}
+		if err := tx.ClearAfterDraw(lotteryID); err != nil {
+			return err
+		}
+
+		drawn := make([]map[string]any, 0, len(winners))
+		for _, w := range winners {
+			drawn = append(drawn, map[string]any{"user_id": w.UserID, "prize_id": w.PrizeID, "prize_name": w.PrizeName})
+		}
+		newValue := drawAuditValue{Source: source, Participants: len(participants), Winners: drawn}
+		if !lottery.IsWeightsDisabled {
+			newValue.Weights = summarizeWeights(prizes, participants)
+		}
+		return appendAudit(tx, lotteryID, actorID, models.AuditLotteryDraw, "", nil, newValue)
+	})
+	if err != nil {
+		if started {
+			s.publish(lotteryID, events.TypeDrawFailed, nil)
+		}
 		return nil, err
 	}
-	committed = true
+	metrics.Draws.WithLabelValues(source).Inc()
+	s.publish(lotteryID, events.TypeWinners, winnersEvent{Lottery: lottery, Winners: winners})
 
-	if s.notifier != nil && len(winners) > 0 {
-		go s.notifier.WinnersDrawn(lottery, winners)
+	if s.notifier != nil {
+		go s.notifier.WinnersDrawn(lottery, prizes, winners)
 	}
 
 	return winners, nil
 }
 
+// DrawLotteryAs draws the lottery on behalf of a user, who must be one of its
+// owners.
+func (s *LotteryService) DrawLotteryAs(lotteryID string, requesterID int64) ([]models.Winner, error) {
+	if _, err := s.requireOwner(lotteryID, requesterID); err != nil {
+		return nil, err
+	}
+	return s.DrawLottery(lotteryID, requesterID)
+}
+
 func (s *LotteryService) CheckAutoDrawLotteries() error {
-	now := time.Now().UTC()
-	rows, err := s.db.Query(`
-		SELECT l.id
-		FROM lotteries l
-		WHERE l.status = 'active' AND (
-			(l.draw_mode = 'timed' AND l.draw_time IS NOT NULL AND l.draw_time <= ?)
-			OR
-			(
-				l.draw_mode = 'full'
-				AND l.max_entries IS NOT NULL
-				AND l.participants >= l.max_entries
-			)
-		)
-	`, now)
+	ids, err := s.repo.DueLotteryIDs(time.Now().UTC())
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
-
-	var ids []string
-	for rows.Next() {
-		var id string
-		if scanErr := rows.Scan(&id); scanErr != nil {
-			continue
-		}
-		ids = append(ids, id)
-	}
 
 	for _, id := range ids {
-		s.drawWithRetry(id, "scheduler")
+		s.drawWithRetry(id, metrics.DrawSourceScheduler)
 	}
 
 	return nil
@@ -578,7 +1214,7 @@ func (s *LotteryService) drawWithRetry(lotteryID string, source string) {
 	backoff := 200 * time.Millisecond
 
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		_, err := s.DrawLottery(lotteryID)
+		_, err := s.drawLottery(lotteryID, SystemActorID, source)
 		if err == nil ||
 			errors.Is(err, ErrLotteryEnded) ||
 			errors.Is(err, ErrLotteryNotActive) ||
@@ -587,7 +1223,8 @@ func (s *LotteryService) drawWithRetry(lotteryID string, source string) {
 		}
 
 		if attempt == maxAttempts {
-			logger.Errorf("%s auto draw failed for %s after %d attempts: %v", source, lotteryID, maxAttempts, err)
+			metrics.DrawFailures.WithLabelValues(source).Inc()
+			logger.Error("auto draw failed", "source", source, "lottery_id", lotteryID, "attempts", maxAttempts, "error", err)
 			return
 		}
 
@@ -596,7 +1233,9 @@ func (s *LotteryService) drawWithRetry(lotteryID string, source string) {
 	}
 }
 
-func drawWinners(lotteryID string, prizes []models.Prize, participants []models.Participant) []models.Winner {
+// drawWinners picks the winners of every prize. When weightsDisabled is set
+// each participant has a single entry and custom weights are ignored.
+func drawWinners(lotteryID string, prizes []models.Prize, participants []models.Participant, weightsDisabled bool) []models.Winner {
 	if len(participants) == 0 {
 		return nil
 	}
@@ -608,9 +1247,9 @@ func drawWinners(lotteryID string, prizes []models.Prize, participants []models.
 	for _, prize := range prizes {
 		var weightedPool []models.Participant
 		for _, p := range participants {
-			weight := p.Weight
-			if w, ok := p.PrizeWeights[prize.ID]; ok {
-				weight = w
+			weight := 1
+			if !weightsDisabled {
+				weight = effectiveWeight(p, prize.ID)
 			}
 			for i := 0; i < weight; i++ {
 				weightedPool = append(weightedPool, p)
@@ -646,12 +1285,16 @@ func drawWinners(lotteryID string, prizes []models.Prize, participants []models.
 				}
 				wonUsers[candidate.UserID] = true
 
+				joinedAt := candidate.JoinedAt.UTC()
 				winners = append(winners, models.Winner{
 					LotteryID:     lotteryID,
 					ParticipantID: candidate.ID,
 					PrizeID:       prize.ID,
 					UserID:        candidate.UserID,
 					Username:      candidate.Username,
+					FirstName:     candidate.FirstName,
+					LastName:      candidate.LastName,
+					JoinedAt:      &joinedAt,
 					PrizeName:     prize.Name,
 				})
 				break
@@ -661,175 +1304,3 @@ func drawWinners(lotteryID string, prizes []models.Prize, participants []models.
 
 	return winners
 }
-
-func createLotteryTx(tx *sql.Tx, lottery *models.Lottery) error {
-	_, err := tx.Exec(`
-		INSERT INTO lotteries (id, title, description, creator_id, participants, draw_mode, draw_time, max_entries, status, created_at, is_weights_disabled)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, lottery.ID, lottery.Title, lottery.Description, lottery.CreatorID, lottery.Participants, lottery.DrawMode, lottery.DrawTime, lottery.MaxEntries, lottery.Status, lottery.CreatedAt, lottery.IsWeightsDisabled)
-	return err
-}
-
-func getLotteryTx(tx *sql.Tx, id string) (*models.Lottery, error) {
-	lottery := &models.Lottery{}
-	err := tx.QueryRow(`
-		SELECT id, title, description, creator_id, participants, draw_mode, draw_time, max_entries, status, created_at, is_weights_disabled
-		FROM lotteries WHERE id = ?
-	`, id).Scan(
-		&lottery.ID,
-		&lottery.Title,
-		&lottery.Description,
-		&lottery.CreatorID,
-		&lottery.Participants,
-		&lottery.DrawMode,
-		&lottery.DrawTime,
-		&lottery.MaxEntries,
-		&lottery.Status,
-		&lottery.CreatedAt,
-		&lottery.IsWeightsDisabled,
-	)
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, err
-	}
-	return lottery, nil
-}
-
-func updateLotteryTx(tx *sql.Tx, lottery *models.Lottery) error {
-	_, err := tx.Exec(`
-		UPDATE lotteries
-		SET title = ?, description = ?, participants = ?, draw_mode = ?, draw_time = ?, max_entries = ?, status = ?, is_weights_disabled = ?
-		WHERE id = ?
-	`, lottery.Title, lottery.Description, lottery.Participants, lottery.DrawMode, lottery.DrawTime, lottery.MaxEntries, lottery.Status, lottery.IsWeightsDisabled, lottery.ID)
-	return err
-}
-
-func deletePrizesTx(tx *sql.Tx, lotteryID string) error {
-	_, err := tx.Exec(`DELETE FROM prizes WHERE lottery_id = ?`, lotteryID)
-	return err
-}
-
-func createPrizesTx(tx *sql.Tx, lotteryID string, prizes []models.Prize) error {
-	stmt, err := tx.Prepare(`
-		INSERT INTO prizes (lottery_id, name, quantity)
-		VALUES (?, ?, ?)
-	`)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
-
-	for i := range prizes {
-		_, err := stmt.Exec(lotteryID, prizes[i].Name, prizes[i].Quantity)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func getPrizesTx(tx *sql.Tx, lotteryID string) ([]models.Prize, error) {
-	rows, err := tx.Query(`
-		SELECT id, lottery_id, name, quantity
-		FROM prizes WHERE lottery_id = ?
-	`, lotteryID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var prizes []models.Prize
-	for rows.Next() {
-		var p models.Prize
-		if scanErr := rows.Scan(&p.ID, &p.LotteryID, &p.Name, &p.Quantity); scanErr != nil {
-			return nil, scanErr
-		}
-		prizes = append(prizes, p)
-	}
-	if rows.Err() != nil {
-		return nil, rows.Err()
-	}
-	return prizes, nil
-}
-
-func getParticipantsTx(tx *sql.Tx, lotteryID string) ([]models.Participant, error) {
-	rows, err := tx.Query(`
-		SELECT id, lottery_id, user_id, username, first_name, last_name, weight, joined_at
-		FROM participants WHERE lottery_id = ? ORDER BY joined_at
-	`, lotteryID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var participants []models.Participant
-	participantMap := make(map[int64]*models.Participant)
-
-	for rows.Next() {
-		var p models.Participant
-		if scanErr := rows.Scan(&p.ID, &p.LotteryID, &p.UserID, &p.Username, &p.FirstName, &p.LastName, &p.Weight, &p.JoinedAt); scanErr != nil {
-			return nil, scanErr
-		}
-		p.PrizeWeights = make(map[int64]int)
-		participants = append(participants, p)
-		participantMap[p.UserID] = &participants[len(participants)-1]
-	}
-	if rows.Err() != nil {
-		return nil, rows.Err()
-	}
-
-	prizeWeightRows, err := tx.Query(`
-		SELECT user_id, prize_id, weight
-		FROM prize_weights WHERE lottery_id = ?
-	`, lotteryID)
-	if err != nil {
-		return nil, err
-	}
-	defer prizeWeightRows.Close()
-
-	for prizeWeightRows.Next() {
-		var userID int64
-		var prizeID int64
-		var weight int
-		if scanErr := prizeWeightRows.Scan(&userID, &prizeID, &weight); scanErr != nil {
-			return nil, scanErr
-		}
-		if p, ok := participantMap[userID]; ok {
-			p.PrizeWeights[prizeID] = weight
-		}
-	}
-	if prizeWeightRows.Err() != nil {
-		return nil, prizeWeightRows.Err()
-	}
-
-	return participants, nil
-}
-
-func createWinnerStmt(stmt *sql.Stmt, winner *models.Winner) error {
-	result, err := stmt.Exec(winner.LotteryID, winner.ParticipantID, winner.PrizeID, winner.UserID, winner.Username, winner.PrizeName)
-	if err != nil {
-		return err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to fetch winner id: %w", err)
-	}
-	winner.ID = id
-	return nil
-}
-
-func cleanupAfterDrawTx(tx *sql.Tx, lotteryID string) error {
-	if _, err := tx.Exec(`DELETE FROM prize_weights WHERE lottery_id = ?`, lotteryID); err != nil {
-		return err
-	}
-	if _, err := tx.Exec(`DELETE FROM participants WHERE lottery_id = ?`, lotteryID); err != nil {
-		return err
-	}
-	if _, err := tx.Exec(`DELETE FROM edit_tokens WHERE lottery_id = ?`, lotteryID); err != nil {
-		return err
-	}
-	return nil
-}
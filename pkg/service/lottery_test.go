@@ -0,0 +1,324 @@
+package service_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/realSunyz/lucky-tgbot/pkg/config"
+	"github.com/realSunyz/lucky-tgbot/pkg/database"
+	"github.com/realSunyz/lucky-tgbot/pkg/models"
+	"github.com/realSunyz/lucky-tgbot/pkg/service"
+)
+
+const (
+	creatorID int64 = 100
+	adminID   int64 = 900
+)
+
+// recordingNotifier hands the winners of each draw to the test.
+type recordingNotifier struct {
+	drawn chan []models.Winner
+}
+
+func (n *recordingNotifier) LotteryCreated(*models.Lottery, []models.Prize) {}
+
+func (n *recordingNotifier) WinnersDrawn(_ *models.Lottery, _ []models.Prize, winners []models.Winner) {
+	n.drawn <- winners
+}
+
+func newTestService(repo database.LotteryRepository) (*service.LotteryService, *recordingNotifier) {
+	cfg := config.Default()
+	cfg.Telegram.Token = "test-token"
+	cfg.Telegram.AdminUserIDs = []int64{adminID}
+	notifier := &recordingNotifier{drawn: make(chan []models.Winner, 1)}
+	return service.NewLotteryService(repo, notifier, cfg), notifier
+}
+
+func createLottery(t *testing.T, svc *service.LotteryService, id string, input service.CreateLotteryInput) {
+	t.Helper()
+	input.CreatorID = creatorID
+	if input.Title == "" {
+		input.Title = "Test lottery " + id
+	}
+	if input.DrawMode == "" {
+		input.DrawMode = "manual"
+	}
+	if input.Prizes == nil {
+		input.Prizes = []models.Prize{{Name: "Prize", Quantity: 1}}
+	}
+	if _, _, err := svc.CreateLottery(id, input); err != nil {
+		t.Fatalf("CreateLottery(%s): %v", id, err)
+	}
+}
+
+func join(svc *service.LotteryService, lotteryID string, userID int64) error {
+	_, _, err := svc.JoinLottery(lotteryID, service.JoinInput{UserID: userID, Username: "user"})
+	return err
+}
+
+func participantCount(t *testing.T, svc *service.LotteryService, lotteryID string) int {
+	t.Helper()
+	snapshot, err := svc.GetLotterySnapshot(lotteryID)
+	if err != nil {
+		t.Fatalf("GetLotterySnapshot(%s): %v", lotteryID, err)
+	}
+	return snapshot.Lottery.Participants
+}
+
+func TestJoinLottery(t *testing.T) {
+	svc, _ := newTestService(database.NewMemoryRepository())
+	createLottery(t, svc, "100001", service.CreateLotteryInput{})
+
+	if err := join(svc, "100001", 1); err != nil {
+		t.Fatalf("first join: %v", err)
+	}
+	if err := join(svc, "100001", 1); !errors.Is(err, service.ErrParticipantExists) {
+		t.Errorf("second join = %v, want ErrParticipantExists", err)
+	}
+	if err := join(svc, "999999", 1); !errors.Is(err, service.ErrLotteryNotFound) {
+		t.Errorf("join of a missing lottery = %v, want ErrLotteryNotFound", err)
+	}
+
+	if err := svc.AdminBanUser(adminID, 2, "spam"); err != nil {
+		t.Fatalf("AdminBanUser: %v", err)
+	}
+	if err := join(svc, "100001", 2); !errors.Is(err, service.ErrUserBanned) {
+		t.Errorf("join by a banned user = %v, want ErrUserBanned", err)
+	}
+
+	if got := participantCount(t, svc, "100001"); got != 1 {
+		t.Errorf("participants = %d, want 1", got)
+	}
+}
+
+func TestJoinRespectsMaxEntries(t *testing.T) {
+	svc, _ := newTestService(database.NewMemoryRepository())
+	maxEntries := 2
+	createLottery(t, svc, "100002", service.CreateLotteryInput{MaxEntries: &maxEntries})
+
+	for userID := int64(1); userID <= 2; userID++ {
+		if err := join(svc, "100002", userID); err != nil {
+			t.Fatalf("join of user %d: %v", userID, err)
+		}
+	}
+	if err := join(svc, "100002", 3); !errors.Is(err, service.ErrLotteryFull) {
+		t.Errorf("join beyond max entries = %v, want ErrLotteryFull", err)
+	}
+	if got := participantCount(t, svc, "100002"); got != 2 {
+		t.Errorf("participants = %d, want 2", got)
+	}
+}
+
+func TestFullLotteryDrawsOnLastEntry(t *testing.T) {
+	svc, notifier := newTestService(database.NewMemoryRepository())
+	maxEntries := 2
+	createLottery(t, svc, "100003", service.CreateLotteryInput{DrawMode: "full", MaxEntries: &maxEntries})
+
+	for userID := int64(1); userID <= 2; userID++ {
+		if err := join(svc, "100003", userID); err != nil {
+			t.Fatalf("join of user %d: %v", userID, err)
+		}
+	}
+
+	select {
+	case winners := <-notifier.drawn:
+		if len(winners) != 1 {
+			t.Fatalf("got %d winners, want 1", len(winners))
+		}
+		if w := winners[0]; w.UserID != 1 && w.UserID != 2 {
+			t.Errorf("winner %d did not join", w.UserID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the lottery was not drawn after it filled up")
+	}
+
+	lottery, _, winners, err := svc.GetResults("100003")
+	if err != nil {
+		t.Fatalf("GetResults: %v", err)
+	}
+	if lottery.Status != "completed" || len(winners) != 1 {
+		t.Errorf("status %q with %d winners, want completed with 1", lottery.Status, len(winners))
+	}
+	if err := join(svc, "100003", 3); !errors.Is(err, service.ErrLotteryNotActive) {
+		t.Errorf("join after the draw = %v, want ErrLotteryNotActive", err)
+	}
+}
+
+// failingAuditRepository fails every audit write made in a transaction, after
+// the change it records has been made.
+type failingAuditRepository struct {
+	database.LotteryRepository
+}
+
+var errAuditFailed = errors.New("audit failed")
+
+func (r failingAuditRepository) InTx(fn func(tx database.LotteryRepository) error) error {
+	return r.LotteryRepository.InTx(func(tx database.LotteryRepository) error {
+		return fn(failingAuditRepository{tx})
+	})
+}
+
+func (r failingAuditRepository) AppendAudit(*models.AuditEntry) error {
+	return errAuditFailed
+}
+
+func TestJoinRollsBackWhenTransactionFails(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	setup, _ := newTestService(repo)
+	createLottery(t, setup, "100004", service.CreateLotteryInput{})
+
+	svc, _ := newTestService(failingAuditRepository{repo})
+	if err := join(svc, "100004", 1); !errors.Is(err, errAuditFailed) {
+		t.Fatalf("join = %v, want the audit error", err)
+	}
+
+	participant, err := repo.GetParticipant("100004", 1)
+	if err != nil {
+		t.Fatalf("GetParticipant: %v", err)
+	}
+	if participant != nil {
+		t.Error("the participant was kept after the transaction failed")
+	}
+	if got := participantCount(t, setup, "100004"); got != 0 {
+		t.Errorf("participants = %d after the transaction failed, want 0", got)
+	}
+}
+
+func auditActions(entries []models.AuditEntry) []string {
+	actions := make([]string, 0, len(entries))
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+	}
+	return actions
+}
+
+func TestAuditLog(t *testing.T) {
+	svc, _ := newTestService(database.NewMemoryRepository())
+	createLottery(t, svc, "100005", service.CreateLotteryInput{})
+	if err := join(svc, "100005", 1); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	if err := svc.UpdateParticipantWeight("100005", creatorID, 1, 5); err != nil {
+		t.Fatalf("UpdateParticipantWeight: %v", err)
+	}
+
+	entries, err := svc.GetAuditLog("100005", creatorID, 0, 0)
+	if err != nil {
+		t.Fatalf("GetAuditLog: %v", err)
+	}
+	want := []string{models.AuditParticipantWeight, models.AuditParticipantJoin, models.AuditLotteryCreate}
+	if got := auditActions(entries); !slices.Equal(got, want) {
+		t.Errorf("actions = %v, want %v", got, want)
+	}
+	if weight := entries[0]; string(weight.OldValue) != `{"weight":1}` || string(weight.NewValue) != `{"weight":5}` {
+		t.Errorf("weight change recorded as %s -> %s", weight.OldValue, weight.NewValue)
+	}
+
+	page, err := svc.GetAuditLog("100005", creatorID, entries[0].ID, 1)
+	if err != nil {
+		t.Fatalf("GetAuditLog page: %v", err)
+	}
+	if got := auditActions(page); !slices.Equal(got, want[1:2]) {
+		t.Errorf("second page = %v, want %v", got, want[1:2])
+	}
+
+	if _, err := svc.GetAuditLog("100005", 1, 0, 0); !errors.Is(err, service.ErrPermissionDenied) {
+		t.Errorf("GetAuditLog by a participant = %v, want ErrPermissionDenied", err)
+	}
+
+	// Bans belong to no lottery and only show up in the full log
+	if err := svc.AdminBanUser(adminID, 2, "spam"); err != nil {
+		t.Fatalf("AdminBanUser: %v", err)
+	}
+	full, err := svc.GetFullAuditLog(adminID, 0, 0)
+	if err != nil {
+		t.Fatalf("GetFullAuditLog: %v", err)
+	}
+	if len(full) == 0 {
+		t.Fatal("the full log is empty")
+	}
+	if full[0].Action != models.AuditUserBan || full[0].LotteryID != "" {
+		t.Errorf("newest full log entry = %+v, want a ban without a lottery", full[0])
+	}
+	if _, err := svc.GetFullAuditLog(creatorID, 0, 0); !errors.Is(err, service.ErrPermissionDenied) {
+		t.Errorf("GetFullAuditLog by a creator = %v, want ErrPermissionDenied", err)
+	}
+}
+
+func TestDrawEnforcesWeightsSetting(t *testing.T) {
+	tests := []struct {
+		name            string
+		weightsDisabled bool
+		want            []int64
+	}{
+		// User 1 has weight 0 and never wins while weights count
+		{"weights enabled", false, []int64{2}},
+		{"weights disabled", true, []int64{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _ := newTestService(database.NewMemoryRepository())
+			createLottery(t, svc, "100006", service.CreateLotteryInput{
+				Prizes:            []models.Prize{{Name: "Prize", Quantity: 2}},
+				IsWeightsDisabled: tt.weightsDisabled,
+			})
+			for userID := int64(1); userID <= 2; userID++ {
+				if err := join(svc, "100006", userID); err != nil {
+					t.Fatalf("join of user %d: %v", userID, err)
+				}
+			}
+			if err := svc.UpdateParticipantWeight("100006", creatorID, 1, 0); err != nil {
+				t.Fatalf("UpdateParticipantWeight: %v", err)
+			}
+
+			winners, err := svc.DrawLottery("100006", creatorID)
+			if err != nil {
+				t.Fatalf("DrawLottery: %v", err)
+			}
+			var got []int64
+			for _, w := range winners {
+				got = append(got, w.UserID)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("winners = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetPrizeWeightChecksEntry(t *testing.T) {
+	svc, _ := newTestService(database.NewMemoryRepository())
+	createLottery(t, svc, "100007", service.CreateLotteryInput{})
+	createLottery(t, svc, "100008", service.CreateLotteryInput{})
+	if err := join(svc, "100007", 1); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	prizeID := func(lotteryID string) int64 {
+		t.Helper()
+		snapshot, err := svc.GetLotterySnapshot(lotteryID)
+		if err != nil {
+			t.Fatalf("GetLotterySnapshot(%s): %v", lotteryID, err)
+		}
+		return snapshot.Prizes[0].ID
+	}
+
+	if err := svc.SetPrizeWeight("100007", creatorID, 2, prizeID("100007"), 3); !errors.Is(err, service.ErrParticipantNotFound) {
+		t.Errorf("prize weight for a non-participant = %v, want ErrParticipantNotFound", err)
+	}
+	if err := svc.SetPrizeWeight("100007", creatorID, 1, prizeID("100008"), 3); !errors.Is(err, service.ErrPrizeNotFound) {
+		t.Errorf("prize weight for another lottery's prize = %v, want ErrPrizeNotFound", err)
+	}
+	if err := svc.SetPrizeWeight("100007", creatorID, 1, prizeID("100007"), 3); err != nil {
+		t.Errorf("SetPrizeWeight: %v", err)
+	}
+
+	if _, err := svc.DrawLottery("100007", creatorID); err != nil {
+		t.Fatalf("DrawLottery: %v", err)
+	}
+	if err := svc.SetPrizeWeight("100007", creatorID, 1, prizeID("100007"), 5); !errors.Is(err, service.ErrLotteryEnded) {
+		t.Errorf("prize weight after the draw = %v, want ErrLotteryEnded", err)
+	}
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IssueCreateToken signs a short-lived token that lets the creator publish
+// the given draft. The token is stateless: it carries the draft ID, the
+// creator and the expiry, and is verified against the service secret.
+func (s *LotteryService) IssueCreateToken(lotteryID string, creatorID int64) string {
+	payload := fmt.Sprintf("%s:%d:%d", lotteryID, creatorID, time.Now().Add(s.cfg.CreateTokenTTL).Unix())
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.signCreateToken(encoded))
+}
+
+// VerifyCreateToken checks a creation token for the given draft and returns
+// the creator it was issued to.
+func (s *LotteryService) VerifyCreateToken(lotteryID, token string) (int64, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, ErrTokenInvalid
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, s.signCreateToken(encoded)) {
+		return 0, ErrTokenInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, ErrTokenInvalid
+	}
+
+	parts := strings.Split(string(payload), ":")
+	if len(parts) != 3 || parts[0] != lotteryID {
+		return 0, ErrTokenInvalid
+	}
+
+	creatorID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, ErrTokenInvalid
+	}
+	expiresAt, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return 0, ErrTokenInvalid
+	}
+
+	return creatorID, nil
+}
+
+func (s *LotteryService) signCreateToken(encoded string) []byte {
+	mac := hmac.New(sha256.New, s.tokenSecret)
+	mac.Write([]byte("create:" + encoded))
+	return mac.Sum(nil)
+}
+
+// newSecret returns a random URL-safe secret for edit link codes and session
+// tokens.
+func newSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashSecret is what gets stored for link codes and session tokens, so a
+// leaked database does not leak usable credentials.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/realSunyz/lucky-tgbot/pkg/models"
+)
+
+// effectiveWeight is the number of entries a participant has in the draw of
+// a prize.
+func effectiveWeight(p models.Participant, prizeID int64) int {
+	if w, ok := p.PrizeWeights[prizeID]; ok {
+		return w
+	}
+	return p.Weight
+}
+
+// summarizeWeights counts how many participants share each weight without
+// saying who has which. Prizes lists only prizes with per-prize overrides.
+func summarizeWeights(prizes []models.Prize, participants []models.Participant) *models.WeightSummary {
+	tally := newWeightTally(prizes)
+	tally.add(participants)
+	return tally.summary()
+}
+
+// weightTally builds a weight summary from participants added a page at a
+// time.
+type weightTally struct {
+	prizes     []models.Prize
+	custom     bool
+	base       map[int]int
+	perPrize   map[int64]map[int]int
+	overridden map[int64]bool
+}
+
+func newWeightTally(prizes []models.Prize) *weightTally {
+	t := &weightTally{
+		prizes:     prizes,
+		base:       make(map[int]int),
+		perPrize:   make(map[int64]map[int]int, len(prizes)),
+		overridden: make(map[int64]bool),
+	}
+	for _, prize := range prizes {
+		t.perPrize[prize.ID] = make(map[int]int)
+	}
+	return t
+}
+
+func (t *weightTally) add(participants []models.Participant) {
+	for _, p := range participants {
+		t.base[p.Weight]++
+		if p.Weight != 1 {
+			t.custom = true
+		}
+		for _, prize := range t.prizes {
+			if _, ok := p.PrizeWeights[prize.ID]; ok {
+				t.overridden[prize.ID] = true
+			}
+			t.perPrize[prize.ID][effectiveWeight(p, prize.ID)]++
+		}
+	}
+}
+
+func (t *weightTally) summary() *models.WeightSummary {
+	summary := &models.WeightSummary{
+		Custom:       t.custom,
+		Distribution: weightBuckets(t.base),
+	}
+	for _, prize := range t.prizes {
+		if !t.overridden[prize.ID] {
+			continue
+		}
+		summary.Custom = true
+		summary.Prizes = append(summary.Prizes, models.PrizeWeightDistribution{
+			PrizeID:      prize.ID,
+			Distribution: weightBuckets(t.perPrize[prize.ID]),
+		})
+	}
+	return summary
+}
+
+func weightBuckets(counts map[int]int) []models.WeightBucket {
+	buckets := make([]models.WeightBucket, 0, len(counts))
+	for weight, n := range counts {
+		buckets = append(buckets, models.WeightBucket{Weight: weight, Participants: n})
+	}
+	slices.SortFunc(buckets, func(a, b models.WeightBucket) int {
+		return a.Weight - b.Weight
+	})
+	return buckets
+}
+
+// GetWeightSummary returns the public weight summary of a lottery, or nil when
+// weights are disabled. The distribution of an active lottery is counted by
+// the database. Participants are removed once a lottery is drawn, so
+// completed lotteries report the distribution recorded with the draw.
+func (s *LotteryService) GetWeightSummary(lottery *models.Lottery) (*models.WeightSummary, error) {
+	if lottery.IsWeightsDisabled {
+		return nil, nil
+	}
+
+	var summary *models.WeightSummary
+	if lottery.Status == "completed" {
+		draw, err := s.lastDrawAudit(lottery.ID)
+		if err != nil {
+			return nil, err
+		}
+		if draw == nil || draw.Weights == nil {
+			return nil, nil
+		}
+		summary = draw.Weights
+	} else {
+		distribution, err := s.repo.GetWeightDistribution(lottery.ID)
+		if err != nil {
+			return nil, err
+		}
+		prizes, err := s.repo.GetPrizeWeightDistributions(lottery.ID)
+		if err != nil {
+			return nil, err
+		}
+		summary = &models.WeightSummary{
+			Custom: len(prizes) > 0 || slices.ContainsFunc(distribution, func(b models.WeightBucket) bool {
+				return b.Weight != 1
+			}),
+			Distribution: distribution,
+			Prizes:       prizes,
+		}
+	}
+
+	changedAt, err := s.repo.LastWeightChangeAfterJoin(lottery.ID)
+	if err != nil {
+		return nil, err
+	}
+	summary.ChangedAfterJoin = changedAt != nil
+	summary.LastChangedAt = changedAt
+	return summary, nil
+}
+
+func (s *LotteryService) lastDrawAudit(lotteryID string) (*drawAuditValue, error) {
+	entry, err := s.repo.LastAuditEntry(lotteryID, models.AuditLotteryDraw)
+	if err != nil || entry == nil || entry.NewValue == nil {
+		return nil, err
+	}
+
+	var draw drawAuditValue
+	if err := json.Unmarshal(entry.NewValue, &draw); err != nil {
+		return nil, err
+	}
+	return &draw, nil
+}
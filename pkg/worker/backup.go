@@ -0,0 +1,119 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/realSunyz/lucky-tgbot/pkg/database"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	"github.com/realSunyz/lucky-tgbot/pkg/metrics"
+	"github.com/realSunyz/lucky-tgbot/pkg/models"
+)
+
+const backupTimeFormat = "20060102T150405.000Z"
+
+// backupName matches the snapshots Backups writes, which sort by age.
+var backupName = regexp.MustCompile(`^lottery-\d{8}T\d{6}\.\d{3}Z\.db$`)
+
+// Backups writes snapshots of a SQLite database into a directory and keeps
+// only the newest ones.
+type Backups struct {
+	dbPath string
+	dir    string
+	keep   int
+	// mu keeps a scheduled and an on-demand backup from overlapping.
+	mu sync.Mutex
+}
+
+func NewBackups(dbPath, dir string, keep int) *Backups {
+	return &Backups{dbPath: dbPath, dir: dir, keep: keep}
+}
+
+// Backup writes a new snapshot and removes the snapshots beyond the newest
+// keep. The snapshot is written under a temporary name and renamed when it
+// is complete, so the directory only ever holds whole snapshots.
+func (b *Backups) Backup() (*models.Backup, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	timer := prometheus.NewTimer(metrics.BackupDuration)
+	defer timer.ObserveDuration()
+
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	now := time.Now().UTC()
+	name := "lottery-" + now.Format(backupTimeFormat) + ".db"
+	path := filepath.Join(b.dir, name)
+	tmpPath := path + ".tmp"
+	if err := database.Snapshot(b.dbPath, tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	metrics.LastBackup.SetToCurrentTime()
+
+	if err := b.rotate(); err != nil {
+		logger.Error("error removing old backups", "dir", b.dir, "error", err)
+	}
+	return &models.Backup{Name: name, Size: info.Size(), CreatedAt: now}, nil
+}
+
+// rotate removes all but the newest keep snapshots.
+func (b *Backups) rotate() error {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && backupName.MatchString(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) <= b.keep {
+		return nil
+	}
+
+	slices.Sort(names)
+	for _, name := range names[:len(names)-b.keep] {
+		if err := os.Remove(filepath.Join(b.dir, name)); err != nil {
+			return err
+		}
+		logger.Info("removed old backup", "name", name)
+	}
+	return nil
+}
+
+// StartBackupWorker takes a backup every interval.
+func StartBackupWorker(backups *Backups, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			backup, err := backups.Backup()
+			if err != nil {
+				logger.Error("error backing up database", "error", err)
+				continue
+			}
+			logger.Info("database backed up", "name", backup.Name, "size", backup.Size)
+		}
+	}()
+}
@@ -3,59 +3,37 @@ package worker
 import (
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/realSunyz/lucky-tgbot/pkg/database"
 	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	"github.com/realSunyz/lucky-tgbot/pkg/metrics"
 )
 
-func StartCleanupWorker() {
+// StartCleanupWorker runs the periodic maintenance every interval and
+// removes drafts older than draftExpiry.
+func StartCleanupWorker(repo database.LotteryRepository, interval, draftExpiry time.Duration) {
 	go func() {
-		ticker := time.NewTicker(time.Hour)
+		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 
 		for range ticker.C {
-			if err := cleanupDrafts(); err != nil {
-				logger.Errorf("error cleaning up drafts: %v", err)
-			}
-			if err := cleanupExpiredTokens(); err != nil {
-				logger.Errorf("error cleaning up expired tokens: %v", err)
-			}
-			if err := checkpointWAL(); err != nil {
-				logger.Errorf("error checkpointing WAL: %v", err)
-			}
-			if err := optimizeSQLite(); err != nil {
-				logger.Errorf("error optimizing sqlite: %v", err)
-			}
+			runCleanup(repo, draftExpiry)
 		}
 	}()
 }
 
-func cleanupDrafts() error {
-	db := database.GetDB()
-	cutoff := time.Now().UTC().Add(-1 * time.Hour)
-	_, err := db.Exec(`DELETE FROM lotteries WHERE status = 'draft' AND created_at < ?`, cutoff)
-	if err != nil {
-		return err
-	}
-	return nil
-}
+func runCleanup(repo database.LotteryRepository, draftExpiry time.Duration) {
+	timer := prometheus.NewTimer(metrics.CleanupDuration)
+	defer timer.ObserveDuration()
 
-func cleanupExpiredTokens() error {
-	db := database.GetDB()
-	_, err := db.Exec(`DELETE FROM edit_tokens WHERE expires_at < ?`, time.Now().UTC())
-	if err != nil {
-		return err
+	now := time.Now().UTC()
+	if err := repo.DeleteDraftsCreatedBefore(now.Add(-draftExpiry)); err != nil {
+		logger.Error("error cleaning up drafts", "error", err)
+	}
+	if err := repo.DeleteExpiredEditSessions(now); err != nil {
+		logger.Error("error cleaning up expired edit sessions", "error", err)
+	}
+	if err := repo.Maintain(); err != nil {
+		logger.Error("error running database maintenance", "error", err)
 	}
-	return nil
-}
-
-func checkpointWAL() error {
-	db := database.GetDB()
-	_, err := db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`)
-	return err
-}
-
-func optimizeSQLite() error {
-	db := database.GetDB()
-	_, err := db.Exec(`PRAGMA optimize`)
-	return err
 }
@@ -0,0 +1,175 @@
+package lottery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	"github.com/realSunyz/lucky-tgbot/pkg/service"
+)
+
+const adminUsage = "用法:\n" +
+	"<code>/admin stats</code> 查看统计\n" +
+	"<code>/admin lottery 123456</code> 查看任意抽奖\n" +
+	"<code>/admin forcedraw 123456</code> 强制开奖\n" +
+	"<code>/admin delete 123456</code> 强制删除\n" +
+	"<code>/admin ban 用户ID [原因]</code> 封禁用户\n" +
+	"<code>/admin unban 用户ID</code> 解除封禁\n" +
+	"<code>/admin backup</code> 立即备份数据库"
+
+// HandleAdminCommand serves the operator-only /admin subcommands. Users not
+// listed in ADMIN_USER_IDS get the same reply as for an unknown command.
+func HandleAdminCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if lotteryService == nil {
+		logger.ErrorContext(ctx, "lottery service is not initialized")
+		return
+	}
+
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	adminID := update.Message.From.ID
+	if update.Message.Chat.Type != "private" || !lotteryService.IsAdmin(adminID) {
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 此命令仅限管理员在私聊中使用"})
+		return
+	}
+
+	parts := strings.Fields(strings.TrimSpace(update.Message.Text))
+	if len(parts) < 2 {
+		sendAdminText(ctx, b, chatID, adminUsage)
+		return
+	}
+
+	switch subcommand := parts[1]; subcommand {
+	case "stats":
+		stats, err := lotteryService.AdminStats(adminID)
+		if err != nil {
+			sendAdminError(ctx, b, chatID, err)
+			return
+		}
+		sendAdminText(ctx, b, chatID, fmt.Sprintf("📈 运行统计\n\n抽奖总数: %d\n草稿: %d\n进行中: %d\n已开奖: %d\n定时待开奖: %d\n今日创建: %d\n\n参与记录: %d\n中奖记录: %d\n创建者: %d\n有效编辑会话: %d\n封禁用户: %d",
+			stats.TotalCount, stats.DraftCount, stats.ActiveCount, stats.CompletedCount, stats.ScheduledCount, stats.TodayCount,
+			stats.ParticipantCount, stats.WinnerCount, stats.CreatorCount, stats.EditSessionCount, stats.BannedUserCount))
+	case "lottery":
+		if len(parts) < 3 {
+			sendAdminText(ctx, b, chatID, adminUsage)
+			return
+		}
+		info, err := lotteryService.AdminInspectLottery(adminID, parts[2])
+		if err != nil {
+			sendAdminError(ctx, b, chatID, err)
+			return
+		}
+		sendAdminText(ctx, b, chatID, formatAdminLottery(info))
+	case "forcedraw":
+		if len(parts) < 3 {
+			sendAdminText(ctx, b, chatID, adminUsage)
+			return
+		}
+		winners, err := lotteryService.AdminDrawLottery(adminID, parts[2])
+		if err != nil {
+			sendAdminError(ctx, b, chatID, err)
+			return
+		}
+		sendAdminText(ctx, b, chatID, fmt.Sprintf("🎉 已强制开奖 <code>%s</code>, 共 %d 位中奖者", parts[2], len(winners)))
+	case "delete":
+		if len(parts) < 3 {
+			sendAdminText(ctx, b, chatID, adminUsage)
+			return
+		}
+		if err := lotteryService.AdminDeleteLottery(adminID, parts[2]); err != nil {
+			sendAdminError(ctx, b, chatID, err)
+			return
+		}
+		sendAdminText(ctx, b, chatID, fmt.Sprintf("🗑 已强制删除抽奖 <code>%s</code>", parts[2]))
+	case "ban", "unban":
+		if len(parts) < 3 {
+			sendAdminText(ctx, b, chatID, adminUsage)
+			return
+		}
+		userID, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 无效的用户 ID"})
+			return
+		}
+		if subcommand == "ban" {
+			err = lotteryService.AdminBanUser(adminID, userID, strings.Join(parts[3:], " "))
+		} else {
+			err = lotteryService.AdminUnbanUser(adminID, userID)
+		}
+		if err != nil {
+			sendAdminError(ctx, b, chatID, err)
+			return
+		}
+		if subcommand == "ban" {
+			sendAdminText(ctx, b, chatID, fmt.Sprintf("🚫 已封禁用户 <code>%d</code>", userID))
+		} else {
+			sendAdminText(ctx, b, chatID, fmt.Sprintf("✅ 已解除封禁用户 <code>%d</code>", userID))
+		}
+	case "backup":
+		backup, err := lotteryService.AdminBackup(adminID)
+		if err != nil {
+			sendAdminError(ctx, b, chatID, err)
+			return
+		}
+		sendAdminText(ctx, b, chatID, fmt.Sprintf("💾 已备份数据库\n\n文件: <code>%s</code>\n大小: %.1f MB", backup.Name, float64(backup.Size)/(1<<20)))
+	default:
+		sendAdminText(ctx, b, chatID, adminUsage)
+	}
+}
+
+func formatAdminLottery(info *service.AdminLotteryInfo) string {
+	lottery := info.Lottery
+
+	var sb strings.Builder
+	sb.WriteString(formatLotteryStatus(info.LotterySnapshot))
+	sb.WriteString(fmt.Sprintf("\n\n创建者: <code>%d</code>\n创建时间: %s", lottery.CreatorID, lottery.CreatedAt.Local().Format("2006-01-02 15:04")))
+
+	sb.WriteString("\n\n组织者:")
+	if len(info.Organizers) == 0 {
+		sb.WriteString(" 无")
+	}
+	for _, organizer := range info.Organizers {
+		sb.WriteString(fmt.Sprintf("\n- <code>%d</code> %s", organizer.UserID, roleNames[organizer.Role]))
+	}
+
+	sb.WriteString(fmt.Sprintf("\n\n有效编辑会话: %d", len(info.Sessions)))
+	return sb.String()
+}
+
+func sendAdminText(ctx context.Context, b *bot.Bot, chatID int64, text string) {
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      text,
+		ParseMode: tgmodels.ParseModeHTML,
+	})
+}
+
+func sendAdminError(ctx context.Context, b *bot.Bot, chatID int64, err error) {
+	switch {
+	case errors.Is(err, service.ErrLotteryNotFound):
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 未找到该抽奖"})
+	case errors.Is(err, service.ErrLotteryEnded):
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 该抽奖已结束"})
+	case errors.Is(err, service.ErrLotteryNotActive):
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 该抽奖尚未发布"})
+	case errors.Is(err, service.ErrCannotBanAdmin):
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 无法封禁管理员"})
+	case errors.Is(err, service.ErrUserNotBanned):
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 该用户未被封禁"})
+	case errors.Is(err, service.ErrBackupsDisabled):
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 未配置数据库备份"})
+	case errors.Is(err, service.ErrPermissionDenied):
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 此命令仅限管理员在私聊中使用"})
+	default:
+		logger.ErrorContext(ctx, "admin command failed", "error", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 操作失败, 请稍后重试"})
+	}
+}
@@ -0,0 +1,223 @@
+package lottery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	dbmodels "github.com/realSunyz/lucky-tgbot/pkg/models"
+	"github.com/realSunyz/lucky-tgbot/pkg/service"
+)
+
+const DrawCallbackPrefix = "draw_"
+
+func HandleDrawCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	lotteryID, ok := lotteryCommandArg(ctx, b, update, "/draw")
+	if !ok {
+		return
+	}
+
+	snapshot, err := lotteryService.GetLotterySnapshot(lotteryID)
+	if err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: drawErrorText(ctx, lotteryID, err)})
+		return
+	}
+	switch snapshot.Lottery.Status {
+	case "completed":
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: drawErrorText(ctx, lotteryID, service.ErrLotteryEnded)})
+		return
+	case "draft":
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: drawErrorText(ctx, lotteryID, service.ErrLotteryNotActive)})
+		return
+	}
+
+	sendDrawConfirmation(ctx, b, update.Message.Chat.ID, lotteryID)
+}
+
+func HandleStatusCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	lotteryID, ok := lotteryCommandArg(ctx, b, update, "/status")
+	if !ok {
+		return
+	}
+
+	snapshot, err := lotteryService.GetLotterySnapshot(lotteryID)
+	if err != nil {
+		if errors.Is(err, service.ErrLotteryNotFound) {
+			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: "❌ 未找到该抽奖"})
+			return
+		}
+		logger.ErrorContext(ctx, "failed to get lottery for status", "lottery_id", lotteryID, "error", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: "❌ 获取抽奖状态失败, 请稍后重试"})
+		return
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    update.Message.Chat.ID,
+		Text:      formatLotteryStatus(snapshot),
+		ParseMode: tgmodels.ParseModeHTML,
+	})
+}
+
+// lotteryCommandArg runs the common checks for "/<command> <id>" commands and
+// returns the lottery ID.
+func lotteryCommandArg(ctx context.Context, b *bot.Bot, update *tgmodels.Update, command string) (string, bool) {
+	if lotteryService == nil {
+		logger.ErrorContext(ctx, "lottery service is not initialized")
+		return "", false
+	}
+
+	if update.Message == nil {
+		return "", false
+	}
+
+	if update.Message.Chat.Type != "private" {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ 请在私聊中使用此命令",
+		})
+		return "", false
+	}
+
+	parts := strings.Fields(strings.TrimSpace(update.Message.Text))
+	if len(parts) < 2 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    update.Message.Chat.ID,
+			Text:      fmt.Sprintf("❌ 请提供抽奖 ID\n\n用法: <code>%s 123456</code>", command),
+			ParseMode: tgmodels.ParseModeHTML,
+		})
+		return "", false
+	}
+
+	return parts[1], true
+}
+
+// sendDrawConfirmation asks the owner to confirm a manual draw.
+func sendDrawConfirmation(ctx context.Context, b *bot.Bot, chatID int64, lotteryID string) {
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      fmt.Sprintf("⚠️ 确定要立即为抽奖 <code>%s</code> 开奖吗? 开奖后将无法再参与或修改", lotteryID),
+		ParseMode: tgmodels.ParseModeHTML,
+		ReplyMarkup: &tgmodels.InlineKeyboardMarkup{InlineKeyboard: [][]tgmodels.InlineKeyboardButton{{
+			{Text: "🎲 确认开奖", CallbackData: DrawCallbackPrefix + "ok_" + lotteryID},
+			{Text: "取消", CallbackData: DrawCallbackPrefix + "no_" + lotteryID},
+		}}},
+	})
+}
+
+// HandleDrawCallback answers the draw confirmation keyboard.
+func HandleDrawCallback(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if lotteryService == nil {
+		logger.ErrorContext(ctx, "lottery service is not initialized")
+		return
+	}
+
+	query := update.CallbackQuery
+	if query == nil {
+		return
+	}
+
+	answer, lotteryID, _ := strings.Cut(strings.TrimPrefix(query.Data, DrawCallbackPrefix), "_")
+	message := query.Message.Message
+	if answer != "ok" {
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID})
+		editCallbackMessage(ctx, b, message, fmt.Sprintf("已取消为抽奖 <code>%s</code> 开奖", lotteryID))
+		return
+	}
+
+	winners, err := lotteryService.DrawLotteryAs(lotteryID, query.From.ID)
+	if err != nil {
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            drawErrorText(ctx, lotteryID, err),
+			ShowAlert:       true,
+		})
+		return
+	}
+	logger.InfoContext(ctx, "lottery drawn", "lottery_id", lotteryID, "source", "telegram", "winners", len(winners))
+
+	b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID, Text: "🎉 开奖成功"})
+	editCallbackMessage(ctx, b, message, fmt.Sprintf("🎉 抽奖 <code>%s</code> 已开奖, 共 %d 位中奖者\n\n查看结果:\n%s/lottery/%s",
+		lotteryID, len(winners), getWebDomain(), lotteryID))
+}
+
+func drawErrorText(ctx context.Context, lotteryID string, err error) string {
+	switch {
+	case errors.Is(err, service.ErrLotteryNotFound):
+		return "❌ 未找到该抽奖"
+	case errors.Is(err, service.ErrPermissionDenied):
+		return "❌ 只有该抽奖的所有者可以开奖"
+	case errors.Is(err, service.ErrLotteryEnded):
+		return "❌ 该抽奖已结束"
+	case errors.Is(err, service.ErrLotteryNotActive):
+		return "❌ 该抽奖尚未发布"
+	default:
+		logger.ErrorContext(ctx, "failed to draw lottery", "lottery_id", lotteryID, "error", err)
+		return "❌ 开奖失败, 请稍后重试"
+	}
+}
+
+func formatLotteryStatus(snapshot *service.LotterySnapshot) string {
+	lottery := snapshot.Lottery
+
+	participants := fmt.Sprintf("%d", lottery.Participants)
+	if lottery.MaxEntries != nil {
+		participants = fmt.Sprintf("%d / %d", lottery.Participants, *lottery.MaxEntries)
+	}
+
+	text := fmt.Sprintf("📊 抽奖状态\n\n抽奖 ID: <code>%s</code>\n抽奖标题: %s\n状态: %s\n参与人数: %s\n奖品内容:\n%s\n开奖方式: %s",
+		lottery.ID, html.EscapeString(lottery.Title), statusNames[lottery.Status], participants, formatPrizeLines(snapshot.Prizes), formatDrawInfo(lottery))
+
+	switch lottery.Status {
+	case "active":
+		text += "\n" + formatRemaining(lottery)
+	case "completed":
+		text += fmt.Sprintf("\n\n已开奖, 共 %d 位中奖者, 查看结果:\n%s/lottery/%s", len(snapshot.Winners), getWebDomain(), lottery.ID)
+	}
+	return text
+}
+
+func formatRemaining(lottery *dbmodels.Lottery) string {
+	switch lottery.DrawMode {
+	case "timed":
+		if lottery.DrawTime == nil {
+			return "剩余时间: 未知"
+		}
+		remaining := time.Until(*lottery.DrawTime)
+		if remaining <= 0 {
+			return "剩余时间: 即将开奖"
+		}
+		return "剩余时间: " + formatDuration(remaining)
+	case "full":
+		if lottery.MaxEntries == nil {
+			return "剩余名额: 未知"
+		}
+		return fmt.Sprintf("剩余名额: %d", max(*lottery.MaxEntries-lottery.Participants, 0))
+	default:
+		return "等待所有者手动开奖, 可使用 <code>/draw " + lottery.ID + "</code>"
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	var parts []string
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%d 天", days))
+	}
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%d 小时", hours))
+	}
+	if minutes > 0 || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%d 分钟", max(minutes, 1)))
+	}
+	return strings.Join(parts, " ")
+}
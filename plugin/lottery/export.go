@@ -0,0 +1,113 @@
+package lottery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	"github.com/realSunyz/lucky-tgbot/pkg/service"
+)
+
+// HandleExportCommand sends the participants or winners of a lottery as a
+// document. Usage: /export <id> [participants|winners] [csv|json]. Without a
+// choice, completed lotteries export their winners and others their
+// participants.
+func HandleExportCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if lotteryService == nil {
+		logger.ErrorContext(ctx, "lottery service is not initialized")
+		return
+	}
+
+	if update.Message == nil {
+		return
+	}
+
+	if update.Message.Chat.Type != "private" {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ 请在私聊中使用此命令",
+		})
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	parts := strings.Fields(strings.TrimSpace(update.Message.Text))
+	if len(parts) < 2 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      "❌ 请提供抽奖 ID\n\n用法: <code>/export 123456 [participants|winners] [csv|json]</code>",
+			ParseMode: tgmodels.ParseModeHTML,
+		})
+		return
+	}
+
+	lotteryID := parts[1]
+	format, what := service.ExportFormatCSV, ""
+	for _, arg := range parts[2:] {
+		switch arg = strings.ToLower(arg); arg {
+		case service.ExportFormatCSV, service.ExportFormatJSON:
+			format = arg
+		case service.ExportParticipants, service.ExportWinners:
+			what = arg
+		default:
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:    chatID,
+				Text:      "❌ 无效的参数\n\n用法: <code>/export 123456 [participants|winners] [csv|json]</code>",
+				ParseMode: tgmodels.ParseModeHTML,
+			})
+			return
+		}
+	}
+
+	lottery, err := lotteryService.AuthorizeExport(lotteryID, update.Message.From.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrLotteryNotFound):
+			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 未找到该抽奖"})
+		case errors.Is(err, service.ErrPermissionDenied):
+			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 您不是该抽奖的组织者"})
+		default:
+			logger.ErrorContext(ctx, "failed to authorize export", "lottery_id", lotteryID, "error", err)
+			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 导出失败, 请稍后重试"})
+		}
+		return
+	}
+
+	if what == "" {
+		what = service.ExportParticipants
+		if lottery.Status == "completed" {
+			what = service.ExportWinners
+		}
+	}
+
+	// The export is written into a pipe that the upload reads from. Closing
+	// the reader afterwards unblocks the writer if the upload stopped early.
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(lotteryService.Export(pw, lotteryID, format, what))
+	}()
+
+	heading := "👥 参与名单"
+	if what == service.ExportWinners {
+		heading = "🏆 中奖名单"
+	}
+	caption := fmt.Sprintf("%s\n\n标题: %s\n抽奖 ID: <code>%s</code>", heading, html.EscapeString(lottery.Title), lotteryID)
+
+	_, err = b.SendDocument(ctx, &bot.SendDocumentParams{
+		ChatID:    chatID,
+		Document:  &tgmodels.InputFileUpload{Filename: service.ExportFilename(lotteryID, format, what), Data: pr},
+		Caption:   caption,
+		ParseMode: tgmodels.ParseModeHTML,
+	})
+	pr.Close()
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to send export", "lottery_id", lotteryID, "format", format, "what", what, "error", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 导出失败, 请稍后重试"})
+	}
+}
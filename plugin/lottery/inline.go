@@ -0,0 +1,83 @@
+package lottery
+
+import (
+	"context"
+	"fmt"
+	"html"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	dbmodels "github.com/realSunyz/lucky-tgbot/pkg/models"
+)
+
+const inlineCacheTime = 10
+
+func IsInlineQuery(update *tgmodels.Update) bool {
+	return update.InlineQuery != nil
+}
+
+func HandleInlineQuery(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if lotteryService == nil {
+		logger.ErrorContext(ctx, "lottery service is not initialized")
+		return
+	}
+
+	query := update.InlineQuery
+	if query == nil || query.From == nil {
+		return
+	}
+
+	snapshots, err := lotteryService.SearchLotteries(query.From.ID, query.Query)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to search lotteries for inline query", "error", err)
+		return
+	}
+
+	results := make([]tgmodels.InlineQueryResult, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		result := &tgmodels.InlineQueryResultArticle{
+			ID:          snapshot.Lottery.ID,
+			Title:       snapshot.Lottery.Title,
+			Description: fmt.Sprintf("抽奖 ID: %s | %s", snapshot.Lottery.ID, formatDrawInfo(snapshot.Lottery)),
+			InputMessageContent: &tgmodels.InputTextMessageContent{
+				MessageText: formatInlineMessage(snapshot.Lottery, snapshot.Prizes),
+				ParseMode:   tgmodels.ParseModeHTML,
+			},
+			ReplyMarkup: buildJoinMarkup(snapshot.Lottery),
+		}
+		results = append(results, result)
+	}
+
+	_, err = b.AnswerInlineQuery(ctx, &bot.AnswerInlineQueryParams{
+		InlineQueryID: query.ID,
+		Results:       results,
+		CacheTime:     inlineCacheTime,
+		IsPersonal:    true,
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to answer inline query", "error", err)
+	}
+}
+
+func formatInlineMessage(lottery *dbmodels.Lottery, prizes []dbmodels.Prize) string {
+	return fmt.Sprintf("抽奖 ID: <code>%s</code>\n抽奖标题: %s\n奖品内容:\n%s\n开奖方式: %s\n\n服务条款及更多详情请前往网页端查看:\n%s/lottery/%s",
+		lottery.ID, html.EscapeString(lottery.Title), formatPrizeLines(prizes), formatDrawInfo(lottery), getWebDomain(), lottery.ID)
+}
+
+func formatDrawInfo(lottery *dbmodels.Lottery) string {
+	switch lottery.DrawMode {
+	case "timed":
+		if lottery.DrawTime != nil {
+			return fmt.Sprintf("%s 定时开奖", lottery.DrawTime.Local().Format("2006-01-02 15:04"))
+		}
+		return "定时开奖"
+	case "full":
+		if lottery.MaxEntries != nil {
+			return fmt.Sprintf("满 %d 人开奖", *lottery.MaxEntries)
+		}
+		return "满人开奖"
+	default:
+		return "手动开奖"
+	}
+}
@@ -0,0 +1,91 @@
+package lottery
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	"github.com/realSunyz/lucky-tgbot/pkg/service"
+)
+
+// joinedListLimit keeps /joined well below Telegram's message length limit.
+const joinedListLimit = 15
+
+func HandleJoinedCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if lotteryService == nil {
+		logger.ErrorContext(ctx, "lottery service is not initialized")
+		return
+	}
+
+	if update.Message == nil {
+		return
+	}
+
+	if update.Message.Chat.Type != "private" {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ 请在私聊中使用此命令",
+		})
+		return
+	}
+
+	entries, err := lotteryService.GetUserEntries(update.Message.From.ID)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to get user entries", "error", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: "❌ 获取参与记录失败, 请稍后重试"})
+		return
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    update.Message.Chat.ID,
+		Text:      formatUserEntries(entries),
+		ParseMode: tgmodels.ParseModeHTML,
+	})
+}
+
+func formatUserEntries(entries *service.UserEntries) string {
+	if len(entries.Active) == 0 && len(entries.Wins) == 0 {
+		return "📭 您还没有参与过抽奖"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🎟 进行中的抽奖:\n")
+	if len(entries.Active) == 0 {
+		sb.WriteString("暂无\n")
+	}
+	for i := range entries.Active {
+		if i == joinedListLimit {
+			sb.WriteString(fmt.Sprintf("\n… 另有%s %d 个进行中的抽奖\n", atLeast(entries.MoreActive), len(entries.Active)-joinedListLimit))
+			break
+		}
+		entry := &entries.Active[i]
+		sb.WriteString(fmt.Sprintf("\n%d. %s\n   ID: <code>%s</code> | %d 人参与\n   开奖: %s\n",
+			i+1, html.EscapeString(entry.Title), entry.ID, entry.Participants, formatDrawInfo(&entry.Lottery)))
+	}
+
+	sb.WriteString("\n🏆 中奖记录:\n")
+	if len(entries.Wins) == 0 {
+		sb.WriteString("暂无\n")
+	}
+	for i, win := range entries.Wins {
+		if i == joinedListLimit {
+			sb.WriteString(fmt.Sprintf("\n… 另有%s %d 条中奖记录\n", atLeast(entries.MoreWins), len(entries.Wins)-joinedListLimit))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("\n%d. %s\n   ID: <code>%s</code> | 奖品: %s\n", i+1, html.EscapeString(win.Title), win.LotteryID, html.EscapeString(win.PrizeName)))
+	}
+
+	return sb.String()
+}
+
+// atLeast qualifies the count of a list the service cut short.
+func atLeast(more bool) string {
+	if more {
+		return "至少"
+	}
+	return ""
+}
@@ -4,24 +4,33 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
+	"html"
 	"strings"
-	"time"
 
 	"github.com/go-telegram/bot"
 	tgmodels "github.com/go-telegram/bot/models"
-	"github.com/realSunyz/lucky-tgbot/pkg/database"
+	"github.com/realSunyz/lucky-tgbot/pkg/config"
 	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	"github.com/realSunyz/lucky-tgbot/pkg/metrics"
 	dbmodels "github.com/realSunyz/lucky-tgbot/pkg/models"
 	"github.com/realSunyz/lucky-tgbot/pkg/service"
 )
 
-var lotteryService *service.LotteryService
+const JoinCallbackPrefix = "join_"
+
+var (
+	lotteryService *service.LotteryService
+	botConfig      = config.Default()
+)
 
 func SetService(svc *service.LotteryService) {
 	lotteryService = svc
 }
 
+func SetConfig(cfg *config.Config) {
+	botConfig = cfg
+}
+
 type TelegramNotifier struct {
 	bot *bot.Bot
 }
@@ -34,17 +43,42 @@ func (n *TelegramNotifier) LotteryCreated(lottery *dbmodels.Lottery, prizes []db
 	sendLotteryCreatedMessage(context.Background(), n.bot, lottery, prizes)
 }
 
-func (n *TelegramNotifier) WinnersDrawn(lottery *dbmodels.Lottery, winners []dbmodels.Winner) {
-	sendWinnerNotification(context.Background(), n.bot, lottery, winners)
+func (n *TelegramNotifier) WinnersDrawn(lottery *dbmodels.Lottery, prizes []dbmodels.Prize, winners []dbmodels.Winner) {
+	sendWinnerNotification(context.Background(), n.bot, lottery, prizes, winners)
+}
+
+// FillImportedProfile is a bot middleware that fills in the names of
+// participants who were imported before the bot had seen them. Only private
+// messages and button presses are looked at, which is where participants
+// talk to the bot, so group chatter does not reach the database.
+func FillImportedProfile(next bot.HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		var user *tgmodels.User
+		switch {
+		case update.Message != nil && update.Message.Chat.Type == "private":
+			user = update.Message.From
+		case update.CallbackQuery != nil:
+			user = &update.CallbackQuery.From
+		}
+		if lotteryService != nil && user != nil && !user.IsBot {
+			lotteryService.FillParticipantProfile(service.JoinInput{
+				UserID:    user.ID,
+				Username:  user.Username,
+				FirstName: user.FirstName,
+				LastName:  user.LastName,
+			})
+		}
+		next(ctx, b, update)
+	}
 }
 
 func getWebDomain() string {
-	return strings.TrimSuffix(os.Getenv("WEB_DOMAIN"), "/")
+	return botConfig.Server.WebDomain
 }
 
 func HandleLotteryCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
 	if lotteryService == nil {
-		logger.Errorf("lottery service is not initialized")
+		logger.ErrorContext(ctx, "lottery service is not initialized")
 		return
 	}
 
@@ -66,15 +100,20 @@ func HandleLotteryCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Upda
 		case errors.Is(err, service.ErrCreateTooFrequent):
 			b.SendMessage(ctx, &bot.SendMessageParams{
 				ChatID: update.Message.Chat.ID,
-				Text:   "⚠️ 创建过于频繁, 请 1 分钟后再试",
+				Text:   fmt.Sprintf("⚠️ 创建过于频繁, 请 %s后再试", formatDuration(botConfig.Lottery.CreateCooldown)),
 			})
 		case errors.Is(err, service.ErrCreateDailyLimit):
 			b.SendMessage(ctx, &bot.SendMessageParams{
 				ChatID: update.Message.Chat.ID,
 				Text:   "⚠️ 今日抽奖创建次数已达上限",
 			})
+		case errors.Is(err, service.ErrUserBanned):
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "❌ 您已被禁止创建抽奖",
+			})
 		default:
-			logger.Errorf("failed to create draft lottery: %v", err)
+			logger.ErrorContext(ctx, "failed to create draft lottery", "error", err)
 			b.SendMessage(ctx, &bot.SendMessageParams{
 				ChatID: update.Message.Chat.ID,
 				Text:   "❌ 创建抽奖失败, 请稍后重试",
@@ -82,10 +121,11 @@ func HandleLotteryCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Upda
 		}
 		return
 	}
-	logger.Infof("user %d created lottery %s", update.Message.From.ID, lottery.ID)
+	logger.InfoContext(ctx, "lottery created", "lottery_id", lottery.ID)
 
-	createLink := fmt.Sprintf("%s/create/%s", getWebDomain(), lottery.ID)
-	message := fmt.Sprintf("✅ 新抽奖创建成功\n\n请在 30 分钟内点击下方链接完成抽奖设置:\n%s", createLink)
+	createToken := lotteryService.IssueCreateToken(lottery.ID, update.Message.From.ID)
+	createLink := fmt.Sprintf("%s/create/%s?token=%s", getWebDomain(), lottery.ID, createToken)
+	message := fmt.Sprintf("✅ 新抽奖创建成功\n\n请在 %s内点击下方链接完成抽奖设置:\n%s", formatDuration(botConfig.Lottery.CreateTokenTTL), createLink)
 
 	_, sendErr := b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:    update.Message.Chat.ID,
@@ -93,13 +133,13 @@ func HandleLotteryCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Upda
 		ParseMode: tgmodels.ParseModeHTML,
 	})
 	if sendErr != nil {
-		logger.Errorf("failed to send create message: %v", sendErr)
+		logger.ErrorContext(ctx, "failed to send create message", "lottery_id", lottery.ID, "error", sendErr)
 	}
 }
 
 func HandleEditCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
 	if lotteryService == nil {
-		logger.Errorf("lottery service is not initialized")
+		logger.ErrorContext(ctx, "lottery service is not initialized")
 		return
 	}
 
@@ -120,29 +160,34 @@ func HandleEditCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Update)
 	if len(parts) < 2 {
 		b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:    update.Message.Chat.ID,
-			Text:      "❌ 请提供抽奖 ID\n\n用法: <code>/edit 123456</code>",
+			Text:      "❌ 请提供抽奖 ID\n\n用法: <code>/edit 123456 [会话名称]</code>",
 			ParseMode: tgmodels.ParseModeHTML,
 		})
 		return
 	}
 
-	lotteryID := parts[1]
-	token, lottery, err := lotteryService.CreateEditToken(lotteryID, update.Message.From.ID, time.Hour)
+	sendEditLink(ctx, b, update.Message.Chat.ID, update.Message.From.ID, parts[1], strings.Join(parts[2:], " "))
+}
+
+// sendEditLink opens a new edit session for the user and sends its one-time
+// link to the chat.
+func sendEditLink(ctx context.Context, b *bot.Bot, chatID int64, userID int64, lotteryID string, sessionName string) {
+	code, lottery, err := lotteryService.CreateEditSession(lotteryID, userID, sessionName)
 	if err != nil {
 		switch {
 		case errors.Is(err, service.ErrLotteryNotFound):
-			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: "❌ 未找到该抽奖"})
+			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 未找到该抽奖"})
 		case errors.Is(err, service.ErrPermissionDenied):
-			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: "❌ 您不是该抽奖的创建者"})
+			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 您不是该抽奖的组织者"})
 		default:
-			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: "❌ 生成编辑链接失败, 请稍后重试"})
+			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 生成编辑链接失败, 请稍后重试"})
 		}
 		return
 	}
 
 	if lottery.Status == "draft" {
 		b.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID: update.Message.Chat.ID,
+			ChatID: chatID,
 			Text:   "❌ 该抽奖尚未发布",
 		})
 		return
@@ -150,17 +195,17 @@ func HandleEditCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Update)
 
 	if lottery.Status == "completed" {
 		b.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID: update.Message.Chat.ID,
+			ChatID: chatID,
 			Text:   "❌ 该抽奖已结束",
 		})
 		return
 	}
 
-	editLink := fmt.Sprintf("%s/edit/%s?token=%s", getWebDomain(), lotteryID, token)
-	message := fmt.Sprintf("✏️ 编辑抽奖\n\n抽奖 ID: <code>%s</code>\n标题: %s\n\n编辑链接有效期 1 小时:\n%s", lotteryID, lottery.Title, editLink)
+	editLink := fmt.Sprintf("%s/edit/%s?code=%s", getWebDomain(), lotteryID, code)
+	message := fmt.Sprintf("✏️ 编辑抽奖\n\n抽奖 ID: <code>%s</code>\n标题: %s\n\n编辑链接仅可打开一次, 会话有效期 %s:\n%s\n\n使用 <code>/sessions %s</code> 查看或撤销编辑会话", lotteryID, html.EscapeString(lottery.Title), formatDuration(botConfig.Lottery.EditSessionTTL), editLink, lotteryID)
 
 	b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:    update.Message.Chat.ID,
+		ChatID:    chatID,
 		Text:      message,
 		ParseMode: tgmodels.ParseModeHTML,
 	})
@@ -168,7 +213,7 @@ func HandleEditCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Update)
 
 func HandleDeleteCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
 	if lotteryService == nil {
-		logger.Errorf("lottery service is not initialized")
+		logger.ErrorContext(ctx, "lottery service is not initialized")
 		return
 	}
 
@@ -198,16 +243,7 @@ func HandleDeleteCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Updat
 	lotteryID := parts[1]
 	err := lotteryService.DeleteLottery(lotteryID, update.Message.From.ID)
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrLotteryNotFound):
-			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: "❌ 未找到该抽奖"})
-		case errors.Is(err, service.ErrPermissionDenied):
-			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: "❌ 您不是该抽奖的创建者"})
-		case errors.Is(err, service.ErrLotteryCannotDelete):
-			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: "❌ 只有处于草稿或进行中的抽奖可以被删除"})
-		default:
-			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: "❌ 删除抽奖失败, 请稍后重试"})
-		}
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: deleteErrorText(ctx, lotteryID, err)})
 		return
 	}
 
@@ -218,6 +254,20 @@ func HandleDeleteCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Updat
 	})
 }
 
+func deleteErrorText(ctx context.Context, lotteryID string, err error) string {
+	switch {
+	case errors.Is(err, service.ErrLotteryNotFound):
+		return "❌ 未找到该抽奖"
+	case errors.Is(err, service.ErrPermissionDenied):
+		return "❌ 您不是该抽奖的所有者"
+	case errors.Is(err, service.ErrLotteryCannotDelete):
+		return "❌ 只有处于草稿或进行中的抽奖可以被删除"
+	default:
+		logger.ErrorContext(ctx, "failed to delete lottery", "lottery_id", lotteryID, "error", err)
+		return "❌ 删除抽奖失败, 请稍后重试"
+	}
+}
+
 func HandleStartCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
 	if update.Message == nil {
 		return
@@ -246,6 +296,39 @@ func handleJoin(ctx context.Context, b *bot.Bot, update *tgmodels.Update, lotter
 	}
 
 	user := update.Message.From
+	lottery, _, err := lotteryService.JoinLottery(lotteryID, service.JoinInput{
+		UserID:    user.ID,
+		Username:  user.Username,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+	})
+	// A user joined from a group the bot could not message yet lands here
+	// already entered, and gets the confirmation they missed
+	if err != nil && !(errors.Is(err, service.ErrParticipantExists) && lottery != nil) {
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: joinErrorText(ctx, lotteryID, lottery, err)})
+		return
+	}
+
+	b.SendMessage(ctx, joinSuccessMessage(update.Message.Chat.ID, lottery))
+}
+
+// HandleJoinCallback joins the lottery straight from the group the button was
+// pressed in. When the confirmation cannot be sent privately, the user is sent
+// to the private chat, since winner notifications are delivered there too.
+func HandleJoinCallback(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if lotteryService == nil {
+		logger.ErrorContext(ctx, "lottery service is not initialized")
+		return
+	}
+
+	query := update.CallbackQuery
+	if query == nil {
+		return
+	}
+
+	lotteryID := strings.TrimPrefix(query.Data, JoinCallbackPrefix)
+	user := query.From
+
 	lottery, _, err := lotteryService.JoinLottery(lotteryID, service.JoinInput{
 		UserID:    user.ID,
 		Username:  user.Username,
@@ -253,86 +336,130 @@ func handleJoin(ctx context.Context, b *bot.Bot, update *tgmodels.Update, lotter
 		LastName:  user.LastName,
 	})
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrLotteryNotFound):
-			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: "❌ 找不到该抽奖"})
-		case errors.Is(err, service.ErrLotteryNotActive):
-			msg := "❌ 无效的抽奖 ID, 请稍后再试"
-			if lottery != nil {
-				switch lottery.Status {
-				case "completed":
-					msg = "❌ 该抽奖已结束"
-				case "draft":
-					msg = "❌ 该抽奖尚未发布"
-				}
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            joinErrorText(ctx, lotteryID, lottery, err),
+			ShowAlert:       true,
+		})
+		return
+	}
+
+	// Telegram refuses private messages until the user has started the bot or
+	// after they blocked it
+	if _, err := b.SendMessage(ctx, joinSuccessMessage(user.ID, lottery)); err != nil {
+		if errors.Is(err, bot.ErrorForbidden) {
+			if link := joinDeepLink(ctx, b, lotteryID); link != "" {
+				b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID, URL: link})
+				return
 			}
-			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: msg})
-		case errors.Is(err, service.ErrLotteryFull):
-			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: "❌ 该抽奖名额已满"})
-		case errors.Is(err, service.ErrParticipantExists):
-			b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:    update.Message.Chat.ID,
-				Text:      fmt.Sprintf("⚠️ 您已参与抽奖 <code>%s</code>, 请勿重复点击", lotteryID),
-				ParseMode: tgmodels.ParseModeHTML,
-			})
-		default:
-			logger.Errorf("failed to join lottery %s: %v", lotteryID, err)
-			b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: "❌ 参与失败, 请稍后重试"})
 		}
-		return
+		logger.WarnContext(ctx, "failed to send join confirmation", "lottery_id", lotteryID, "user_id", user.ID, "error", err)
 	}
 
-	b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID: update.Message.Chat.ID,
+	b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: query.ID,
+		Text:            fmt.Sprintf("✅ 已成功参加抽奖 %s", lottery.Title),
+	})
+}
+
+func joinErrorText(ctx context.Context, lotteryID string, lottery *dbmodels.Lottery, err error) string {
+	switch {
+	case errors.Is(err, service.ErrLotteryNotFound):
+		return "❌ 找不到该抽奖"
+	case errors.Is(err, service.ErrLotteryNotActive):
+		if lottery != nil {
+			switch lottery.Status {
+			case "completed":
+				return "❌ 该抽奖已结束"
+			case "draft":
+				return "❌ 该抽奖尚未发布"
+			}
+		}
+		return "❌ 无效的抽奖 ID, 请稍后再试"
+	case errors.Is(err, service.ErrLotteryFull):
+		return "❌ 该抽奖名额已满"
+	case errors.Is(err, service.ErrParticipantExists):
+		return fmt.Sprintf("⚠️ 您已参与抽奖 %s, 请勿重复点击", lotteryID)
+	case errors.Is(err, service.ErrUserBanned):
+		return "❌ 您已被禁止参与抽奖"
+	default:
+		logger.ErrorContext(ctx, "failed to join lottery", "lottery_id", lotteryID, "error", err)
+		return "❌ 参与失败, 请稍后重试"
+	}
+}
+
+func joinSuccessMessage(chatID int64, lottery *dbmodels.Lottery) *bot.SendMessageParams {
+	return &bot.SendMessageParams{
+		ChatID: chatID,
 		Text: fmt.Sprintf("✅ 参加抽奖成功\n\n抽奖 ID: <code>%s</code>\n抽奖标题: %s\n\n更多详情请前往网页端查看:\n%s/lottery/%s",
-			lottery.ID, lottery.Title, getWebDomain(), lottery.ID),
+			lottery.ID, html.EscapeString(lottery.Title), getWebDomain(), lottery.ID),
 		ParseMode: tgmodels.ParseModeHTML,
-	})
+	}
 }
 
-func sendLotteryCreatedMessage(ctx context.Context, b *bot.Bot, lottery *dbmodels.Lottery, prizes []dbmodels.Prize) {
-	if b == nil {
-		return
+func joinDeepLink(ctx context.Context, b *bot.Bot, lotteryID string) string {
+	botUser, err := b.GetMe(ctx)
+	if err != nil || botUser.Username == "" {
+		return ""
 	}
+	return fmt.Sprintf("https://t.me/%s?start=join_%s", botUser.Username, lotteryID)
+}
 
+func formatPrizeLines(prizes []dbmodels.Prize) string {
 	var prizeLines []string
 	for _, p := range prizes {
-		prizeLines = append(prizeLines, fmt.Sprintf("- %s × %d", p.Name, p.Quantity))
+		prizeLines = append(prizeLines, fmt.Sprintf("- %s × %d", html.EscapeString(p.Name), p.Quantity))
 	}
-	prizesText := strings.Join(prizeLines, "\n")
-	lotteryLink := fmt.Sprintf("%s/lottery/%s", getWebDomain(), lottery.ID)
-	message := fmt.Sprintf("抽奖 ID: <code>%s</code>\n抽奖标题: %s\n奖品内容:\n%s\n\n服务条款及更多详情请前往网页端查看:\n%s", lottery.ID, lottery.Title, prizesText, lotteryLink)
+	return strings.Join(prizeLines, "\n")
+}
 
-	botUser, err := b.GetMe(ctx)
-	botUsername := ""
-	if err == nil {
-		botUsername = botUser.Username
+func formatWinnerLines(winners []dbmodels.Winner) string {
+	var winnerLines []string
+	for _, w := range winners {
+		winnerLines = append(winnerLines, fmt.Sprintf("- <a href=\"tg://user?id=%d\">%d</a> 获得了 \"%s\"", w.UserID, w.UserID, html.EscapeString(w.PrizeName)))
 	}
+	return strings.Join(winnerLines, "\n")
+}
 
-	var joinButton tgmodels.InlineKeyboardButton
-	if botUsername != "" {
-		deepLink := fmt.Sprintf("https://t.me/%s?start=join_%s", botUsername, lottery.ID)
-		joinButton = tgmodels.InlineKeyboardButton{Text: ">>> 点击参与 <<<", URL: deepLink}
-	} else if strings.HasPrefix(getWebDomain(), "https://") {
-		joinButton = tgmodels.InlineKeyboardButton{Text: ">>> 点击参与 <<<", URL: lotteryLink}
+func buildJoinMarkup(lottery *dbmodels.Lottery) *tgmodels.InlineKeyboardMarkup {
+	joinButton := tgmodels.InlineKeyboardButton{Text: ">>> 点击参与 <<<", CallbackData: JoinCallbackPrefix + lottery.ID}
+	return &tgmodels.InlineKeyboardMarkup{InlineKeyboard: [][]tgmodels.InlineKeyboardButton{{joinButton}}}
+}
+
+func sendLotteryCreatedMessage(ctx context.Context, b *bot.Bot, lottery *dbmodels.Lottery, prizes []dbmodels.Prize) {
+	if b == nil {
+		return
 	}
 
-	params := &bot.SendMessageParams{ChatID: lottery.CreatorID, Text: message, ParseMode: tgmodels.ParseModeHTML}
-	if joinButton.Text != "" {
-		params.ReplyMarkup = &tgmodels.InlineKeyboardMarkup{InlineKeyboard: [][]tgmodels.InlineKeyboardButton{{joinButton}}}
+	lotteryLink := fmt.Sprintf("%s/lottery/%s", getWebDomain(), lottery.ID)
+	message := fmt.Sprintf("抽奖 ID: <code>%s</code>\n抽奖标题: %s\n奖品内容:\n%s\n\n服务条款及更多详情请前往网页端查看:\n%s", lottery.ID, html.EscapeString(lottery.Title), formatPrizeLines(prizes), lotteryLink)
+
+	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      lottery.CreatorID,
+		Text:        message,
+		ParseMode:   tgmodels.ParseModeHTML,
+		ReplyMarkup: buildJoinMarkup(lottery),
+	})
+	if err != nil {
+		notifySendFailed(ctx, "lottery_created", lottery.ID, lottery.CreatorID, err)
 	}
-	b.SendMessage(ctx, params)
 }
 
-func sendWinnerNotification(ctx context.Context, b *bot.Bot, lottery *dbmodels.Lottery, winners []dbmodels.Winner) {
-	if b == nil || lottery == nil || len(winners) == 0 {
+func sendWinnerNotification(ctx context.Context, b *bot.Bot, lottery *dbmodels.Lottery, prizes []dbmodels.Prize, winners []dbmodels.Winner) {
+	if b == nil || lottery == nil {
+		return
+	}
+
+	updateAnnouncementsWithWinners(ctx, b, lottery, winners)
+
+	if len(winners) == 0 {
 		return
 	}
 
 	resultLink := fmt.Sprintf("%s/lottery/%s", getWebDomain(), lottery.ID)
 	userWins := make(map[int64][]string)
 	for _, w := range winners {
-		userWins[w.UserID] = append(userWins[w.UserID], w.PrizeName)
+		userWins[w.UserID] = append(userWins[w.UserID], html.EscapeString(w.PrizeName))
 	}
 
 	creatorName := "发起者"
@@ -340,46 +467,49 @@ func sendWinnerNotification(ctx context.Context, b *bot.Bot, lottery *dbmodels.L
 		if chat.Username != "" {
 			creatorName = "@" + chat.Username
 		} else if chat.FirstName != "" {
-			creatorName = chat.FirstName
+			creatorName = html.EscapeString(chat.FirstName)
 		}
 	}
 
 	for userID, prizes := range userWins {
 		prizeText := strings.Join(prizes, ", ")
 		message := fmt.Sprintf("🎉 中奖通知\n\n恭喜您在抽奖活动 %s 中获奖\n获得奖品: %s\n\n请及时联系发起者 <a href=\"tg://user?id=%d\">%s</a> 领取奖品",
-			lottery.Title, prizeText, lottery.CreatorID, creatorName)
-		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: userID, Text: message, ParseMode: tgmodels.ParseModeHTML})
+			html.EscapeString(lottery.Title), prizeText, lottery.CreatorID, creatorName)
+		if _, err := b.SendMessage(ctx, &bot.SendMessageParams{ChatID: userID, Text: message, ParseMode: tgmodels.ParseModeHTML}); err != nil {
+			notifySendFailed(ctx, "winner", lottery.ID, userID, err)
+		}
 	}
 
-	var winnerLines []string
+	failedPrizesText := ""
+	winnerCountByPrizeID := make(map[int64]int)
 	for _, w := range winners {
-		winnerLines = append(winnerLines, fmt.Sprintf("- <a href=\"tg://user?id=%d\">%d</a> 获得了 \"%s\"", w.UserID, w.UserID, w.PrizeName))
+		winnerCountByPrizeID[w.PrizeID]++
 	}
-	failedPrizesText := ""
-	prizes, prizeErr := database.GetPrizes(lottery.ID)
-	if prizeErr == nil {
-		winnerCountByPrizeID := make(map[int64]int)
-		for _, w := range winners {
-			winnerCountByPrizeID[w.PrizeID]++
-		}
 
-		var failedPrizeLines []string
-		for _, prize := range prizes {
-			failedCount := prize.Quantity - winnerCountByPrizeID[prize.ID]
-			if failedCount > 0 {
-				failedPrizeLines = append(failedPrizeLines, fmt.Sprintf("- %s × %d", prize.Name, failedCount))
-			}
-		}
-		if len(failedPrizeLines) > 0 {
-			failedPrizesText = "\n流标奖品:\n" + strings.Join(failedPrizeLines, "\n")
+	var failedPrizes []dbmodels.Prize
+	for _, prize := range prizes {
+		if failedCount := prize.Quantity - winnerCountByPrizeID[prize.ID]; failedCount > 0 {
+			prize.Quantity = failedCount
+			failedPrizes = append(failedPrizes, prize)
 		}
 	}
+	if len(failedPrizes) > 0 {
+		failedPrizesText = "\n流标奖品:\n" + formatPrizeLines(failedPrizes)
+	}
 
 	creatorMessage := fmt.Sprintf("🎊 开奖已完成\n\n抽奖 ID: <code>%s</code>\n抽奖标题: %s\n中奖用户列表:\n%s%s\n\n更多详情请前往网页端查看:\n%s",
-		lottery.ID, lottery.Title, strings.Join(winnerLines, "\n"), failedPrizesText, resultLink)
-	b.SendMessage(ctx, &bot.SendMessageParams{
+		lottery.ID, html.EscapeString(lottery.Title), formatWinnerLines(winners), failedPrizesText, resultLink)
+	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:    lottery.CreatorID,
 		Text:      creatorMessage,
 		ParseMode: tgmodels.ParseModeHTML,
 	})
+	if err != nil {
+		notifySendFailed(ctx, "draw_summary", lottery.ID, lottery.CreatorID, err)
+	}
+}
+
+func notifySendFailed(ctx context.Context, notification string, lotteryID string, chatID int64, err error) {
+	metrics.NotifierSendErrors.WithLabelValues(notification).Inc()
+	logger.WarnContext(ctx, "failed to send notification", "notification", notification, "lottery_id", lotteryID, "chat_id", chatID, "error", err)
 }
@@ -0,0 +1,219 @@
+package lottery
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	dbmodels "github.com/realSunyz/lucky-tgbot/pkg/models"
+)
+
+const (
+	MyLotteriesCallbackPrefix = "my_"
+
+	myLotteriesPageSize = 5
+)
+
+var statusNames = map[string]string{
+	"draft":     "草稿",
+	"active":    "进行中",
+	"completed": "已开奖",
+}
+
+func HandleMyLotteriesCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if lotteryService == nil {
+		logger.ErrorContext(ctx, "lottery service is not initialized")
+		return
+	}
+
+	if update.Message == nil {
+		return
+	}
+
+	if update.Message.Chat.Type != "private" {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ 请在私聊中使用此命令",
+		})
+		return
+	}
+
+	text, markup, err := renderMyLotteries(update.Message.From.ID, 0)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list creator lotteries", "error", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: "❌ 获取抽奖列表失败, 请稍后重试"})
+		return
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      update.Message.Chat.ID,
+		Text:        text,
+		ParseMode:   tgmodels.ParseModeHTML,
+		ReplyMarkup: markup,
+	})
+}
+
+// HandleMyLotteriesCallback handles paging and the quick-action buttons of
+// the /mylotteries list. Callback data is "my_<action>_<argument>".
+func HandleMyLotteriesCallback(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if lotteryService == nil {
+		logger.ErrorContext(ctx, "lottery service is not initialized")
+		return
+	}
+
+	query := update.CallbackQuery
+	if query == nil {
+		return
+	}
+
+	action, arg, _ := strings.Cut(strings.TrimPrefix(query.Data, MyLotteriesCallbackPrefix), "_")
+	message := query.Message.Message
+	userID := query.From.ID
+
+	switch action {
+	case "page":
+		page, err := strconv.Atoi(arg)
+		if err != nil || message == nil {
+			b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID})
+			return
+		}
+		text, markup, err := renderMyLotteries(userID, page)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to list creator lotteries", "page", page, "error", err)
+			b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID, Text: "❌ 获取抽奖列表失败, 请稍后重试"})
+			return
+		}
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID})
+		b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:      message.Chat.ID,
+			MessageID:   message.ID,
+			Text:        text,
+			ParseMode:   tgmodels.ParseModeHTML,
+			ReplyMarkup: markup,
+		})
+	case "edit":
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID})
+		sendEditLink(ctx, b, userID, userID, arg, "")
+	case "draw":
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID})
+		sendDrawConfirmation(ctx, b, userID, arg)
+	case "delete":
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID})
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    userID,
+			Text:      fmt.Sprintf("⚠️ 确定要取消并删除抽奖 <code>%s</code> 吗? 此操作无法撤销", arg),
+			ParseMode: tgmodels.ParseModeHTML,
+			ReplyMarkup: &tgmodels.InlineKeyboardMarkup{InlineKeyboard: [][]tgmodels.InlineKeyboardButton{{
+				{Text: "✅ 确认删除", CallbackData: MyLotteriesCallbackPrefix + "deleteok_" + arg},
+				{Text: "返回", CallbackData: MyLotteriesCallbackPrefix + "dismiss_" + arg},
+			}}},
+		})
+	case "deleteok":
+		if err := lotteryService.DeleteLottery(arg, userID); err != nil {
+			b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+				CallbackQueryID: query.ID,
+				Text:            deleteErrorText(ctx, arg, err),
+				ShowAlert:       true,
+			})
+			return
+		}
+		logger.InfoContext(ctx, "lottery deleted", "lottery_id", arg, "via", "/mylotteries")
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID})
+		editCallbackMessage(ctx, b, message, fmt.Sprintf("🗑 抽奖 <code>%s</code> 已成功删除", arg))
+	case "dismiss":
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID})
+		if message != nil {
+			b.DeleteMessage(ctx, &bot.DeleteMessageParams{ChatID: message.Chat.ID, MessageID: message.ID})
+		}
+	default:
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID, Text: "❌ 无效的操作"})
+	}
+}
+
+func editCallbackMessage(ctx context.Context, b *bot.Bot, message *tgmodels.Message, text string) {
+	if message == nil {
+		return
+	}
+	_, err := b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    message.Chat.ID,
+		MessageID: message.ID,
+		Text:      text,
+		ParseMode: tgmodels.ParseModeHTML,
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to edit callback message", "error", err)
+	}
+}
+
+func renderMyLotteries(userID int64, page int) (string, *tgmodels.InlineKeyboardMarkup, error) {
+	lotteries, pages, err := lotteryService.ListCreatorLotteries(userID, page, myLotteriesPageSize)
+	if err != nil {
+		return "", nil, err
+	}
+	if pages == 0 {
+		return "📭 您还没有创建过抽奖\n\n使用 /create 创建一个新的抽奖", &tgmodels.InlineKeyboardMarkup{InlineKeyboard: [][]tgmodels.InlineKeyboardButton{}}, nil
+	}
+	if page >= pages {
+		page = pages - 1
+		lotteries, _, err = lotteryService.ListCreatorLotteries(userID, page, myLotteriesPageSize)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📋 我创建的抽奖 (第 %d/%d 页)\n", page+1, pages))
+
+	rows := make([][]tgmodels.InlineKeyboardButton, 0, len(lotteries)+1)
+	for i := range lotteries {
+		lottery := &lotteries[i]
+		n := page*myLotteriesPageSize + i + 1
+		sb.WriteString(fmt.Sprintf("\n%d. %s\n   ID: <code>%s</code> | %s | %d 人参与\n   开奖: %s\n",
+			n, html.EscapeString(lottery.Title), lottery.ID, statusNames[lottery.Status], lottery.Participants, formatDrawInfo(lottery)))
+
+		if row := myLotteryActions(n, lottery); len(row) > 0 {
+			rows = append(rows, row)
+		}
+	}
+
+	var nav []tgmodels.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, tgmodels.InlineKeyboardButton{Text: "⬅️ 上一页", CallbackData: fmt.Sprintf("%spage_%d", MyLotteriesCallbackPrefix, page-1)})
+	}
+	if page+1 < pages {
+		nav = append(nav, tgmodels.InlineKeyboardButton{Text: "下一页 ➡️", CallbackData: fmt.Sprintf("%spage_%d", MyLotteriesCallbackPrefix, page+1)})
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+
+	return sb.String(), &tgmodels.InlineKeyboardMarkup{InlineKeyboard: rows}, nil
+}
+
+// myLotteryActions returns the quick actions that make sense for the
+// lottery's current status, labelled with its position in the list.
+func myLotteryActions(n int, lottery *dbmodels.Lottery) []tgmodels.InlineKeyboardButton {
+	switch lottery.Status {
+	case "active":
+		return []tgmodels.InlineKeyboardButton{
+			{Text: fmt.Sprintf("✏️ 编辑 %d", n), CallbackData: MyLotteriesCallbackPrefix + "edit_" + lottery.ID},
+			{Text: fmt.Sprintf("🎲 开奖 %d", n), CallbackData: MyLotteriesCallbackPrefix + "draw_" + lottery.ID},
+			{Text: fmt.Sprintf("🗑 取消 %d", n), CallbackData: MyLotteriesCallbackPrefix + "delete_" + lottery.ID},
+		}
+	case "draft":
+		return []tgmodels.InlineKeyboardButton{
+			{Text: fmt.Sprintf("🗑 取消 %d", n), CallbackData: MyLotteriesCallbackPrefix + "delete_" + lottery.ID},
+		}
+	case "completed":
+		return []tgmodels.InlineKeyboardButton{
+			{Text: fmt.Sprintf("📊 结果 %d", n), URL: fmt.Sprintf("%s/lottery/%s", getWebDomain(), lottery.ID)},
+		}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,134 @@
+package lottery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	dbmodels "github.com/realSunyz/lucky-tgbot/pkg/models"
+	"github.com/realSunyz/lucky-tgbot/pkg/service"
+)
+
+var roleNames = map[string]string{
+	dbmodels.RoleOwner:     "所有者",
+	dbmodels.RoleEditor:    "编辑者",
+	dbmodels.RoleModerator: "协管员",
+}
+
+func HandleAddAdminCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if lotteryService == nil {
+		logger.ErrorContext(ctx, "lottery service is not initialized")
+		return
+	}
+
+	if update.Message == nil {
+		return
+	}
+
+	if update.Message.Chat.Type != "private" {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ 请在私聊中使用此命令",
+		})
+		return
+	}
+
+	parts := strings.Fields(strings.TrimSpace(update.Message.Text))
+	if len(parts) < 4 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    update.Message.Chat.ID,
+			Text:      "❌ 参数不足\n\n用法: <code>/addadmin 123456 用户ID 角色</code>\n\n角色可选:\n- owner: 所有者, 拥有全部权限\n- editor: 编辑者, 可修改奖品和权重\n- moderator: 协管员, 仅可移除参与者",
+			ParseMode: tgmodels.ParseModeHTML,
+		})
+		return
+	}
+
+	lotteryID := parts[1]
+	userID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: "❌ 无效的用户 ID"})
+		return
+	}
+	role := strings.ToLower(parts[3])
+
+	if err := lotteryService.SetOrganizer(lotteryID, update.Message.From.ID, userID, role); err != nil {
+		sendOrganizerError(ctx, b, update.Message.Chat.ID, lotteryID, err)
+		return
+	}
+	logger.InfoContext(ctx, "organizer set", "lottery_id", lotteryID, "organizer_id", userID, "role", role)
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    update.Message.Chat.ID,
+		Text:      fmt.Sprintf("✅ 已将用户 <code>%d</code> 设为抽奖 <code>%s</code> 的%s\n\n对方可通过 <code>/edit %s</code> 获取编辑链接", userID, lotteryID, roleNames[role], lotteryID),
+		ParseMode: tgmodels.ParseModeHTML,
+	})
+}
+
+func HandleRemoveAdminCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if lotteryService == nil {
+		logger.ErrorContext(ctx, "lottery service is not initialized")
+		return
+	}
+
+	if update.Message == nil {
+		return
+	}
+
+	if update.Message.Chat.Type != "private" {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ 请在私聊中使用此命令",
+		})
+		return
+	}
+
+	parts := strings.Fields(strings.TrimSpace(update.Message.Text))
+	if len(parts) < 3 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    update.Message.Chat.ID,
+			Text:      "❌ 参数不足\n\n用法: <code>/removeadmin 123456 用户ID</code>",
+			ParseMode: tgmodels.ParseModeHTML,
+		})
+		return
+	}
+
+	lotteryID := parts[1]
+	userID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: "❌ 无效的用户 ID"})
+		return
+	}
+
+	if err := lotteryService.RemoveOrganizer(lotteryID, update.Message.From.ID, userID); err != nil {
+		sendOrganizerError(ctx, b, update.Message.Chat.ID, lotteryID, err)
+		return
+	}
+	logger.InfoContext(ctx, "organizer removed", "lottery_id", lotteryID, "organizer_id", userID)
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    update.Message.Chat.ID,
+		Text:      fmt.Sprintf("🗑 已移除用户 <code>%d</code> 在抽奖 <code>%s</code> 中的管理权限", userID, lotteryID),
+		ParseMode: tgmodels.ParseModeHTML,
+	})
+}
+
+func sendOrganizerError(ctx context.Context, b *bot.Bot, chatID int64, lotteryID string, err error) {
+	switch {
+	case errors.Is(err, service.ErrLotteryNotFound):
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 未找到该抽奖"})
+	case errors.Is(err, service.ErrPermissionDenied):
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 只有该抽奖的所有者可以管理组织者"})
+	case errors.Is(err, service.ErrInvalidRole):
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 无效的角色, 可选 owner / editor / moderator"})
+	case errors.Is(err, service.ErrOrganizerIsCreator):
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 创建者始终是该抽奖的所有者"})
+	default:
+		logger.ErrorContext(ctx, "failed to manage organizers", "lottery_id", lotteryID, "error", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 操作失败, 请稍后重试"})
+	}
+}
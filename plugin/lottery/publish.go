@@ -0,0 +1,280 @@
+package lottery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	"github.com/realSunyz/lucky-tgbot/pkg/metrics"
+	dbmodels "github.com/realSunyz/lucky-tgbot/pkg/models"
+	"github.com/realSunyz/lucky-tgbot/pkg/service"
+)
+
+const announcementRefreshInterval = time.Minute
+
+func HandlePublishCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if lotteryService == nil {
+		logger.ErrorContext(ctx, "lottery service is not initialized")
+		return
+	}
+
+	if update.Message == nil {
+		return
+	}
+
+	if update.Message.Chat.Type != "private" {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ 请在私聊中使用此命令",
+		})
+		return
+	}
+
+	parts := strings.Fields(strings.TrimSpace(update.Message.Text))
+	if len(parts) < 2 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    update.Message.Chat.ID,
+			Text:      "❌ 请提供抽奖 ID\n\n用法: <code>/publish 123456</code>",
+			ParseMode: tgmodels.ParseModeHTML,
+		})
+		return
+	}
+
+	lotteryID := parts[1]
+	// The numeric lottery ID doubles as the request ID so the shared chat
+	// can be matched back to the lottery without keeping extra state.
+	requestID, err := strconv.ParseInt(lotteryID, 10, 32)
+	if err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: "❌ 未找到该抽奖"})
+		return
+	}
+
+	snapshot, err := lotteryService.GetPublishableLottery(lotteryID, update.Message.From.ID)
+	if err != nil {
+		sendPublishError(ctx, b, update.Message.Chat.ID, lotteryID, err)
+		return
+	}
+
+	adminRights := &tgmodels.ChatAdministratorRights{}
+	botRights := &tgmodels.ChatAdministratorRights{CanPostMessages: true, CanEditMessages: true}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    update.Message.Chat.ID,
+		Text:      fmt.Sprintf("📢 发布抽奖\n\n抽奖 ID: <code>%s</code>\n抽奖标题: %s\n\n请选择要发布到的群组或频道, 您和机器人都需要是该聊天的管理员", lotteryID, html.EscapeString(snapshot.Lottery.Title)),
+		ParseMode: tgmodels.ParseModeHTML,
+		ReplyMarkup: &tgmodels.ReplyKeyboardMarkup{
+			Keyboard: [][]tgmodels.KeyboardButton{
+				{
+					{
+						Text: "选择群组",
+						RequestChat: &tgmodels.KeyboardButtonRequestChat{
+							RequestID:               int32(requestID),
+							ChatIsChannel:           false,
+							UserAdministratorRights: adminRights,
+							BotAdministratorRights:  adminRights,
+							BotIsMember:             true,
+						},
+					},
+					{
+						Text: "选择频道",
+						RequestChat: &tgmodels.KeyboardButtonRequestChat{
+							RequestID:               int32(requestID),
+							ChatIsChannel:           true,
+							UserAdministratorRights: adminRights,
+							BotAdministratorRights:  botRights,
+							BotIsMember:             true,
+						},
+					},
+				},
+			},
+			ResizeKeyboard:  true,
+			OneTimeKeyboard: true,
+		},
+	})
+}
+
+func HandleChatShared(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if lotteryService == nil {
+		logger.ErrorContext(ctx, "lottery service is not initialized")
+		return
+	}
+
+	if update.Message == nil || update.Message.ChatShared == nil || update.Message.From == nil {
+		return
+	}
+
+	shared := update.Message.ChatShared
+	userID := update.Message.From.ID
+	lotteryID := fmt.Sprintf("%06d", shared.RequestID)
+	removeKeyboard := &tgmodels.ReplyKeyboardRemove{RemoveKeyboard: true}
+
+	snapshot, err := lotteryService.GetPublishableLottery(lotteryID, userID)
+	if err != nil {
+		sendPublishError(ctx, b, update.Message.Chat.ID, lotteryID, err)
+		return
+	}
+
+	if !isChatAdmin(ctx, b, shared.ChatID, userID) || !isChatAdmin(ctx, b, shared.ChatID, b.ID()) {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:      update.Message.Chat.ID,
+			Text:        "❌ 您和机器人都需要是该聊天的管理员",
+			ReplyMarkup: removeKeyboard,
+		})
+		return
+	}
+
+	msg, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      shared.ChatID,
+		Text:        formatAnnouncementMessage(snapshot.Lottery, snapshot.Prizes),
+		ParseMode:   tgmodels.ParseModeHTML,
+		ReplyMarkup: buildJoinMarkup(snapshot.Lottery),
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to publish lottery", "lottery_id", lotteryID, "target_chat_id", shared.ChatID, "error", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:      update.Message.Chat.ID,
+			Text:        "❌ 发布失败, 请检查机器人是否有发送消息的权限",
+			ReplyMarkup: removeKeyboard,
+		})
+		return
+	}
+
+	if err := lotteryService.SaveLotteryMessage(lotteryID, shared.ChatID, msg.ID, snapshot.Lottery.Participants); err != nil {
+		logger.ErrorContext(ctx, "failed to save published message", "lottery_id", lotteryID, "error", err)
+	}
+	logger.InfoContext(ctx, "lottery published", "lottery_id", lotteryID, "target_chat_id", shared.ChatID)
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      update.Message.Chat.ID,
+		Text:        fmt.Sprintf("✅ 抽奖 <code>%s</code> 已发布", lotteryID),
+		ParseMode:   tgmodels.ParseModeHTML,
+		ReplyMarkup: removeKeyboard,
+	})
+}
+
+// StartAnnouncementUpdater periodically refreshes the participant count shown
+// in published announcements.
+func StartAnnouncementUpdater(b *bot.Bot) {
+	go func() {
+		ticker := time.NewTicker(announcementRefreshInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := refreshAnnouncements(context.Background(), b); err != nil {
+				logger.Error("error refreshing announcements", "error", err)
+			}
+		}
+	}()
+}
+
+func refreshAnnouncements(ctx context.Context, b *bot.Bot) error {
+	if lotteryService == nil {
+		return nil
+	}
+
+	messages, err := lotteryService.GetOutdatedLotteryMessages()
+	if err != nil {
+		return err
+	}
+
+	snapshots := make(map[string]*service.LotterySnapshot)
+	for _, m := range messages {
+		snapshot, ok := snapshots[m.LotteryID]
+		if !ok {
+			snapshot, err = lotteryService.GetLotterySnapshot(m.LotteryID)
+			if err != nil {
+				logger.ErrorContext(ctx, "failed to load lottery for announcement", "lottery_id", m.LotteryID, "error", err)
+				continue
+			}
+			snapshots[m.LotteryID] = snapshot
+		}
+
+		_, err := b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:      m.ChatID,
+			MessageID:   m.MessageID,
+			Text:        formatAnnouncementMessage(snapshot.Lottery, snapshot.Prizes),
+			ParseMode:   tgmodels.ParseModeHTML,
+			ReplyMarkup: buildJoinMarkup(snapshot.Lottery),
+		})
+		if err != nil {
+			logger.WarnContext(ctx, "failed to refresh announcement", "lottery_id", m.LotteryID, "chat_id", m.ChatID, "message_id", m.MessageID, "error", err)
+			continue
+		}
+
+		if err := lotteryService.MarkLotteryMessageRendered(m, snapshot.Lottery.Participants); err != nil {
+			logger.ErrorContext(ctx, "failed to mark announcement rendered", "lottery_id", m.LotteryID, "announcement_id", m.ID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+func updateAnnouncementsWithWinners(ctx context.Context, b *bot.Bot, lottery *dbmodels.Lottery, winners []dbmodels.Winner) {
+	if lotteryService == nil {
+		return
+	}
+
+	messages, err := lotteryService.GetLotteryMessages(lottery.ID)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to load announcements", "lottery_id", lottery.ID, "error", err)
+		return
+	}
+
+	winnersText := "暂无中奖用户"
+	if len(winners) > 0 {
+		winnersText = formatWinnerLines(winners)
+	}
+	text := fmt.Sprintf("🎊 开奖已完成\n\n抽奖 ID: <code>%s</code>\n抽奖标题: %s\n中奖用户列表:\n%s\n\n更多详情请前往网页端查看:\n%s/lottery/%s",
+		lottery.ID, html.EscapeString(lottery.Title), winnersText, getWebDomain(), lottery.ID)
+
+	for _, m := range messages {
+		_, err := b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:      m.ChatID,
+			MessageID:   m.MessageID,
+			Text:        text,
+			ParseMode:   tgmodels.ParseModeHTML,
+			ReplyMarkup: &tgmodels.InlineKeyboardMarkup{InlineKeyboard: [][]tgmodels.InlineKeyboardButton{}},
+		})
+		if err != nil {
+			metrics.NotifierSendErrors.WithLabelValues("announcement").Inc()
+			logger.WarnContext(ctx, "failed to update announcement with winners", "lottery_id", lottery.ID, "chat_id", m.ChatID, "message_id", m.MessageID, "error", err)
+		}
+	}
+}
+
+func formatAnnouncementMessage(lottery *dbmodels.Lottery, prizes []dbmodels.Prize) string {
+	return fmt.Sprintf("抽奖 ID: <code>%s</code>\n抽奖标题: %s\n奖品内容:\n%s\n当前参与人数: %d\n\n服务条款及更多详情请前往网页端查看:\n%s/lottery/%s",
+		lottery.ID, html.EscapeString(lottery.Title), formatPrizeLines(prizes), lottery.Participants, getWebDomain(), lottery.ID)
+}
+
+func isChatAdmin(ctx context.Context, b *bot.Bot, chatID int64, userID int64) bool {
+	member, err := b.GetChatMember(ctx, &bot.GetChatMemberParams{ChatID: chatID, UserID: userID})
+	if err != nil {
+		logger.WarnContext(ctx, "failed to get chat member", "target_chat_id", chatID, "member_id", userID, "error", err)
+		return false
+	}
+	return member.Type == tgmodels.ChatMemberTypeOwner || member.Type == tgmodels.ChatMemberTypeAdministrator
+}
+
+func sendPublishError(ctx context.Context, b *bot.Bot, chatID int64, lotteryID string, err error) {
+	removeKeyboard := &tgmodels.ReplyKeyboardRemove{RemoveKeyboard: true}
+	switch {
+	case errors.Is(err, service.ErrLotteryNotFound):
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 未找到该抽奖", ReplyMarkup: removeKeyboard})
+	case errors.Is(err, service.ErrPermissionDenied):
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 您不是该抽奖的所有者", ReplyMarkup: removeKeyboard})
+	case errors.Is(err, service.ErrLotteryEnded):
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 该抽奖已结束", ReplyMarkup: removeKeyboard})
+	case errors.Is(err, service.ErrLotteryNotActive):
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 该抽奖尚未发布", ReplyMarkup: removeKeyboard})
+	default:
+		logger.ErrorContext(ctx, "failed to publish lottery", "lottery_id", lotteryID, "error", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ 发布抽奖失败, 请稍后重试", ReplyMarkup: removeKeyboard})
+	}
+}
@@ -0,0 +1,158 @@
+package lottery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/realSunyz/lucky-tgbot/pkg/logger"
+	dbmodels "github.com/realSunyz/lucky-tgbot/pkg/models"
+	"github.com/realSunyz/lucky-tgbot/pkg/service"
+)
+
+const RevokeSessionCallbackPrefix = "revoke_"
+
+func HandleSessionsCommand(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if lotteryService == nil {
+		logger.ErrorContext(ctx, "lottery service is not initialized")
+		return
+	}
+
+	if update.Message == nil {
+		return
+	}
+
+	if update.Message.Chat.Type != "private" {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ 请在私聊中使用此命令",
+		})
+		return
+	}
+
+	parts := strings.Fields(strings.TrimSpace(update.Message.Text))
+	if len(parts) < 2 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    update.Message.Chat.ID,
+			Text:      "❌ 请提供抽奖 ID\n\n用法: <code>/sessions 123456</code>",
+			ParseMode: tgmodels.ParseModeHTML,
+		})
+		return
+	}
+
+	lotteryID := parts[1]
+	sessions, lottery, err := lotteryService.ListEditSessions(lotteryID, update.Message.From.ID)
+	if err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{ChatID: update.Message.Chat.ID, Text: sessionErrorText(ctx, lotteryID, err)})
+		return
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      update.Message.Chat.ID,
+		Text:        formatSessionList(lottery, sessions),
+		ParseMode:   tgmodels.ParseModeHTML,
+		ReplyMarkup: buildSessionMarkup(lotteryID, sessions),
+	})
+}
+
+// HandleRevokeSessionCallback revokes the session behind the pressed button
+// and redraws the list in place.
+func HandleRevokeSessionCallback(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if lotteryService == nil {
+		logger.ErrorContext(ctx, "lottery service is not initialized")
+		return
+	}
+
+	query := update.CallbackQuery
+	if query == nil {
+		return
+	}
+
+	lotteryID, rawSessionID, ok := strings.Cut(strings.TrimPrefix(query.Data, RevokeSessionCallbackPrefix), "_")
+	sessionID, err := strconv.ParseInt(rawSessionID, 10, 64)
+	if !ok || err != nil {
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID, Text: "❌ 无效的操作"})
+		return
+	}
+
+	if err := lotteryService.RevokeEditSession(lotteryID, query.From.ID, sessionID); err != nil {
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            sessionErrorText(ctx, lotteryID, err),
+			ShowAlert:       true,
+		})
+		return
+	}
+	logger.InfoContext(ctx, "edit session revoked", "lottery_id", lotteryID, "session_id", sessionID)
+
+	b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID, Text: "🗑 已撤销该编辑会话"})
+
+	message := query.Message.Message
+	if message == nil {
+		return
+	}
+	sessions, lottery, err := lotteryService.ListEditSessions(lotteryID, query.From.ID)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to reload edit sessions", "lottery_id", lotteryID, "error", err)
+		return
+	}
+	_, err = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      message.Chat.ID,
+		MessageID:   message.ID,
+		Text:        formatSessionList(lottery, sessions),
+		ParseMode:   tgmodels.ParseModeHTML,
+		ReplyMarkup: buildSessionMarkup(lotteryID, sessions),
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to update session list", "lottery_id", lotteryID, "error", err)
+	}
+}
+
+func formatSessionList(lottery *dbmodels.Lottery, sessions []dbmodels.EditSession) string {
+	if len(sessions) == 0 {
+		return fmt.Sprintf("🔑 抽奖 <code>%s</code> 暂无有效的编辑会话", lottery.ID)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🔑 抽奖 <code>%s</code> 的编辑会话:\n", lottery.ID))
+	for i, session := range sessions {
+		status := "未打开"
+		if session.LastUsedAt != nil {
+			status = "最近使用 " + session.LastUsedAt.Local().Format("01-02 15:04")
+		}
+		sb.WriteString(fmt.Sprintf("\n%d. %s\n   用户: <code>%d</code> | %s\n   %s | 过期 %s",
+			i+1, html.EscapeString(session.Name), session.UserID, roleNames[session.Role],
+			status, session.ExpiresAt.Local().Format("01-02 15:04")))
+	}
+	return sb.String()
+}
+
+func buildSessionMarkup(lotteryID string, sessions []dbmodels.EditSession) tgmodels.ReplyMarkup {
+	rows := make([][]tgmodels.InlineKeyboardButton, 0, len(sessions))
+	for i, session := range sessions {
+		rows = append(rows, []tgmodels.InlineKeyboardButton{{
+			Text:         fmt.Sprintf("撤销 %d. %s", i+1, session.Name),
+			CallbackData: fmt.Sprintf("%s%s_%d", RevokeSessionCallbackPrefix, lotteryID, session.ID),
+		}})
+	}
+	return &tgmodels.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+func sessionErrorText(ctx context.Context, lotteryID string, err error) string {
+	switch {
+	case errors.Is(err, service.ErrLotteryNotFound):
+		return "❌ 未找到该抽奖"
+	case errors.Is(err, service.ErrPermissionDenied):
+		return "❌ 您不是该抽奖的组织者"
+	case errors.Is(err, service.ErrSessionNotFound):
+		return "❌ 该编辑会话不存在或已失效"
+	default:
+		logger.ErrorContext(ctx, "failed to manage edit sessions", "lottery_id", lotteryID, "error", err)
+		return "❌ 操作失败, 请稍后重试"
+	}
+}
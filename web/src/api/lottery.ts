@@ -54,10 +54,13 @@ export async function updatePrizeWeight(
   token: string,
 ): Promise<void> {
   const res = await fetch(
-    `${API_BASE}/api/lottery/${id}/participants/${userId}/prize_weight?token=${token}`,
+    `${API_BASE}/api/lottery/${id}/participants/${userId}/prize_weight`,
     {
       method: "POST",
-      headers: { "Content-Type": "application/json" },
+      headers: {
+        "Content-Type": "application/json",
+        Authorization: `Bearer ${token}`,
+      },
       body: JSON.stringify({ prize_id: prizeId, weight }),
     },
   );
@@ -77,6 +80,19 @@ export interface Winner {
   prize_name: string;
 }
 
+export interface WeightBucket {
+  weight: number;
+  participants: number;
+}
+
+export interface WeightSummary {
+  custom: boolean;
+  distribution: WeightBucket[];
+  prizes?: { prize_id: number; distribution: WeightBucket[] }[];
+  changed_after_join: boolean;
+  last_changed_at?: string;
+}
+
 export interface LotteryResponse {
   id: string;
   title: string;
@@ -91,6 +107,7 @@ export interface LotteryResponse {
   is_weights_disabled?: boolean;
   prizes: Prize[];
   winners?: Winner[];
+  weights?: WeightSummary;
 }
 
 export interface CreateLotteryRequest {
@@ -100,7 +117,6 @@ export interface CreateLotteryRequest {
   draw_time?: string;
   max_entries?: number;
   prizes: Prize[];
-  creator_id: number;
   is_weights_disabled?: boolean;
 }
 
@@ -117,14 +133,18 @@ export async function getLottery(
   return res.json();
 }
 
-// Create or update lottery (from creation page)
+// Create or update lottery (from creation page, requires creation token)
 export async function createLottery(
   id: string,
+  token: string,
   data: CreateLotteryRequest,
 ): Promise<LotteryResponse> {
   const res = await fetch(`${API_BASE}/api/lottery/${id}`, {
     method: "POST",
-    headers: { "Content-Type": "application/json" },
+    headers: {
+      "Content-Type": "application/json",
+      Authorization: `Bearer ${token}`,
+    },
     body: JSON.stringify(data),
   });
   if (!res.ok) {
@@ -140,9 +160,12 @@ export async function updateLottery(
   token: string,
   data: Partial<CreateLotteryRequest>,
 ): Promise<LotteryResponse> {
-  const res = await fetch(`${API_BASE}/api/lottery/${id}?token=${token}`, {
+  const res = await fetch(`${API_BASE}/api/lottery/${id}`, {
     method: "PUT",
-    headers: { "Content-Type": "application/json" },
+    headers: {
+      "Content-Type": "application/json",
+      Authorization: `Bearer ${token}`,
+    },
     body: JSON.stringify(data),
   });
   if (!res.ok) {
@@ -152,20 +175,38 @@ export async function updateLottery(
   return res.json();
 }
 
-// Join lottery
+export interface EditSession {
+  token: string;
+  role: "owner" | "editor" | "moderator";
+  name: string;
+  expires_at: string;
+}
+
+// Exchange the one-time code from an /edit link for an edit session token
+export async function exchangeEditCode(
+  id: string,
+  code: string,
+): Promise<EditSession> {
+  const res = await fetch(`${API_BASE}/api/lottery/${id}/session`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ code }),
+  });
+  if (!res.ok) {
+    const error = await res.json();
+    throw error;
+  }
+  return res.json();
+}
+
+// Join lottery (identity comes from the verified Telegram Mini App init data)
 export async function joinLottery(
   id: string,
-  userData: {
-    user_id: number;
-    username: string;
-    first_name: string;
-    last_name: string;
-  },
+  initData: string,
 ): Promise<Participant> {
   const res = await fetch(`${API_BASE}/api/lottery/${id}/join`, {
     method: "POST",
-    headers: { "Content-Type": "application/json" },
-    body: JSON.stringify(userData),
+    headers: { Authorization: `tma ${initData}` },
   });
   if (!res.ok) {
     const error = await res.json();
@@ -182,10 +223,13 @@ export async function addParticipant(
   },
 ): Promise<Participant> {
   const res = await fetch(
-    `${API_BASE}/api/lottery/${id}/participants?token=${token}`,
+    `${API_BASE}/api/lottery/${id}/participants`,
     {
       method: "POST",
-      headers: { "Content-Type": "application/json" },
+      headers: {
+        "Content-Type": "application/json",
+        Authorization: `Bearer ${token}`,
+      },
       body: JSON.stringify(userData),
     },
   );
@@ -196,15 +240,99 @@ export async function addParticipant(
   return res.json();
 }
 
-// Get participants (requires token)
+export type ParticipantSort = "joined" | "weight";
+export type ParticipantFilter = "" | "custom_weight" | "custom_prize_weight";
+
+export interface ParticipantQuery {
+  q?: string;
+  sort?: ParticipantSort;
+  order?: "asc" | "desc";
+  filter?: ParticipantFilter;
+  cursor?: string;
+  limit?: number;
+}
+
+export interface ParticipantPage {
+  participants: Participant[];
+  next_cursor?: string;
+  total: number;
+  matched: number;
+}
+
+// Get one page of participants (requires token)
 export async function getParticipants(
   id: string,
   token: string,
+  query: ParticipantQuery = {},
   signal?: AbortSignal,
-): Promise<Participant[]> {
+): Promise<ParticipantPage> {
+  const params = new URLSearchParams();
+  Object.entries(query).forEach(([key, value]) => {
+    if (value !== undefined && value !== "") params.set(key, String(value));
+  });
+  const res = await fetch(
+    `${API_BASE}/api/lottery/${id}/participants?${params}`,
+    { signal, headers: { Authorization: `Bearer ${token}` } },
+  );
+  if (!res.ok) {
+    const error = await res.json();
+    throw error;
+  }
+  return res.json();
+}
+
+export type ExportFormat = "csv" | "json";
+export type ExportContent = "participants" | "winners";
+
+// Download participants or winners as a file (requires token)
+export async function exportLottery(
+  id: string,
+  token: string,
+  format: ExportFormat,
+  what: ExportContent,
+): Promise<Blob> {
+  const params = new URLSearchParams({ format, what });
+  const res = await fetch(`${API_BASE}/api/lottery/${id}/export?${params}`, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+  if (!res.ok) {
+    const error = await res.json();
+    throw error;
+  }
+  return res.blob();
+}
+
+export interface ImportResult {
+  row: number;
+  user_id?: number;
+  status: "added" | "duplicate" | "invalid";
+  error?: string;
+}
+
+export interface ImportReport {
+  added: number;
+  duplicates: number;
+  invalid: number;
+  rows: ImportResult[];
+}
+
+// Import participants from a CSV or JSON file (requires token)
+export async function importParticipants(
+  id: string,
+  token: string,
+  file: File,
+): Promise<ImportReport> {
+  const format = file.name.toLowerCase().endsWith(".json") ? "json" : "csv";
   const res = await fetch(
-    `${API_BASE}/api/lottery/${id}/participants?token=${token}`,
-    { signal },
+    `${API_BASE}/api/lottery/${id}/participants/import?format=${format}`,
+    {
+      method: "POST",
+      headers: {
+        "Content-Type": format === "json" ? "application/json" : "text/csv",
+        Authorization: `Bearer ${token}`,
+      },
+      body: file,
+    },
   );
   if (!res.ok) {
     const error = await res.json();
@@ -221,10 +349,13 @@ export async function updateParticipantWeight(
   token: string,
 ): Promise<void> {
   const res = await fetch(
-    `${API_BASE}/api/lottery/${id}/participants/${userId}?token=${token}`,
+    `${API_BASE}/api/lottery/${id}/participants/${userId}`,
     {
       method: "PUT",
-      headers: { "Content-Type": "application/json" },
+      headers: {
+        "Content-Type": "application/json",
+        Authorization: `Bearer ${token}`,
+      },
       body: JSON.stringify({ weight }),
     },
   );
@@ -242,9 +373,10 @@ export async function deletePrizeWeight(
   token: string,
 ): Promise<void> {
   const res = await fetch(
-    `${API_BASE}/api/lottery/${id}/participants/${userId}/prize_weight/${prizeId}?token=${token}`,
+    `${API_BASE}/api/lottery/${id}/participants/${userId}/prize_weight/${prizeId}`,
     {
       method: "DELETE",
+      headers: { Authorization: `Bearer ${token}` },
     },
   );
   if (!res.ok) {
@@ -260,9 +392,10 @@ export async function removeParticipant(
   token: string,
 ): Promise<void> {
   const res = await fetch(
-    `${API_BASE}/api/lottery/${id}/participants/${userId}?token=${token}`,
+    `${API_BASE}/api/lottery/${id}/participants/${userId}`,
     {
       method: "DELETE",
+      headers: { Authorization: `Bearer ${token}` },
     },
   );
   if (!res.ok) {
@@ -276,8 +409,9 @@ export async function drawLottery(
   id: string,
   token: string,
 ): Promise<{ success: boolean; winners: Winner[] }> {
-  const res = await fetch(`${API_BASE}/api/lottery/${id}/draw?token=${token}`, {
+  const res = await fetch(`${API_BASE}/api/lottery/${id}/draw`, {
     method: "POST",
+    headers: { Authorization: `Bearer ${token}` },
   });
   if (!res.ok) {
     const error = await res.json();
@@ -298,6 +432,60 @@ export async function getResults(
   return res.json();
 }
 
+export interface LotteryEventHandlers {
+  onParticipants?: (participants: number) => void;
+  onLottery?: (lottery: Omit<LotteryResponse, "winners" | "weights">) => void;
+  onWeights?: (weights: WeightSummary | null) => void;
+  onDrawStarted?: () => void;
+  onDrawFailed?: () => void;
+  onWinners?: (result: { lottery: Lottery; winners: Winner[] | null }) => void;
+  onDeleted?: () => void;
+  // Called when the stream reconnects, since events may have been missed
+  onReconnect?: () => void;
+}
+
+// Subscribe to live lottery updates. Returns a function that closes the stream.
+export function subscribeLotteryEvents(
+  id: string,
+  handlers: LotteryEventHandlers,
+): () => void {
+  const source = new EventSource(`${API_BASE}/api/lottery/${id}/events`);
+  let opened = false;
+
+  const on = <T>(type: string, handle: (data: T) => void) => {
+    source.addEventListener(type, (event) => {
+      handle(JSON.parse((event as MessageEvent<string>).data) as T);
+    });
+  };
+
+  source.onopen = () => {
+    if (opened) handlers.onReconnect?.();
+    opened = true;
+  };
+  on<{ participants: number }>("participants", (data) =>
+    handlers.onParticipants?.(data.participants),
+  );
+  on<Omit<LotteryResponse, "winners" | "weights">>("lottery", (data) =>
+    handlers.onLottery?.(data),
+  );
+  on<{ weights: WeightSummary | null }>("weights", (data) =>
+    handlers.onWeights?.(data.weights),
+  );
+  on<null>("draw_started", () => handlers.onDrawStarted?.());
+  on<null>("draw_failed", () => handlers.onDrawFailed?.());
+  // The server ends the stream after these, so don't reconnect
+  on<{ lottery: Lottery; winners: Winner[] | null }>("winners", (data) => {
+    source.close();
+    handlers.onWinners?.(data);
+  });
+  on<null>("deleted", () => {
+    source.close();
+    handlers.onDeleted?.();
+  });
+
+  return () => source.close();
+}
+
 export async function getLotteryStats(): Promise<LotteryStats> {
   const res = await fetch(`${API_BASE}/api/stats`);
   if (!res.ok) {
@@ -0,0 +1,66 @@
+import { useState } from "react";
+import { toast } from "@/components/ui/sonner";
+
+import { Button } from "@/components/ui/button";
+import {
+  Card,
+  CardContent,
+  CardDescription,
+  CardHeader,
+  CardTitle,
+} from "@/components/ui/card";
+import { Download, Loader2 } from "lucide-react";
+import { exportLottery, type ExportFormat } from "@/api/lottery";
+import { getErrorMessage } from "@/utils/errors";
+
+interface ExportCardProps {
+  lotteryId: string;
+  token: string;
+}
+
+export function ExportCard({ lotteryId, token }: ExportCardProps) {
+  const [exporting, setExporting] = useState<ExportFormat | null>(null);
+
+  const handleExport = async (format: ExportFormat) => {
+    setExporting(format);
+    try {
+      const blob = await exportLottery(lotteryId, token, format, "participants");
+      const url = URL.createObjectURL(blob);
+      const link = document.createElement("a");
+      link.href = url;
+      link.download = `lottery-${lotteryId}-participants.${format}`;
+      link.click();
+      URL.revokeObjectURL(url);
+    } catch (err) {
+      toast.error(getErrorMessage(err));
+    } finally {
+      setExporting(null);
+    }
+  };
+
+  return (
+    <Card className="gap-4">
+      <CardHeader>
+        <CardTitle>导出数据</CardTitle>
+        <CardDescription>下载参与者名单及权重</CardDescription>
+      </CardHeader>
+      <CardContent className="grid grid-cols-2 gap-3">
+        {(["csv", "json"] as const).map((format) => (
+          <Button
+            key={format}
+            variant="outline"
+            disabled={exporting !== null}
+            onClick={() => handleExport(format)}
+          >
+            {exporting === format ? (
+              <Loader2 className="w-4 h-4 mr-2 animate-spin" />
+            ) : (
+              <Download className="w-4 h-4 mr-2" />
+            )}
+            {format.toUpperCase()}
+          </Button>
+        ))}
+      </CardContent>
+    </Card>
+  );
+}
@@ -0,0 +1,95 @@
+import { useRef, useState } from "react";
+import { toast } from "@/components/ui/sonner";
+
+import { Button } from "@/components/ui/button";
+import {
+  Card,
+  CardContent,
+  CardDescription,
+  CardHeader,
+  CardTitle,
+} from "@/components/ui/card";
+import { Loader2, Upload } from "lucide-react";
+import { importParticipants, type ImportReport } from "@/api/lottery";
+import { getErrorMessage } from "@/utils/errors";
+
+interface ImportCardProps {
+  lotteryId: string;
+  token: string;
+  onImported: () => Promise<void>;
+}
+
+export function ImportCard({ lotteryId, token, onImported }: ImportCardProps) {
+  const inputRef = useRef<HTMLInputElement>(null);
+  const [importing, setImporting] = useState(false);
+  const [report, setReport] = useState<ImportReport | null>(null);
+
+  const handleFile = async (file: File | undefined) => {
+    if (!file) return;
+    setImporting(true);
+    try {
+      const result = await importParticipants(lotteryId, token, file);
+      setReport(result);
+      toast.success(`已导入 ${result.added} 人`);
+      await onImported();
+    } catch (err) {
+      toast.error(getErrorMessage(err));
+    } finally {
+      setImporting(false);
+      if (inputRef.current) inputRef.current.value = "";
+    }
+  };
+
+  const skipped = report?.rows.filter((row) => row.status !== "added") ?? [];
+
+  return (
+    <Card className="gap-4">
+      <CardHeader>
+        <CardTitle>批量导入</CardTitle>
+        <CardDescription>
+          上传含 user_id 列的 CSV 或 JSON 文件, 可选 weight 列
+        </CardDescription>
+      </CardHeader>
+      <CardContent className="space-y-3">
+        <input
+          ref={inputRef}
+          type="file"
+          accept=".csv,.json,text/csv,application/json"
+          className="hidden"
+          onChange={(e) => handleFile(e.target.files?.[0])}
+        />
+        <Button
+          variant="outline"
+          className="w-full"
+          disabled={importing}
+          onClick={() => inputRef.current?.click()}
+        >
+          {importing ? (
+            <Loader2 className="w-4 h-4 mr-2 animate-spin" />
+          ) : (
+            <Upload className="w-4 h-4 mr-2" />
+          )}
+          选择文件
+        </Button>
+        {report && (
+          <div className="space-y-2 text-sm">
+            <p className="text-muted-foreground">
+              新增 {report.added} · 重复 {report.duplicates} · 无效{" "}
+              {report.invalid}
+            </p>
+            {skipped.length > 0 && (
+              <ul className="max-h-40 overflow-y-auto space-y-1 font-mono text-xs">
+                {skipped.map((row) => (
+                  <li key={row.row} className="text-muted-foreground">
+                    第 {row.row} 行{row.user_id ? ` (${row.user_id})` : ""}:{" "}
+                    {row.error}
+                  </li>
+                ))}
+              </ul>
+            )}
+          </div>
+        )}
+      </CardContent>
+    </Card>
+  );
+}
@@ -25,6 +25,13 @@ import {
   ResponsiveDrawerTitle as DialogTitle,
   ResponsiveDrawerFooter as DialogFooter,
 } from "@/components/ui/responsive-drawer";
+import {
+  Select,
+  SelectContent,
+  SelectItem,
+  SelectTrigger,
+  SelectValue,
+} from "@/components/ui/select";
 import {
   Trash2,
   Settings2,
@@ -33,8 +40,15 @@ import {
   RotateCcw,
   Check,
   X,
+  Loader2,
 } from "lucide-react";
-import type { Participant, Prize } from "@/api/lottery";
+import type {
+  Participant,
+  ParticipantFilter,
+  ParticipantSort,
+  Prize,
+} from "@/api/lottery";
+import type { ParticipantListQuery } from "@/hooks/useLotteryData";
 import {
   updateParticipantWeight,
   updatePrizeWeight,
@@ -45,6 +59,15 @@ import { getErrorMessage } from "@/utils/errors";
 
 interface ParticipantsTableProps {
   participants: Participant[];
+  total: number;
+  matched: number;
+  hasMore: boolean;
+  changed: boolean;
+  onRefresh: () => void;
+  loadingMore: boolean;
+  onLoadMore: () => Promise<void>;
+  query: ParticipantListQuery;
+  onQueryChange: (query: ParticipantListQuery) => void;
   prizes: Prize[];
   lotteryId: string;
   token: string;
@@ -53,8 +76,30 @@ interface ParticipantsTableProps {
   isWeightsDisabled?: boolean;
 }
 
+const SORT_OPTIONS: Record<string, string> = {
+  "joined:asc": "最早加入",
+  "joined:desc": "最近加入",
+  "weight:desc": "权重从高到低",
+  "weight:asc": "权重从低到高",
+};
+
+const FILTER_OPTIONS: Record<string, string> = {
+  all: "全部用户",
+  custom_weight: "自定义权重",
+  custom_prize_weight: "自定义奖品权重",
+};
+
 export function ParticipantsTable({
   participants,
+  total,
+  matched,
+  hasMore,
+  changed,
+  onRefresh,
+  loadingMore,
+  onLoadMore,
+  query,
+  onQueryChange,
   prizes,
   lotteryId,
   token,
@@ -67,6 +112,34 @@ export function ParticipantsTable({
   const [weightEditingParticipant, setWeightEditingParticipant] =
     useState<Participant | null>(null);
   const [isAddUserOpen, setIsAddUserOpen] = useState(false);
+  const [searchInput, setSearchInput] = useState(query.q ?? "");
+
+  // Search once typing pauses
+  useEffect(() => {
+    if (searchInput === (query.q ?? "")) return;
+    const timer = window.setTimeout(
+      () => onQueryChange({ ...query, q: searchInput }),
+      300,
+    );
+    return () => window.clearTimeout(timer);
+  }, [searchInput, query, onQueryChange]);
+
+  const sortValue = `${query.sort ?? "joined"}:${query.order ?? "asc"}`;
+  const handleSortChange = (value: string) => {
+    const [sort, order] = value.split(":");
+    onQueryChange({
+      ...query,
+      sort: sort as ParticipantSort,
+      order: order as "asc" | "desc",
+    });
+  };
+  const handleFilterChange = (value: string) => {
+    onQueryChange({
+      ...query,
+      filter: value === "all" ? "" : (value as ParticipantFilter),
+    });
+  };
+  const isFiltered = !!query.q || !!query.filter;
 
   const formatDate = (dateStr: string) => {
     return new Date(dateStr).toLocaleString("zh-CN");
@@ -87,8 +160,8 @@ export function ParticipantsTable({
             <CardTitle>参与者列表</CardTitle>
             <CardDescription>
               {isWeightsDisabled
-                ? "查看所有参与用户"
-                : "查看所有参与用户并修改权重"}
+                ? `共 ${total} 位参与用户`
+                : `共 ${total} 位参与用户, 可修改权重`}
             </CardDescription>
           </div>
           {!isWeightsDisabled && (
@@ -105,10 +178,63 @@ export function ParticipantsTable({
             </Button>
           )}
         </CardHeader>
-        <CardContent>
+        <CardContent className="space-y-4">
+          <div className="flex flex-col sm:flex-row gap-2">
+            <div className="relative flex-1">
+              <Search className="absolute left-2.5 top-2.5 h-4 w-4 text-muted-foreground" />
+              <Input
+                placeholder="搜索用户名、姓名或用户 ID"
+                value={searchInput}
+                onChange={(e) => setSearchInput(e.target.value)}
+                className="pl-8"
+              />
+            </div>
+            <div className="flex gap-2">
+              <Select value={sortValue} onValueChange={handleSortChange}>
+                <SelectTrigger className="flex-1 sm:w-36">
+                  <SelectValue />
+                </SelectTrigger>
+                <SelectContent>
+                  {Object.entries(SORT_OPTIONS).map(([value, label]) => (
+                    <SelectItem key={value} value={value}>
+                      {label}
+                    </SelectItem>
+                  ))}
+                </SelectContent>
+              </Select>
+              <Select
+                value={query.filter || "all"}
+                onValueChange={handleFilterChange}
+              >
+                <SelectTrigger className="flex-1 sm:w-40">
+                  <SelectValue />
+                </SelectTrigger>
+                <SelectContent>
+                  {Object.entries(FILTER_OPTIONS).map(([value, label]) => (
+                    <SelectItem key={value} value={value}>
+                      {label}
+                    </SelectItem>
+                  ))}
+                </SelectContent>
+              </Select>
+            </div>
+          </div>
+
+          {changed && (
+            <Button
+              variant="outline"
+              size="sm"
+              className="w-full"
+              onClick={onRefresh}
+            >
+              <RotateCcw className="w-4 h-4 mr-2" />
+              有新的参与者, 点击刷新
+            </Button>
+          )}
+
           {participants.length === 0 ? (
             <div className="text-center py-12 text-muted-foreground bg-muted/20 rounded-lg border border-dashed">
-              <p>暂无参与者</p>
+              <p>{isFiltered ? "没有匹配的参与者" : "暂无参与者"}</p>
             </div>
           ) : (
             <>
@@ -234,6 +360,26 @@ export function ParticipantsTable({
                   </div>
                 ))}
               </div>
+
+              <div className="flex items-center justify-between gap-2 text-sm text-muted-foreground">
+                <span>
+                  已显示 {participants.length} / {matched}
+                  {isFiltered && ` (共 ${total})`}
+                </span>
+                {hasMore && (
+                  <Button
+                    variant="outline"
+                    size="sm"
+                    disabled={loadingMore}
+                    onClick={onLoadMore}
+                  >
+                    {loadingMore && (
+                      <Loader2 className="w-4 h-4 mr-2 animate-spin" />
+                    )}
+                    加载更多
+                  </Button>
+                )}
+              </div>
             </>
           )}
         </CardContent>
@@ -1,4 +1,6 @@
 export { DrawActions } from "./DrawActions";
 export { ParticipantsTable } from "./ParticipantsTable";
 export { EditLotterySkeleton } from "./EditLotterySkeleton";
+export { ExportCard } from "./ExportCard";
+export { ImportCard } from "./ImportCard";
 export { PrizesCard } from "./PrizesCard";
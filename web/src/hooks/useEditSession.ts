@@ -0,0 +1,57 @@
+import { useEffect, useState } from "react";
+import { useSearchParams } from "react-router-dom";
+import { exchangeEditCode } from "@/api/lottery";
+
+interface UseEditSessionReturn {
+  token: string | null;
+  exchanging: boolean;
+}
+
+function storageKey(id: string) {
+  return `edit_session_${id}`;
+}
+
+// Exchanges the one-time `code` from an /edit link for a session token and
+// keeps the token in sessionStorage, so it never stays in the URL.
+export function useEditSession(id: string | undefined): UseEditSessionReturn {
+  const [searchParams, setSearchParams] = useSearchParams();
+  const code = searchParams.get("code");
+  const [token, setToken] = useState<string | null>(() =>
+    id ? sessionStorage.getItem(storageKey(id)) : null,
+  );
+  const [exchanging, setExchanging] = useState(Boolean(id && code));
+
+  useEffect(() => {
+    if (!id || !code) return;
+
+    let cancelled = false;
+    setExchanging(true);
+    exchangeEditCode(id, code)
+      .then((session) => {
+        if (cancelled) return;
+        sessionStorage.setItem(storageKey(id), session.token);
+        setToken(session.token);
+      })
+      .catch(() => {
+        // A used or expired code falls back to any session already stored.
+      })
+      .finally(() => {
+        if (cancelled) return;
+        setExchanging(false);
+        setSearchParams(
+          (prev) => {
+            const next = new URLSearchParams(prev);
+            next.delete("code");
+            return next;
+          },
+          { replace: true },
+        );
+      });
+
+    return () => {
+      cancelled = true;
+    };
+  }, [id, code, setSearchParams]);
+
+  return { token, exchanging };
+}
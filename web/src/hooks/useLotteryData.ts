@@ -3,8 +3,11 @@ import {
   getLottery,
   getParticipants,
   type Participant,
+  type ParticipantQuery,
   type LotteryResponse,
 } from "@/api/lottery";
+import { toast } from "@/components/ui/sonner";
+import { useLotteryEvents } from "@/hooks/useLotteryEvents";
 import { getErrorMessage } from "@/utils/errors";
 
 interface UseLotteryDataOptions {
@@ -12,15 +15,28 @@ interface UseLotteryDataOptions {
   token: string | null;
 }
 
+export type ParticipantListQuery = Omit<ParticipantQuery, "cursor" | "limit">;
+
 interface UseLotteryDataReturn {
   lottery: LotteryResponse | null;
   participants: Participant[];
+  totalParticipants: number;
+  matchedParticipants: number;
+  hasMoreParticipants: boolean;
+  // Someone joined since the list was loaded
+  participantsChanged: boolean;
+  loadingMore: boolean;
+  query: ParticipantListQuery;
+  setQuery: (query: ParticipantListQuery) => void;
+  loadMore: () => Promise<void>;
   loading: boolean;
   error: string | null;
   loadData: (options?: { signal?: AbortSignal }) => Promise<void>;
   setParticipants: React.Dispatch<React.SetStateAction<Participant[]>>;
 }
 
+const PAGE_SIZE = 50;
+
 function isAbortError(err: unknown) {
   return (
     typeof err === "object" &&
@@ -36,10 +52,17 @@ export function useLotteryData({
 }: UseLotteryDataOptions): UseLotteryDataReturn {
   const [lottery, setLottery] = useState<LotteryResponse | null>(null);
   const [participants, setParticipants] = useState<Participant[]>([]);
+  const [total, setTotal] = useState(0);
+  const [matched, setMatched] = useState(0);
+  const [nextCursor, setNextCursor] = useState<string | undefined>();
+  const [query, setQuery] = useState<ParticipantListQuery>({});
   const [loading, setLoading] = useState(true);
+  const [loadingMore, setLoadingMore] = useState(false);
   const [error, setError] = useState<string | null>(null);
+  const [participantsChanged, setParticipantsChanged] = useState(false);
   const requestIdRef = useRef(0);
 
+  // Reloads the lottery and the first page of participants
   const loadData = useCallback(
     async (options?: { signal?: AbortSignal }) => {
       const signal = options?.signal;
@@ -53,15 +76,19 @@ export function useLotteryData({
       const requestId = ++requestIdRef.current;
 
       try {
-        const [lotteryData, participantsData] = await Promise.all([
+        const [lotteryData, page] = await Promise.all([
           getLottery(id, signal),
-          getParticipants(id, token, signal),
+          getParticipants(id, token, { ...query, limit: PAGE_SIZE }, signal),
         ]);
 
         if (signal?.aborted || requestId !== requestIdRef.current) return;
         setError(null);
         setLottery(lotteryData);
-        setParticipants(participantsData || []);
+        setParticipants(page.participants);
+        setTotal(page.total);
+        setMatched(page.matched);
+        setNextCursor(page.next_cursor);
+        setParticipantsChanged(false);
       } catch (err) {
         if (isAbortError(err) || requestId !== requestIdRef.current) return;
         setError(getErrorMessage(err));
@@ -71,18 +98,64 @@ export function useLotteryData({
         }
       }
     },
-    [id, token],
+    [id, token, query],
   );
 
+  const loadMore = useCallback(async () => {
+    if (!id || !token || !nextCursor) return;
+
+    const requestId = requestIdRef.current;
+    setLoadingMore(true);
+    try {
+      const page = await getParticipants(id, token, {
+        ...query,
+        cursor: nextCursor,
+        limit: PAGE_SIZE,
+      });
+      // A reload since started would make this page stale
+      if (requestId !== requestIdRef.current) return;
+      setParticipants((prev) => [...prev, ...page.participants]);
+      setTotal(page.total);
+      setMatched(page.matched);
+      setNextCursor(page.next_cursor);
+    } catch (err) {
+      toast.error(getErrorMessage(err));
+    } finally {
+      setLoadingMore(false);
+    }
+  }, [id, token, query, nextCursor]);
+
   useEffect(() => {
     const controller = new AbortController();
     loadData({ signal: controller.signal });
     return () => controller.abort();
   }, [loadData]);
 
+  useLotteryEvents(id, !!token && lottery?.status === "active", {
+    onParticipants: (count) => {
+      // Removals made on this page already show in the list
+      if (count > total) setParticipantsChanged(true);
+      setTotal(count);
+      setLottery((prev) => (prev ? { ...prev, participants: count } : prev));
+    },
+    onLottery: (data) =>
+      setLottery((prev) => (prev ? { ...prev, ...data } : prev)),
+    onWinners: () => loadData(),
+    onDeleted: () => loadData(),
+    onReconnect: () => loadData(),
+  });
+
   return {
     lottery,
     participants,
+    totalParticipants: total,
+    matchedParticipants: matched,
+    hasMoreParticipants: !!nextCursor,
+    participantsChanged,
+    loadingMore,
+    query,
+    setQuery,
+    loadMore,
     loading,
     error,
     loadData,
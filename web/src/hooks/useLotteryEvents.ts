@@ -0,0 +1,30 @@
+import { useEffect, useRef } from "react";
+import {
+  subscribeLotteryEvents,
+  type LotteryEventHandlers,
+} from "@/api/lottery";
+
+// Listens for live updates to an active lottery. Handlers may change between
+// renders without reopening the stream.
+export function useLotteryEvents(
+  id: string | undefined,
+  enabled: boolean,
+  handlers: LotteryEventHandlers,
+) {
+  const handlersRef = useRef(handlers);
+  handlersRef.current = handlers;
+
+  useEffect(() => {
+    if (!id || !enabled) return;
+
+    return subscribeLotteryEvents(id, {
+      onParticipants: (n) => handlersRef.current.onParticipants?.(n),
+      onLottery: (lottery) => handlersRef.current.onLottery?.(lottery),
+      onDrawStarted: () => handlersRef.current.onDrawStarted?.(),
+      onDrawFailed: () => handlersRef.current.onDrawFailed?.(),
+      onWinners: (result) => handlersRef.current.onWinners?.(result),
+      onDeleted: () => handlersRef.current.onDeleted?.(),
+      onReconnect: () => handlersRef.current.onReconnect?.(),
+    });
+  }, [id, enabled]);
+}
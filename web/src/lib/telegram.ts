@@ -0,0 +1,15 @@
+interface TelegramWebApp {
+  initData: string;
+  ready: () => void;
+}
+
+declare global {
+  interface Window {
+    Telegram?: { WebApp?: TelegramWebApp };
+  }
+}
+
+// Signed init data is only present when the page runs as a Telegram Mini App.
+export function getInitData(): string {
+  return window.Telegram?.WebApp?.initData ?? "";
+}
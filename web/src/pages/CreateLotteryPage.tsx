@@ -1,5 +1,10 @@
 import { useState, useEffect } from "react";
-import { useParams, useNavigate, Link } from "react-router-dom";
+import {
+  useParams,
+  useNavigate,
+  useSearchParams,
+  Link,
+} from "react-router-dom";
 import { toast } from "@/components/ui/sonner";
 import { Button } from "@/components/ui/button";
 import { Input } from "@/components/ui/input";
@@ -53,6 +58,8 @@ function isAbortError(err: unknown) {
 
 export default function CreateLotteryPage() {
   const { id } = useParams<{ id: string }>();
+  const [searchParams] = useSearchParams();
+  const createToken = searchParams.get("token");
   const navigate = useNavigate();
 
   const [creatorId, setCreatorId] = useState<number | null>(null);
@@ -162,7 +169,7 @@ export default function CreateLotteryPage() {
     e.preventDefault();
     setErrorMsg(null);
 
-    if (!id || !creatorId) {
+    if (!id || !creatorId || !createToken) {
       setErrorMsg(VALIDATION_ERRORS.INVALID_CREATE_LINK);
       return;
     }
@@ -246,14 +253,13 @@ export default function CreateLotteryPage() {
         drawTimeISO = date.toISOString();
       }
 
-      await createLottery(id, {
+      await createLottery(id, createToken, {
         title: title.trim(),
         description: description.trim(),
         draw_mode: drawMode,
         draw_time: drawTimeISO,
         max_entries: drawMode === "full" ? parseInt(maxEntries, 10) : undefined,
         prizes: validPrizes,
-        creator_id: creatorId,
         is_weights_disabled: isWeightsDisabled,
       });
 
@@ -270,7 +276,7 @@ export default function CreateLotteryPage() {
     return <LoadingDisplay />;
   }
 
-  if (!id || !creatorId) {
+  if (!id || !creatorId || !createToken) {
     return (
       <ErrorDisplay
         title={UI_MESSAGES.LOAD_FAILED_TITLE}
@@ -2,10 +2,13 @@ import { useParams, useSearchParams, useNavigate } from "react-router-dom";
 import { Hash, UserRoundPlus } from "lucide-react";
 import { UI_MESSAGES } from "@/utils/errors";
 
+import { useEditSession } from "@/hooks/useEditSession";
 import { useLotteryData } from "@/hooks/useLotteryData";
 import { useParticipantActions } from "@/hooks/useParticipantActions";
 import {
   DrawActions,
+  ExportCard,
+  ImportCard,
   ParticipantsTable,
   PrizesCard,
   EditLotterySkeleton,
@@ -15,11 +18,25 @@ import { ErrorDisplay } from "@/components/ui/error-display";
 export default function EditLotteryPage() {
   const { id } = useParams<{ id: string }>();
   const [searchParams] = useSearchParams();
-  const token = searchParams.get("token");
+  const { token, exchanging } = useEditSession(id);
 
   // Data fetching hook
-  const { lottery, participants, loading, error, loadData, setParticipants } =
-    useLotteryData({ id, token });
+  const {
+    lottery,
+    participants,
+    totalParticipants,
+    matchedParticipants,
+    hasMoreParticipants,
+    participantsChanged,
+    loadingMore,
+    query,
+    setQuery,
+    loadMore,
+    loading,
+    error,
+    loadData,
+    setParticipants,
+  } = useLotteryData({ id, token });
 
   const navigate = useNavigate();
 
@@ -35,6 +52,11 @@ export default function EditLotteryPage() {
       onDrawSuccess: () => navigate(`/lottery/${id}`),
     });
 
+  // A freshly exchanged token has not loaded any data yet
+  if (exchanging || (token && !lottery && !error)) {
+    return <EditLotterySkeleton />;
+  }
+
   // Error states
   if (id && !token) {
     return (
@@ -104,9 +126,15 @@ export default function EditLotteryPage() {
               token={token!}
               onDraw={handleDraw}
               isDrawing={isDrawing}
-              disabled={participants.length === 0}
+              disabled={totalParticipants === 0}
               onUpdate={loadData}
             />
+            <ImportCard
+              lotteryId={id!}
+              token={token!}
+              onImported={loadData}
+            />
+            <ExportCard lotteryId={id!} token={token!} />
           </div>
 
           <div className="lg:col-span-2 space-y-6">
@@ -118,6 +146,15 @@ export default function EditLotteryPage() {
             />
             <ParticipantsTable
               participants={participants}
+              total={totalParticipants}
+              matched={matchedParticipants}
+              hasMore={hasMoreParticipants}
+              changed={participantsChanged}
+              onRefresh={() => loadData()}
+              loadingMore={loadingMore}
+              onLoadMore={loadMore}
+              query={query}
+              onQueryChange={setQuery}
               prizes={lottery.prizes || []}
               lotteryId={id!}
               token={token!}
@@ -1,4 +1,4 @@
-import { useState, useEffect } from "react";
+import { useState, useEffect, useCallback } from "react";
 import { useParams } from "react-router-dom";
 import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card";
 import { Badge } from "@/components/ui/badge";
@@ -10,7 +10,16 @@ import {
   TableHeader,
   TableRow,
 } from "@/components/ui/table";
-import { getLottery, type LotteryResponse } from "@/api/lottery";
+import { Button } from "@/components/ui/button";
+import { toast } from "@/components/ui/sonner";
+import {
+  getLottery,
+  joinLottery,
+  type LotteryResponse,
+  type WeightBucket,
+} from "@/api/lottery";
+import { useLotteryEvents } from "@/hooks/useLotteryEvents";
+import { getInitData } from "@/lib/telegram";
 import {
   Trophy,
   Gift,
@@ -19,6 +28,8 @@ import {
   UserRoundPlus,
   ListCollapse,
   BadgeCheck,
+  Scale,
+  TriangleAlert,
 } from "lucide-react";
 import { getErrorMessage, UI_MESSAGES } from "@/utils/errors";
 import { ErrorDisplay } from "@/components/ui/error-display";
@@ -38,6 +49,25 @@ export default function LotteryPage() {
   const [lottery, setLottery] = useState<LotteryResponse | null>(null);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState<string | null>(null);
+  const [joining, setJoining] = useState(false);
+  const [drawing, setDrawing] = useState(false);
+  const initData = getInitData();
+
+  const handleJoin = async () => {
+    if (!id || !initData) return;
+    setJoining(true);
+    try {
+      await joinLottery(id, initData);
+      toast.success("参加抽奖成功");
+      setLottery((prev) =>
+        prev ? { ...prev, participants: prev.participants + 1 } : prev,
+      );
+    } catch (err) {
+      toast.error(getErrorMessage(err));
+    } finally {
+      setJoining(false);
+    }
+  };
 
   useEffect(() => {
     if (!id) {
@@ -64,6 +94,37 @@ export default function LotteryPage() {
     return () => controller.abort();
   }, [id]);
 
+  // Reloads quietly in the background, keeping what is shown on failure
+  const refresh = useCallback(() => {
+    if (!id) return;
+    getLottery(id)
+      .then(setLottery)
+      .catch(() => {});
+  }, [id]);
+
+  useLotteryEvents(id, lottery?.status === "active", {
+    onParticipants: (participants) =>
+      setLottery((prev) => (prev ? { ...prev, participants } : prev)),
+    onLottery: (data) =>
+      setLottery((prev) => (prev ? { ...prev, ...data } : prev)),
+    onWeights: (weights) =>
+      setLottery((prev) =>
+        prev ? { ...prev, weights: weights ?? undefined } : prev,
+      ),
+    onDrawStarted: () => setDrawing(true),
+    onDrawFailed: () => setDrawing(false),
+    // The full response also carries the weights the draw used
+    onWinners: () => {
+      setDrawing(false);
+      refresh();
+    },
+    onDeleted: () => {
+      setLottery(null);
+      setError(UI_MESSAGES.INVALID_LOTTERY_ID);
+    },
+    onReconnect: refresh,
+  });
+
   if (loading) {
     return <LoadingDisplay />;
   }
@@ -193,6 +254,28 @@ export default function LotteryPage() {
     return `${winRate}%`;
   };
 
+  const weights = lottery.weights;
+  const showWeights =
+    !!weights && (weights.custom || weights.changed_after_join);
+  const prizeNameById = new Map<number, string>();
+  lottery.prizes.forEach((prize) => {
+    if (prize.id != null) prizeNameById.set(prize.id, prize.name);
+  });
+
+  const renderDistribution = (buckets: WeightBucket[]) => (
+    <div className="flex flex-wrap gap-2">
+      {buckets.map((bucket) => (
+        <Badge
+          key={bucket.weight}
+          variant="secondary"
+          className="font-mono text-sm px-3 py-0.5"
+        >
+          权重 {bucket.weight} × {bucket.participants} 人
+        </Badge>
+      ))}
+    </div>
+  );
+
   return (
     <div className="py-8 px-4 flex justify-center w-full">
       <div className="w-full max-w-6xl space-y-6">
@@ -370,7 +453,7 @@ export default function LotteryPage() {
                             colSpan={2}
                             className="text-center py-12 text-muted-foreground"
                           >
-                            尚未开奖, 敬请期待
+                            {drawing ? "正在开奖..." : "尚未开奖, 敬请期待"}
                           </TableCell>
                         </TableRow>
                       )}
@@ -423,9 +506,54 @@ export default function LotteryPage() {
               </CardContent>
             </Card>
 
-            <div className="p-4 rounded-lg bg-muted text-center text-sm text-muted-foreground">
-              <p>请通过 Telegram Bot 参与此抽奖</p>
-            </div>
+            {showWeights && weights && (
+              <Card className="gap-2">
+                <CardHeader>
+                  <CardTitle className="flex items-center gap-2 text-lg">
+                    <Scale className="w-5 h-5" /> 权重分布
+                  </CardTitle>
+                </CardHeader>
+                <CardContent className="space-y-3">
+                  {weights.changed_after_join && (
+                    <div className="flex items-start gap-2 p-3 rounded-lg bg-yellow-500/10 text-yellow-700 dark:text-yellow-400 text-sm">
+                      <TriangleAlert className="w-4 h-4 mt-0.5 shrink-0" />
+                      <span>
+                        发布者在用户参与后修改过权重
+                        {weights.last_changed_at &&
+                          `（最近一次：${formatDate(weights.last_changed_at)}）`}
+                      </span>
+                    </div>
+                  )}
+                  <div className="space-y-2">
+                    <span className="text-sm font-medium">参与者权重</span>
+                    {renderDistribution(weights.distribution)}
+                  </div>
+                  {weights.prizes?.map((prize) => (
+                    <div key={prize.prize_id} className="space-y-2">
+                      <span className="text-sm font-medium">
+                        {prizeNameById.get(prize.prize_id) ??
+                          `奖品 ${prize.prize_id}`}
+                      </span>
+                      {renderDistribution(prize.distribution)}
+                    </div>
+                  ))}
+                </CardContent>
+              </Card>
+            )}
+
+            {lottery.status === "active" && initData ? (
+              <Button
+                className="w-full"
+                onClick={handleJoin}
+                disabled={joining || drawing}
+              >
+                {joining ? "参与中..." : "参与抽奖"}
+              </Button>
+            ) : (
+              <div className="p-4 rounded-lg bg-muted text-center text-sm text-muted-foreground">
+                <p>请通过 Telegram Bot 参与此抽奖</p>
+              </div>
+            )}
           </div>
         </div>
       </div>
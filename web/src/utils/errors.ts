@@ -10,6 +10,9 @@ export const ERROR_MESSAGES: Record<string, string> = {
   ERR_TOKEN_INVALID: "编辑令牌无效或已过期",
   ERR_RATE_LIMITED: "请求过于频繁",
   ERR_REQUEST_TIMEOUT: "请求超时",
+  ERR_INIT_DATA_INVALID: "Telegram 身份校验失败",
+  ERR_FORBIDDEN: "无权执行此操作",
+  ERR_USER_BANNED: "该用户已被禁止创建或参与抽奖",
 };
 
 export const VALIDATION_ERRORS = {